@@ -0,0 +1,135 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DHCPClientClassSpec defines a kea-dhcp4 client class.
+type DHCPClientClassSpec struct {
+	// ClassName is the name of the class in Kea, as referenced from
+	// require-client-classes and host reservations. Defaults to metadata.name.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=128
+	ClassName string `json:"className,omitempty"`
+
+	// Test is the Kea expression that decides class membership, e.g.
+	// "option[93].hex == 0x0007". Without it clients only join the class
+	// through host reservations.
+	// +kubebuilder:validation:Optional
+	Test string `json:"test,omitempty"`
+
+	// OnlyIfRequired evaluates the class only for subnets and pools that
+	// require it (require-client-classes).
+	// +kubebuilder:validation:Optional
+	OnlyIfRequired bool `json:"onlyIfRequired,omitempty"`
+
+	// NextServer is the siaddr (TFTP server) handed to class members.
+	// +kubebuilder:validation:Optional
+	NextServer string `json:"nextServer,omitempty"`
+
+	// ServerHostname is the sname field handed to class members.
+	// +kubebuilder:validation:Optional
+	ServerHostname string `json:"serverHostname,omitempty"`
+
+	// BootFileName is the BOOTP file field handed to class members.
+	// +kubebuilder:validation:Optional
+	BootFileName string `json:"bootFileName,omitempty"`
+
+	// OptionData is sent to class members.
+	// +kubebuilder:validation:Optional
+	OptionData []DHCPOption `json:"optionData,omitempty"`
+}
+
+// DHCPOption is one Kea option-data entry. Name or Code is required.
+type DHCPOption struct {
+	// Name is the Kea option name, e.g. "boot-file-name".
+	// +kubebuilder:validation:Optional
+	Name string `json:"name,omitempty"`
+
+	// Code is the option code.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=254
+	Code int `json:"code,omitempty"`
+
+	// Space is the option space; defaults to "dhcp4".
+	// +kubebuilder:validation:Optional
+	Space string `json:"space,omitempty"`
+
+	// Data is the option value, in CSV form unless CSVFormat is false.
+	Data string `json:"data"`
+
+	// CSVFormat is false for data given as hex; defaults to true.
+	// +kubebuilder:validation:Optional
+	CSVFormat *bool `json:"csvFormat,omitempty"`
+}
+
+// DHCPClientClassStatus defines the observed state of DHCPClientClass.
+type DHCPClientClassStatus struct {
+	// ClassName is the name of the class the operator last applied in Kea.
+	// +kubebuilder:validation:Optional
+	ClassName string `json:"className,omitempty"`
+
+	// ObservedGeneration is the generation last applied in Kea.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions holds the Ready condition.
+	// +kubebuilder:validation:Optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=dcc
+// +kubebuilder:printcolumn:name="Class",type=string,JSONPath=`.status.className`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DHCPClientClass is a kea-dhcp4 client class managed through the class_cmds
+// hook.
+type DHCPClientClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DHCPClientClassSpec   `json:"spec,omitempty"`
+	Status DHCPClientClassStatus `json:"status,omitempty"`
+}
+
+// KeaClassName returns the name of the class in Kea: spec.className, or
+// metadata.name when it is unset.
+func (c *DHCPClientClass) KeaClassName() string {
+	if c.Spec.ClassName != "" {
+		return c.Spec.ClassName
+	}
+	return c.Name
+}
+
+// +kubebuilder:object:root=true
+
+// DHCPClientClassList contains a list of DHCPClientClass.
+type DHCPClientClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DHCPClientClass `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DHCPClientClass{}, &DHCPClientClassList{})
+}
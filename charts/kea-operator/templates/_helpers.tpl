@@ -60,3 +60,14 @@ Create the name of the service account to use
 {{- default "default" .Values.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Name of the secret holding the webhook serving certificate
+*/}}
+{{- define "kea-operator.webhookSecretName" -}}
+{{- if .Values.webhook.certManager.enabled }}
+{{- printf "%s-webhook-cert" (include "kea-operator.fullname" .) }}
+{{- else }}
+{{- required "webhook.secretName is required when webhook.certManager.enabled is false" .Values.webhook.secretName }}
+{{- end }}
+{{- end }}
@@ -30,8 +30,10 @@ import (
 	"github.com/vitistack/kea-operator/internal/services/initialchecks"
 
 	// +kubebuilder:scaffold:imports
+	keav1alpha1 "github.com/vitistack/kea-operator/api/v1alpha1"
 	"github.com/vitistack/kea-operator/internal/controller/v1alpha1"
 	"github.com/vitistack/kea-operator/internal/settings"
+	webhookv1alpha1 "github.com/vitistack/kea-operator/internal/webhook/v1alpha1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -56,6 +58,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(vitistackcrdsv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(keav1alpha1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -235,9 +238,28 @@ func setupReconcilers(mgr ctrl.Manager) {
 	// +kubebuilder:scaffold:builder
 
 	vlog.Info("All controllers and webhooks are set up")
-	kubernetesClusterReconciler := v1alpha1.NewNetworkConfigurationReconciler(mgr, clients.KeaClient)
+	kubernetesClusterReconciler := v1alpha1.NewNetworkConfigurationReconciler(mgr, clients.KeaClient, clients.Kea6Client)
 	if err := kubernetesClusterReconciler.SetupWithManager(mgr); err != nil {
 		vlog.Error("unable to create controller", err)
 		os.Exit(1)
 	}
+
+	if viper.GetBool(consts.ENABLE_CLIENT_CLASSES) {
+		clientClassReconciler := v1alpha1.NewDHCPClientClassReconciler(mgr, kubernetesClusterReconciler.Kea)
+		if err := clientClassReconciler.SetupWithManager(mgr); err != nil {
+			vlog.Error("unable to create DHCPClientClass controller", err)
+			os.Exit(1)
+		}
+	}
+
+	if viper.GetBool(consts.ENABLE_WEBHOOKS) {
+		if err := webhookv1alpha1.SetupNetworkConfigurationWebhookWithManager(mgr); err != nil {
+			vlog.Error("unable to create NetworkConfiguration webhook", err)
+			os.Exit(1)
+		}
+		if err := webhookv1alpha1.SetupNetworkNamespaceWebhookWithManager(mgr, kubernetesClusterReconciler.Kea); err != nil {
+			vlog.Error("unable to create NetworkNamespace webhook", err)
+			os.Exit(1)
+		}
+	}
 }
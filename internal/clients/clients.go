@@ -3,6 +3,7 @@ package clients
 import (
 	"context"
 	"os"
+	"slices"
 
 	"github.com/spf13/viper"
 	"github.com/vitistack/kea-operator/internal/consts"
@@ -15,6 +16,11 @@ import (
 var (
 	// KeaClient is a singleton instance available to the operator.
 	KeaClient keainterface.KeaClient
+
+	// Kea6Client talks to the kea-dhcp6 control socket given by KEA_DHCP6_URL.
+	// It is nil when KEA_DHCP6_URL is unset, in which case DHCPv6 commands go
+	// through KeaClient to a Control Agent.
+	Kea6Client keainterface.KeaClient
 )
 
 // InitializeClients initializes the global Kea client.
@@ -24,11 +30,13 @@ var (
 //   - HA: KEA_URL (primary) + KEA_SECONDARY_URL (optional)
 //   - TLS (file or secret based)
 //   - Basic Auth via KEA_BASIC_AUTH_USERNAME / KEA_BASIC_AUTH_PASSWORD (ignored if client certs provided)
+//   - A separate kea-dhcp6 socket via KEA_DHCP6_URL, sharing the TLS and auth settings
 func InitializeClients() {
 	// Load environment variables
 	viper.AutomaticEnv()
 	_ = viper.BindEnv(consts.KEA_URL)
 	_ = viper.BindEnv(consts.KEA_SECONDARY_URL)
+	_ = viper.BindEnv(consts.KEA_DHCP6_URL)
 	_ = viper.BindEnv(consts.KEA_PORT)
 	_ = viper.BindEnv(consts.KEA_TLS_SECRET_NAME)
 	_ = viper.BindEnv(consts.KEA_TLS_SECRET_NAMESPACE)
@@ -38,6 +46,17 @@ func InitializeClients() {
 	// Base options (env-based TLS, timeout, etc.)
 	baseOpts := []keaclient.KeaOption{keaclient.OptionFromEnv()}
 
+	KeaClient = newKeaClient(baseOpts)
+	if url6 := viper.GetString(consts.KEA_DHCP6_URL); url6 != "" {
+		// The HA secondary from KEA_SECONDARY_URL is a kea-dhcp4 peer, so it
+		// is not carried over.
+		Kea6Client = newKeaClient(append(slices.Clone(baseOpts), keaclient.OptionURL(url6), keaclient.OptionNoSecondaryURL()))
+	}
+}
+
+// newKeaClient builds a client from opts, adding the TLS material of the
+// KEA_TLS_SECRET_NAME secret when one is configured and readable.
+func newKeaClient(opts []keaclient.KeaOption) keainterface.KeaClient {
 	// Attempt secret-based TLS if env specifies
 	secretName := viper.GetString(consts.KEA_TLS_SECRET_NAME)
 	secretNS := viper.GetString(consts.KEA_TLS_SECRET_NAMESPACE)
@@ -51,9 +70,8 @@ func InitializeClients() {
 		if cfg, err := config.GetConfig(); err == nil {
 			kube, err2 := kubernetes.NewForConfig(cfg)
 			if err2 == nil {
-				if kc, err3 := BuildKeaClientFromSecret(context.Background(), kube, secretNS, secretName, baseOpts...); err3 == nil && kc != nil {
-					KeaClient = kc
-					return
+				if kc, err3 := BuildKeaClientFromSecret(context.Background(), kube, secretNS, secretName, opts...); err3 == nil && kc != nil {
+					return kc
 				}
 			}
 		}
@@ -61,5 +79,5 @@ func InitializeClients() {
 
 	// If secret TLS wasn't used and basic auth username exists while no cert material was configured via env,
 	// OptionFromEnv already populated the fields inside the client. We just construct now.
-	KeaClient = keaclient.NewKeaClientWithOptions(baseOpts...)
+	return keaclient.NewKeaClientWithOptions(opts...)
 }
@@ -0,0 +1,164 @@
+package consts
+
+// Annotations read by the operator on NetworkConfigurations and
+// NetworkNamespaces. The vitistack CRDs are shared with other operators, so
+// Kea-specific per-object settings are carried as annotations under the
+// kea.vitistack.io prefix rather than as spec fields.
+const (
+	// RequestedIPv4Annotation pins interfaces of a NetworkConfiguration to
+	// specific IPv4 addresses instead of whatever Kea happened to lease.
+	// Format: comma-separated "<interface name or MAC>=<ipv4>" entries,
+	// e.g. "eth0=10.0.0.10,aa:bb:cc:dd:ee:ff=10.0.0.11".
+	RequestedIPv4Annotation = "kea.vitistack.io/requested-ipv4"
+
+	// DHCP6DUIDAnnotation keys the DHCPv6 reservations of interfaces on their
+	// client DUID instead of their MAC, which DHCPv6 clients do not always
+	// send. Format: comma-separated "<interface name or MAC>=<duid>" entries,
+	// e.g. "eth0=00:03:00:01:aa:bb:cc:dd:ee:ff".
+	DHCP6DUIDAnnotation = "kea.vitistack.io/dhcp6-duid"
+
+	// HostIdentifierAnnotation keys the DHCPv4 reservations of interfaces on
+	// another host identifier than their MAC, for clients that rotate or hide
+	// it. Format: comma-separated "<interface name or MAC>=<type>[=<value>]"
+	// entries where type is hw-address, client-id, duid, circuit-id or
+	// flex-id and value is hex or 'quoted text', e.g.
+	// "eth0=client-id,eth1=circuit-id='ge-0/0/1'". Without a value hw-address
+	// is the MAC and client-id is "01:<mac>"; the other types need one.
+	HostIdentifierAnnotation = "kea.vitistack.io/host-identifier"
+
+	// IPv6PDPoolsAnnotation on a NetworkNamespace adds prefix-delegation pools
+	// to its DHCPv6 subnet when it is created. Format: comma-separated
+	// "<prefix>/<length>=<delegated length>" entries, e.g.
+	// "2001:db8:8000::/40=56".
+	IPv6PDPoolsAnnotation = "kea.vitistack.io/ipv6-pd-pools"
+
+	// ReleaseConflictingLeasesAnnotation, when "true" on a NetworkConfiguration,
+	// lets the operator lease4-del a dynamic lease another MAC holds on a
+	// requested IPv4 address. Without it such a lease is reported as a conflict.
+	ReleaseConflictingLeasesAnnotation = "kea.vitistack.io/release-conflicting-leases"
+
+	// IPAllocationModeAnnotation on a NetworkNamespace overrides
+	// KEA_IP_ALLOCATION_MODE for NetworkConfigurations in it ("lease" or "operator").
+	IPAllocationModeAnnotation = "kea.vitistack.io/ip-allocation-mode"
+
+	// ReservationRangeAnnotation on a NetworkNamespace sets an out-of-pool
+	// range, e.g. "10.0.0.10-10.0.0.99", that is carved out of the dynamic
+	// pools and used for operator-side allocation. Without it operator-side
+	// allocation picks from the pools.
+	ReservationRangeAnnotation = "kea.vitistack.io/reservation-range"
+
+	// Pool layout overrides on a NetworkNamespace, applied when its Kea subnet
+	// is created. PoolGatewayAnnotation is "first", "last" or an IPv4 address;
+	// the reserve annotations override KEA_POOL_RESERVE_HEAD/TAIL.
+	// PoolsAnnotation replaces the computed pool with explicit ranges and
+	// PoolExcludeAnnotation removes ranges from the pools; both take
+	// comma-separated "start-end" ranges or single addresses.
+	PoolGatewayAnnotation     = "kea.vitistack.io/pool-gateway"
+	PoolReserveHeadAnnotation = "kea.vitistack.io/pool-reserve-head"
+	PoolReserveTailAnnotation = "kea.vitistack.io/pool-reserve-tail"
+	PoolsAnnotation           = "kea.vitistack.io/pools"
+	PoolExcludeAnnotation     = "kea.vitistack.io/pool-exclude"
+
+	// DHCPOptionsAnnotation on a NetworkNamespace sets option-data on its Kea
+	// subnet, overriding KEA_DHCP_OPTIONS option by option. Format:
+	// ";"-separated "<option>=<data>" entries, where the option is a Kea
+	// option name or code, optionally prefixed with "<space>/", and data
+	// starting with "0x" is hex, e.g.
+	// "ntp-servers=10.0.0.5;interface-mtu=9000;classless-static-route=10.2.0.0/16 - 10.0.0.1".
+	DHCPOptionsAnnotation = "kea.vitistack.io/dhcp-options"
+
+	// RequireClientClassesAnnotation on a NetworkNamespace replaces
+	// KEA_REQUIRE_CLIENT_CLASSES for the pools of its subnet. Format:
+	// comma-separated class names; an empty value requires no classes.
+	RequireClientClassesAnnotation = "kea.vitistack.io/require-client-classes"
+
+	// Lease time annotations on a NetworkNamespace override KEA_VALID_LIFETIME,
+	// KEA_RENEW_TIMER and KEA_REBIND_TIMER for its subnet. Values are seconds
+	// or a duration such as "30m".
+	ValidLifetimeAnnotation = "kea.vitistack.io/valid-lifetime"
+	RenewTimerAnnotation    = "kea.vitistack.io/renew-timer"
+	RebindTimerAnnotation   = "kea.vitistack.io/rebind-timer"
+
+	// RelayAddressesAnnotation on a NetworkNamespace replaces
+	// KEA_RELAY_ADDRESSES for its subnets: "gateway", or comma-separated IPv4
+	// relay agent addresses; an empty value sets none.
+	RelayAddressesAnnotation = "kea.vitistack.io/relay-addresses"
+
+	// SubnetInterfaceAnnotation on a NetworkNamespace replaces
+	// KEA_SUBNET_INTERFACE, the Kea server interface its subnets are served on.
+	SubnetInterfaceAnnotation = "kea.vitistack.io/subnet-interface"
+
+	// SecondaryIPv4PrefixesAnnotation on a NetworkNamespace adds further IPv4
+	// prefixes on the same L2 segment as status.ipv4Prefix. Each gets its own
+	// Kea subnet, and all of them are placed in one shared network. Format:
+	// comma-separated CIDR prefixes, e.g. "10.123.1.0/24".
+	SecondaryIPv4PrefixesAnnotation = "kea.vitistack.io/secondary-ipv4-prefixes"
+
+	// SharedNetworkAnnotation on a NetworkNamespace names the Kea shared
+	// network its subnets are placed in, and places them there even without
+	// secondary prefixes. Without it, NetworkNamespaces with secondary
+	// prefixes use "vlan-<status.vlanId>", or "<namespace>-<name>" when no
+	// VLAN is set.
+	SharedNetworkAnnotation = "kea.vitistack.io/shared-network"
+
+	// HostParamsAnnotation on a NetworkConfiguration sets per-host fields on
+	// the Kea reservations of its interfaces, overriding KEA_HOST_PARAMS key
+	// by key. An annotation named HostParamsAnnotation + "." + <interface name,
+	// or MAC with "-" separators> overrides it again for one interface.
+	// Format: ";"-separated "<key>=<value>" entries. hostname, next-server,
+	// server-hostname and boot-file-name set those reservation fields,
+	// client-classes takes a comma-separated list, and any other key is an
+	// option in the dhcp-options format. {name} and {interface} in values are
+	// replaced by the NetworkConfiguration and interface name, e.g.
+	// "hostname={name}-{interface};boot-file-name=http://boot/{name}.ipxe;client-classes=uefi".
+	HostParamsAnnotation = "kea.vitistack.io/host-params"
+
+	// SubnetOptionsAnnotation is written by the operator on
+	// NetworkConfigurations. It holds, as JSON, the option-data of the Kea
+	// subnet the interfaces are in, as read back from Kea.
+	SubnetOptionsAnnotation = "kea.vitistack.io/subnet-options"
+
+	// ReservationsAnnotation is written by the operator on NetworkConfigurations
+	// and must not be edited by hand. It records, as JSON, the Kea reservations
+	// made for the resource and the prefix they were made for.
+	ReservationsAnnotation = "kea.vitistack.io/reservations"
+
+	// InterfaceStatusAnnotation is written by the operator on
+	// NetworkConfigurations. It holds a JSON list with one entry per interface
+	// carrying a machine-readable reason (Reserved, AwaitingLease, Conflict,
+	// InvalidRequest, VLANMismatch, Error), since the shared status type has
+	// no field for it.
+	InterfaceStatusAnnotation = "kea.vitistack.io/interface-status"
+
+	// ForceFinalizeAnnotation, when "true" on a NetworkConfiguration being
+	// deleted, lets the operator remove its finalizer even though reservation
+	// cleanup in Kea keeps failing. The skipped reservations are recorded in
+	// the kea-operator-orphans ConfigMap.
+	ForceFinalizeAnnotation = "kea.vitistack.io/force-finalize"
+
+	// ReleaseLeasesOnDeleteAnnotation on a NetworkNamespace overrides
+	// KEA_RELEASE_LEASES_ON_DELETE for NetworkConfigurations in it ("true" or
+	// "false").
+	ReleaseLeasesOnDeleteAnnotation = "kea.vitistack.io/release-leases-on-delete"
+
+	// PreseedLeasesAnnotation on a NetworkNamespace overrides
+	// KEA_PRESEED_LEASES for NetworkConfigurations in it ("true" or "false").
+	PreseedLeasesAnnotation = "kea.vitistack.io/preseed-leases"
+
+	// PauseAnnotation on a NetworkConfiguration ("true") makes the operator
+	// skip it entirely, including deletion cleanup; the finalizer is kept.
+	PauseAnnotation = "kea.vitistack.io/pause"
+
+	// DryRunAnnotation on a NetworkConfiguration ("true") makes the operator
+	// record the Kea commands that would change state (subnet4-add,
+	// subnet6-add, subnet4-delta-add, reservation-add/update/del, lease4-del)
+	// on the DryRun condition and as Events instead of sending them.
+	// KEA_DRY_RUN does the same globally.
+	DryRunAnnotation = "kea.vitistack.io/dry-run"
+
+	// AdoptClientClassAnnotation on a DHCPClientClass ("true") lets the
+	// operator take over a Kea client class of the same name that was defined
+	// by hand or by another DHCPClientClass. Without it such a class is left
+	// alone and the resource reports NotManaged.
+	AdoptClientClassAnnotation = "kea.vitistack.io/adopt-existing"
+)
@@ -12,6 +12,7 @@ const (
 	KEA_BASE_URL             = "KEA_BASE_URL"
 	KEA_URL                  = "KEA_URL"           // full URL e.g. https://host:port (preferred)
 	KEA_SECONDARY_URL        = "KEA_SECONDARY_URL" // secondary URL for HA failover (optional)
+	KEA_DHCP6_URL            = "KEA_DHCP6_URL"     // kea-dhcp6 control socket URL (optional)
 	KEA_PORT                 = "KEA_PORT"
 	KEA_TLS_CA_FILE          = "KEA_TLS_CA_FILE"
 	KEA_TLS_CERT_FILE        = "KEA_TLS_CERT_FILE"
@@ -30,6 +31,24 @@ const (
 	// Pool configuration for subnet creation
 	KEA_REQUIRE_CLIENT_CLASSES = "KEA_REQUIRE_CLIENT_CLASSES" // comma-separated list of client classes
 
+	// Default lease times for the subnets the operator manages, in seconds or
+	// as a duration such as "1h". KEA_VALID_LIFETIME defaults to 4000;
+	// KEA_RENEW_TIMER and KEA_REBIND_TIMER are unset by default, leaving them
+	// to Kea. NetworkNamespaces override them with the lease time annotations.
+	KEA_VALID_LIFETIME = "KEA_VALID_LIFETIME"
+	KEA_RENEW_TIMER    = "KEA_RENEW_TIMER"
+	KEA_REBIND_TIMER   = "KEA_REBIND_TIMER"
+
+	// KEA_RELAY_ADDRESSES sets relay.ip-addresses on the subnets the operator
+	// manages, so Kea selects them for requests relayed from those addresses:
+	// "gateway" relays each subnet via its own gateway, otherwise a
+	// comma-separated list of IPv4 addresses. Unset by default.
+	// KEA_SUBNET_INTERFACE sets the interface subnets are served on instead,
+	// for directly attached clients. NetworkNamespaces override both with the
+	// relay-addresses and subnet-interface annotations.
+	KEA_RELAY_ADDRESSES  = "KEA_RELAY_ADDRESSES"
+	KEA_SUBNET_INTERFACE = "KEA_SUBNET_INTERFACE"
+
 	// KEA_STRICT_DEFAULTS, when true, makes the operator refuse to claim
 	// NetworkConfigurations with an unset spec.provider or NetworkNamespaces
 	// with a nil spec.ipAllocation. When false (default), the operator treats
@@ -41,4 +60,86 @@ const (
 	// parallel per controller. The workqueue still serializes by object key, so
 	// concurrency only applies across distinct objects. Defaults to 5 when unset.
 	MAX_CONCURRENT_RECONCILES = "MAX_CONCURRENT_RECONCILES"
+
+	// KEA_IP_ALLOCATION_MODE selects who picks reservation IPs for interfaces
+	// without a requested address: "lease" (default) pins whatever Kea leases
+	// the host, "operator" allocates and reserves a free address up front.
+	// A NetworkNamespace can override it with the ip-allocation-mode annotation.
+	KEA_IP_ALLOCATION_MODE = "KEA_IP_ALLOCATION_MODE"
+
+	// Default pool layout for new subnets. KEA_POOL_GATEWAY is "first" or
+	// "last" usable address; KEA_POOL_RESERVE_HEAD/TAIL are the number of
+	// usable addresses at the start/end of the prefix kept out of the pool.
+	// NetworkNamespaces can override each of them with pool annotations.
+	KEA_POOL_GATEWAY      = "KEA_POOL_GATEWAY"
+	KEA_POOL_RESERVE_HEAD = "KEA_POOL_RESERVE_HEAD"
+	KEA_POOL_RESERVE_TAIL = "KEA_POOL_RESERVE_TAIL"
+
+	// KEA_DHCP_OPTIONS is the default option-data for the subnets the operator
+	// manages, as ";"-separated "<option>=<data>" entries, e.g.
+	// "ntp-servers=10.0.0.5;domain-name=example.org". NetworkNamespaces
+	// override single options with the dhcp-options annotation.
+	KEA_DHCP_OPTIONS = "KEA_DHCP_OPTIONS"
+
+	// KEA_HOST_PARAMS is the default for the per-host fields of the
+	// reservations the operator makes, in the host-params annotation format,
+	// e.g. "hostname={name}-{interface}". NetworkConfigurations override it
+	// key by key with the host-params annotations.
+	KEA_HOST_PARAMS = "KEA_HOST_PARAMS"
+
+	// KEA_MIGRATION_GRACE_PERIOD is how long reservations made for a previous
+	// NetworkNamespace prefix are kept after a prefix change (Go duration,
+	// default 30m). KEA_MIGRATION_RELEASE_LEASES additionally deletes the
+	// leases still held in the old subnet when they are removed.
+	KEA_MIGRATION_GRACE_PERIOD   = "KEA_MIGRATION_GRACE_PERIOD"
+	KEA_MIGRATION_RELEASE_LEASES = "KEA_MIGRATION_RELEASE_LEASES"
+
+	// KEA_DELETION_TIMEOUT is how long after deletion was requested the
+	// operator keeps retrying reservation cleanup before it removes the
+	// finalizer anyway (Go duration, default 1h; 0 retries forever).
+	KEA_DELETION_TIMEOUT = "KEA_DELETION_TIMEOUT"
+
+	// KEA_RELEASE_LEASES_ON_DELETE deletes the DHCPv4 leases of a deleted
+	// NetworkConfiguration's MACs along with its reservations, so the
+	// addresses are free straight away (default false). A NetworkNamespace
+	// can override it with the release-leases-on-delete annotation.
+	KEA_RELEASE_LEASES_ON_DELETE = "KEA_RELEASE_LEASES_ON_DELETE"
+
+	// KEA_PRESEED_LEASES adds a lease with lease4-add for every reserved
+	// address whose MAC holds none yet, so the address is in use before the
+	// host first boots (default false). A NetworkNamespace can override it
+	// with the preseed-leases annotation. KEA_PRESEED_LEASE_LIFETIME is the
+	// lifetime of those leases (Go duration, default the subnet's
+	// valid-lifetime).
+	KEA_PRESEED_LEASES         = "KEA_PRESEED_LEASES"
+	KEA_PRESEED_LEASE_LIFETIME = "KEA_PRESEED_LEASE_LIFETIME"
+
+	// KEA_LEASE_WATCH_INTERVAL is how often the lease watcher reads the next
+	// lease4-get-page page of leases, while NetworkConfigurations are waiting
+	// for an address, to enqueue them once their MACs got a lease (Go
+	// duration, default 30s; 0 disables the watcher and falls back to fixed
+	// 30s polling). KEA_LEASE_WATCH_PAGE_SIZE is the limit per page (default
+	// 1000).
+	KEA_LEASE_WATCH_INTERVAL  = "KEA_LEASE_WATCH_INTERVAL"
+	KEA_LEASE_WATCH_PAGE_SIZE = "KEA_LEASE_WATCH_PAGE_SIZE"
+
+	// KEA_DRY_RUN records the Kea commands that would change state instead of
+	// sending them, for every NetworkConfiguration (default false). The
+	// dry-run annotation enables it per NetworkConfiguration.
+	KEA_DRY_RUN = "KEA_DRY_RUN"
+
+	// ENABLE_WEBHOOKS registers the validating admission webhooks for
+	// NetworkConfiguration and NetworkNamespace (default false). The webhook
+	// server then needs a serving certificate, see --webhook-cert-path.
+	ENABLE_WEBHOOKS = "ENABLE_WEBHOOKS"
+
+	// ENABLE_CLIENT_CLASSES runs the DHCPClientClass controller, which manages
+	// kea-dhcp4 client classes through the class_cmds hook (default false).
+	// The DHCPClientClass CRD must be installed.
+	ENABLE_CLIENT_CLASSES = "ENABLE_CLIENT_CLASSES"
+
+	// ENABLE_DHCP6 manages kea-dhcp6 subnets and reservations for
+	// NetworkNamespaces with status.ipv6Prefix (default false). Commands go to
+	// KEA_DHCP6_URL when set, otherwise through the Control Agent at KEA_URL.
+	ENABLE_DHCP6 = "ENABLE_DHCP6"
 )
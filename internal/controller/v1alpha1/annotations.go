@@ -0,0 +1,335 @@
+package v1alpha1
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	subnetutil "github.com/vitistack/kea-operator/internal/util/subnet"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// normalizeMAC lowercases and trims a MAC address and accepts '-' separators
+// by normalizing them to ':'. It does not validate the result.
+func normalizeMAC(mac string) string {
+	return strings.ToLower(strings.TrimSpace(strings.ReplaceAll(mac, "-", ":")))
+}
+
+// annotationBool reads a boolean annotation. Missing or unparsable values are false.
+func annotationBool(annotations map[string]string, key string) bool {
+	v, ok := annotations[key]
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	return err == nil && b
+}
+
+// requestedIPv4ByMAC parses the requested-ipv4 annotation into a map keyed by
+// normalized MAC. Entries reference an interface by name or by MAC; each must
+// resolve to an interface in spec.networkInterfaces and carry a valid IPv4
+// address, and no address may be requested twice.
+func requestedIPv4ByMAC(nc *vitistackcrdsv1alpha1.NetworkConfiguration) (map[string]string, error) {
+	raw := strings.TrimSpace(nc.GetAnnotations()[consts.RequestedIPv4Annotation])
+	if raw == "" {
+		return nil, nil
+	}
+
+	resolve := interfaceMACResolver(nc)
+	out := make(map[string]string)
+	seenIP := make(map[string]string)
+	for entry := range strings.SplitSeq(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("%s: malformed entry %q, expected <interface>=<ipv4>", consts.RequestedIPv4Annotation, entry)
+		}
+
+		mac, found := resolve(key)
+		if !found {
+			return nil, fmt.Errorf("%s: %q does not match any interface name or MAC", consts.RequestedIPv4Annotation, key)
+		}
+
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() == nil {
+			return nil, fmt.Errorf("%s: %q is not a valid IPv4 address", consts.RequestedIPv4Annotation, value)
+		}
+		value = ip.To4().String()
+		if prev, dup := seenIP[value]; dup && prev != mac {
+			return nil, fmt.Errorf("%s: %s is requested for more than one interface", consts.RequestedIPv4Annotation, value)
+		}
+		seenIP[value] = mac
+		out[mac] = value
+	}
+	return out, nil
+}
+
+// interfaceMACResolver returns a function that resolves an annotation key,
+// either an interface name or a MAC, to the normalized MAC of an interface in
+// spec.networkInterfaces.
+func interfaceMACResolver(nc *vitistackcrdsv1alpha1.NetworkConfiguration) func(string) (string, bool) {
+	byName := make(map[string]string, len(nc.Spec.NetworkInterfaces))
+	byMAC := make(map[string]struct{}, len(nc.Spec.NetworkInterfaces))
+	for _, iface := range nc.Spec.NetworkInterfaces {
+		mac := normalizeMAC(iface.MacAddress)
+		if mac == "" {
+			continue
+		}
+		if iface.Name != "" {
+			byName[iface.Name] = mac
+		}
+		byMAC[mac] = struct{}{}
+	}
+	return func(key string) (string, bool) {
+		if mac, ok := byName[key]; ok {
+			return mac, true
+		}
+		if _, ok := byMAC[normalizeMAC(key)]; ok {
+			return normalizeMAC(key), true
+		}
+		return "", false
+	}
+}
+
+// dhcp6DUIDByMAC parses the dhcp6-duid annotation into a map keyed by
+// normalized MAC. Entries reference an interface by name or by MAC and carry
+// a colon-separated hex DUID.
+func dhcp6DUIDByMAC(nc *vitistackcrdsv1alpha1.NetworkConfiguration) (map[string]string, error) {
+	raw := strings.TrimSpace(nc.GetAnnotations()[consts.DHCP6DUIDAnnotation])
+	if raw == "" {
+		return nil, nil
+	}
+	resolve := interfaceMACResolver(nc)
+	out := make(map[string]string)
+	for entry := range strings.SplitSeq(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		key, value = strings.TrimSpace(key), strings.ToLower(strings.TrimSpace(value))
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("%s: malformed entry %q, expected <interface>=<duid>", consts.DHCP6DUIDAnnotation, entry)
+		}
+		mac, found := resolve(key)
+		if !found {
+			return nil, fmt.Errorf("%s: %q does not match any interface name or MAC", consts.DHCP6DUIDAnnotation, key)
+		}
+		if _, err := hex.DecodeString(strings.ReplaceAll(value, ":", "")); err != nil || len(value) < 2 {
+			return nil, fmt.Errorf("%s: %q is not a hex DUID", consts.DHCP6DUIDAnnotation, value)
+		}
+		out[mac] = value
+	}
+	return out, nil
+}
+
+// hostIdentifier is the DHCPv4 host identifier an interface's reservations
+// are keyed on. Value is in keaservice.NormalizeIdentifier form, or empty to
+// derive it from the MAC; the zero value is the MAC itself.
+type hostIdentifier struct {
+	Type  string
+	Value string
+}
+
+// hostIdentifierByMAC parses the host-identifier annotation into a map keyed
+// by normalized MAC. Entries reference an interface by name or by MAC and
+// carry an identifier type with an optional value; duid, circuit-id and
+// flex-id need a value, and no value may be used for more than one interface.
+func hostIdentifierByMAC(nc *vitistackcrdsv1alpha1.NetworkConfiguration) (map[string]hostIdentifier, error) {
+	raw := strings.TrimSpace(nc.GetAnnotations()[consts.HostIdentifierAnnotation])
+	if raw == "" {
+		return nil, nil
+	}
+	resolve := interfaceMACResolver(nc)
+	out := make(map[string]hostIdentifier)
+	seen := make(map[hostIdentifier]string)
+	for entry := range strings.SplitSeq(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, rest, ok := strings.Cut(entry, "=")
+		idType, value, _ := strings.Cut(rest, "=")
+		key, idType, value = strings.TrimSpace(key), strings.ToLower(strings.TrimSpace(idType)), strings.TrimSpace(value)
+		if !ok || key == "" || idType == "" {
+			return nil, fmt.Errorf("%s: malformed entry %q, expected <interface>=<type>[=<value>]", consts.HostIdentifierAnnotation, entry)
+		}
+		mac, found := resolve(key)
+		if !found {
+			return nil, fmt.Errorf("%s: %q does not match any interface name or MAC", consts.HostIdentifierAnnotation, key)
+		}
+		if !keaservice.IsIdentifierType(idType) {
+			return nil, fmt.Errorf("%s: %q is not a host identifier type", consts.HostIdentifierAnnotation, idType)
+		}
+		id := hostIdentifier{Type: idType}
+		if value != "" {
+			v, err := keaservice.NormalizeIdentifier(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", consts.HostIdentifierAnnotation, key, err)
+			}
+			id.Value = v
+			if prev, dup := seen[id]; dup && prev != mac {
+				return nil, fmt.Errorf("%s: %s %s is used for more than one interface", consts.HostIdentifierAnnotation, idType, v)
+			}
+			seen[id] = mac
+		} else if idType != keaservice.IdentifierHWAddress && idType != keaservice.IdentifierClientID {
+			return nil, fmt.Errorf("%s: %s needs a value for %s", consts.HostIdentifierAnnotation, key, idType)
+		}
+		if id == (hostIdentifier{Type: keaservice.IdentifierHWAddress}) {
+			// The MAC, which interfaces are keyed on anyway.
+			id = hostIdentifier{}
+		}
+		out[mac] = id
+	}
+	return out, nil
+}
+
+// ipv6PDPools parses the ipv6-pd-pools annotation of a NetworkNamespace into
+// prefix-delegation pools. Entries are "<prefix>/<length>=<delegated length>".
+func ipv6PDPools(nn *vitistackcrdsv1alpha1.NetworkNamespace) ([]keamodels.PDPool, error) {
+	var out []keamodels.PDPool
+	for entry := range strings.SplitSeq(nn.GetAnnotations()[consts.IPv6PDPoolsAnnotation], ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, delegated, _ := strings.Cut(entry, "=")
+		_, ipnet, err := net.ParseCIDR(strings.TrimSpace(prefix))
+		if err != nil || ipnet.IP.To4() != nil {
+			return nil, fmt.Errorf("%s: %q is not an IPv6 prefix", consts.IPv6PDPoolsAnnotation, prefix)
+		}
+		ones, _ := ipnet.Mask.Size()
+		n, err := strconv.Atoi(strings.TrimSpace(delegated))
+		if err != nil || n < ones || n > 128 {
+			return nil, fmt.Errorf("%s: delegated length %q for %s must be between %d and 128", consts.IPv6PDPoolsAnnotation, delegated, ipnet, ones)
+		}
+		out = append(out, keamodels.PDPool{Prefix: ipnet.IP.String(), PrefixLen: ones, DelegatedLen: n})
+	}
+	return out, nil
+}
+
+// validateRequestedIPv4 checks that a requested address is usable in ipnet: it
+// must lie inside the prefix and must not be the network, broadcast or gateway
+// address.
+func validateRequestedIPv4(ip string, ipnet *net.IPNet, gateway string) error {
+	p := net.ParseIP(ip).To4()
+	if p == nil {
+		return fmt.Errorf("requested IP %s is not a valid IPv4 address", ip)
+	}
+	if ipnet == nil || !ipnet.Contains(p) {
+		return fmt.Errorf("requested IP %s is outside the NetworkNamespace prefix", ip)
+	}
+	network := ipnet.IP.To4()
+	broadcast := make(net.IP, len(network))
+	for i := range network {
+		broadcast[i] = network[i] | ^ipnet.Mask[i]
+	}
+	if p.Equal(network) || p.Equal(broadcast) {
+		return fmt.Errorf("requested IP %s is the network or broadcast address of %s", ip, ipnet.String())
+	}
+	if gateway != "" && p.Equal(net.ParseIP(gateway)) {
+		return fmt.Errorf("requested IP %s is the subnet gateway", ip)
+	}
+	return nil
+}
+
+const (
+	allocationModeLease    = "lease"
+	allocationModeOperator = "operator"
+)
+
+// ipAllocationMode resolves the allocation mode for a NetworkNamespace: its
+// ip-allocation-mode annotation if set, otherwise KEA_IP_ALLOCATION_MODE.
+func ipAllocationMode(nn *vitistackcrdsv1alpha1.NetworkNamespace) (string, error) {
+	mode, source := viper.GetString(consts.KEA_IP_ALLOCATION_MODE), consts.KEA_IP_ALLOCATION_MODE
+	if v, ok := nn.GetAnnotations()[consts.IPAllocationModeAnnotation]; ok {
+		mode, source = v, consts.IPAllocationModeAnnotation
+	}
+	switch m := strings.ToLower(strings.TrimSpace(mode)); m {
+	case "", allocationModeLease:
+		return allocationModeLease, nil
+	case allocationModeOperator:
+		return m, nil
+	}
+	return "", fmt.Errorf("%s: unknown IP allocation mode %q, expected %q or %q",
+		source, mode, allocationModeLease, allocationModeOperator)
+}
+
+// leaseSeeding reports whether leases are pre-seeded for the reserved
+// addresses of interfaces in nn (KEA_PRESEED_LEASES, replaced by the
+// preseed-leases annotation when present) and their lifetime in seconds, 0
+// for the subnet's valid-lifetime.
+func leaseSeeding(nn *vitistackcrdsv1alpha1.NetworkNamespace) (bool, int) {
+	seed := viper.GetBool(consts.KEA_PRESEED_LEASES)
+	if _, ok := nn.GetAnnotations()[consts.PreseedLeasesAnnotation]; ok {
+		seed = annotationBool(nn.GetAnnotations(), consts.PreseedLeasesAnnotation)
+	}
+	return seed, max(int(viper.GetDuration(consts.KEA_PRESEED_LEASE_LIFETIME).Seconds()), 0)
+}
+
+// poolPolicy resolves the pool layout for a NetworkNamespace: the global
+// KEA_POOL_* settings, overridden field by field by the NetworkNamespace's
+// pool annotations. Ranges only make sense for a specific prefix, so pools,
+// exclusions and the reservation range are per-NetworkNamespace only.
+func poolPolicy(nn *vitistackcrdsv1alpha1.NetworkNamespace) (subnetutil.PoolPolicy, error) {
+	policy := subnetutil.PoolPolicy{
+		Gateway:     viper.GetString(consts.KEA_POOL_GATEWAY),
+		ReserveHead: viper.GetInt(consts.KEA_POOL_RESERVE_HEAD),
+		ReserveTail: viper.GetInt(consts.KEA_POOL_RESERVE_TAIL),
+	}
+	annotations := nn.GetAnnotations()
+
+	if v, ok := annotations[consts.PoolGatewayAnnotation]; ok {
+		policy.Gateway = strings.TrimSpace(v)
+	}
+	for key, dst := range map[string]*int{
+		consts.PoolReserveHeadAnnotation: &policy.ReserveHead,
+		consts.PoolReserveTailAnnotation: &policy.ReserveTail,
+	} {
+		v, ok := annotations[key]
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return policy, fmt.Errorf("%s: %q is not a non-negative integer", key, v)
+		}
+		*dst = n
+	}
+
+	var err error
+	if policy.Pools, err = subnetutil.ParseIPv4Ranges(annotations[consts.PoolsAnnotation]); err != nil {
+		return policy, fmt.Errorf("%s: %w", consts.PoolsAnnotation, err)
+	}
+	if policy.Exclude, err = subnetutil.ParseIPv4Ranges(annotations[consts.PoolExcludeAnnotation]); err != nil {
+		return policy, fmt.Errorf("%s: %w", consts.PoolExcludeAnnotation, err)
+	}
+	if raw := strings.TrimSpace(annotations[consts.ReservationRangeAnnotation]); raw != "" {
+		start, end, err := subnetutil.ParseIPv4Range(raw)
+		if err != nil {
+			return policy, fmt.Errorf("%s: %w", consts.ReservationRangeAnnotation, err)
+		}
+		policy.ReservationRange = &subnetutil.IPRange{Start: start, End: end}
+	}
+	return policy, nil
+}
+
+// allocationRanges returns the ranges operator-side allocation picks from:
+// the out-of-pool reservation range when the policy sets one, otherwise the
+// dynamic pools.
+func allocationRanges(pool *subnetutil.PoolConfig) []subnetutil.IPRange {
+	if pool.ReservationRange != nil {
+		return []subnetutil.IPRange{*pool.ReservationRange}
+	}
+	return pool.Pools
+}
@@ -0,0 +1,161 @@
+package v1alpha1
+
+import (
+	"net"
+	"testing"
+
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testMAC0 = "aa:bb:cc:dd:ee:00"
+	testMAC1 = "aa:bb:cc:dd:ee:01"
+)
+
+func ncWithAnnotations(annotations map[string]string) *vitistackcrdsv1alpha1.NetworkConfiguration {
+	return &vitistackcrdsv1alpha1.NetworkConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "nc", Namespace: "default", Annotations: annotations},
+		Spec: vitistackcrdsv1alpha1.NetworkConfigurationSpec{
+			NetworkInterfaces: []vitistackcrdsv1alpha1.NetworkConfigurationInterface{
+				{Name: "eth0", MacAddress: "AA-BB-CC-DD-EE-00"},
+				{Name: "eth1", MacAddress: testMAC1},
+			},
+		},
+	}
+}
+
+func TestRequestedIPv4ByMAC(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "unset", value: "", want: nil},
+		{
+			name:  "by interface name and by MAC",
+			value: "eth0=10.0.0.10, AA-BB-CC-DD-EE-01=10.0.0.11",
+			want:  map[string]string{testMAC0: "10.0.0.10", testMAC1: "10.0.0.11"},
+		},
+		{name: "unknown interface", value: "eth9=10.0.0.10", wantErr: true},
+		{name: "malformed entry", value: "eth0", wantErr: true},
+		{name: "not IPv4", value: "eth0=fd00::10", wantErr: true},
+		{name: "duplicate IP", value: "eth0=10.0.0.10,eth1=10.0.0.10", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(tt *testing.T) {
+			nc := ncWithAnnotations(map[string]string{consts.RequestedIPv4Annotation: tc.value})
+			got, err := requestedIPv4ByMAC(nc)
+			if (err != nil) != tc.wantErr {
+				tt.Fatalf("err=%v, wantErr=%v", err, tc.wantErr)
+			}
+			if len(got) != len(tc.want) {
+				tt.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for mac, ip := range tc.want {
+				if got[mac] != ip {
+					tt.Fatalf("got %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateRequestedIPv4(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("10.0.0.0/24")
+	for ip, wantErr := range map[string]bool{
+		"10.0.0.10":  false,
+		"10.0.1.10":  true, // outside prefix
+		"10.0.0.0":   true, // network
+		"10.0.0.255": true, // broadcast
+		"10.0.0.1":   true, // gateway
+	} {
+		if err := validateRequestedIPv4(ip, ipnet, "10.0.0.1"); (err != nil) != wantErr {
+			t.Errorf("%s: err=%v, wantErr=%v", ip, err, wantErr)
+		}
+	}
+}
+
+func TestDHCP6DUIDByMAC(t *testing.T) {
+	for value, wantErr := range map[string]bool{
+		"": false,
+		"eth0=00:03:00:01:aa:bb, AA-BB-CC-DD-EE-01=00:01": false,
+		"eth9=00:01": true, // unknown interface
+		"eth0":       true, // malformed
+		"eth0=zz:01": true, // not hex
+	} {
+		nc := ncWithAnnotations(map[string]string{consts.DHCP6DUIDAnnotation: value})
+		got, err := dhcp6DUIDByMAC(nc)
+		if (err != nil) != wantErr {
+			t.Errorf("%q: err=%v, wantErr=%v", value, err, wantErr)
+		}
+		if value != "" && !wantErr && (got[testMAC0] != "00:03:00:01:aa:bb" || got[testMAC1] != "00:01") {
+			t.Errorf("%q: got %v", value, got)
+		}
+	}
+}
+
+func TestHostIdentifierByMAC(t *testing.T) {
+	nc := ncWithAnnotations(map[string]string{consts.HostIdentifierAnnotation: "eth0=client-id, eth1=Circuit-ID='ge-0/0/1'"})
+	got, err := hostIdentifierByMAC(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[testMAC0] != (hostIdentifier{Type: keaservice.IdentifierClientID}) ||
+		got[testMAC1] != (hostIdentifier{Type: keaservice.IdentifierCircuitID, Value: "67:65:2d:30:2f:30:2f:31"}) {
+		t.Fatalf("unexpected identifiers %v", got)
+	}
+	nc = ncWithAnnotations(map[string]string{consts.HostIdentifierAnnotation: "eth0=hw-address"})
+	if got, err := hostIdentifierByMAC(nc); err != nil || got[testMAC0] != (hostIdentifier{}) {
+		t.Fatalf("expected hw-address to be the MAC, got %v, %v", got, err)
+	}
+
+	for _, bad := range []string{
+		"eth9=client-id",                  // unknown interface
+		"eth0",                            // malformed
+		"eth0=serial",                     // unknown type
+		"eth0=flex-id",                    // needs a value
+		"eth0=duid=zz",                    // not hex
+		"eth0=flex-id=01,eth1=flex-id=01", // used twice
+	} {
+		nc := ncWithAnnotations(map[string]string{consts.HostIdentifierAnnotation: bad})
+		if _, err := hostIdentifierByMAC(nc); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestLeaseSeeding(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Set(consts.KEA_PRESEED_LEASES, nil)
+		viper.Set(consts.KEA_PRESEED_LEASE_LIFETIME, nil)
+	})
+	viper.Set(consts.KEA_PRESEED_LEASE_LIFETIME, "10m")
+	if seed, lifetime := leaseSeeding(testNetworkNamespace(testOldPrefix, 0, map[string]string{consts.PreseedLeasesAnnotation: "true"})); !seed || lifetime != 600 {
+		t.Fatalf("expected seeding for 600s, got %v, %d", seed, lifetime)
+	}
+	viper.Set(consts.KEA_PRESEED_LEASES, true)
+	if seed, _ := leaseSeeding(testNetworkNamespace(testOldPrefix, 0, map[string]string{consts.PreseedLeasesAnnotation: "false"})); seed {
+		t.Fatal("expected the annotation to override KEA_PRESEED_LEASES")
+	}
+}
+
+func TestIPv6PDPools(t *testing.T) {
+	nn := &vitistackcrdsv1alpha1.NetworkNamespace{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		consts.IPv6PDPoolsAnnotation: "2001:db8:8000::/40=56",
+	}}}
+	pools, err := ipv6PDPools(nn)
+	if err != nil || len(pools) != 1 || pools[0].Prefix != "2001:db8:8000::" || pools[0].PrefixLen != 40 || pools[0].DelegatedLen != 56 {
+		t.Fatalf("unexpected pools %+v, err %v", pools, err)
+	}
+	for _, bad := range []string{"10.0.0.0/8=16", "2001:db8::/48=40", "2001:db8::/48"} {
+		nn.Annotations[consts.IPv6PDPoolsAnnotation] = bad
+		if _, err := ipv6PDPools(nn); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	viticommonconditions "github.com/vitistack/common/pkg/operator/conditions"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/pkg/interfaces/keainterface"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Stage conditions. Ready summarizes them; each of these reports one stage
+// of the reconcile with a stable, machine-readable reason.
+const (
+	conditionTypeNetworkNamespaceResolved = "NetworkNamespaceResolved"
+	conditionReasonResolved               = "Resolved"
+	conditionReasonNotFound               = "NotFound"
+	conditionReasonPrefixMissing          = "PrefixMissing"
+	conditionReasonIPAllocationUnset      = "IPAllocationUnset"
+	conditionReasonInvalidSettings        = "InvalidSettings"
+
+	conditionTypeKeaReachable    = "KeaReachable"
+	conditionReasonReachable     = "Reachable"
+	conditionReasonUnreachable   = "Unreachable"
+	conditionTypeSubnetReady     = "SubnetReady"
+	conditionReasonSubnetFound   = "SubnetFound"
+	conditionReasonSubnetCreated = "SubnetCreated"
+	conditionReasonSubnetError   = "SubnetError"
+
+	conditionTypeReservationsReady   = "ReservationsReady"
+	conditionReasonReserved          = "Reserved"
+	conditionReasonReservationFailed = "ReservationFailed"
+	conditionReasonInvalidRequest    = "InvalidRequest"
+	conditionTypeLeasesObserved      = "LeasesObserved"
+	conditionReasonLeasesObserved    = "LeasesObserved"
+	conditionReasonAwaitingLease     = "AwaitingLease"
+	conditionReasonNoInterfaces      = "NoInterfaces"
+)
+
+// setStage sets one of the stage conditions on nc. Failures to patch are
+// ignored like the other condition updates; the next reconcile retries.
+func (r *NetworkConfigurationReconciler) setStage(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, condType string, ok bool, reason, message string) {
+	status := metav1.ConditionFalse
+	if ok {
+		status = metav1.ConditionTrue
+	}
+	_ = r.setCondition(ctx, nc, viticommonconditions.New(condType, status, reason, message, nc.GetGeneration()))
+}
+
+// reportKeaReachable sets KeaReachable from the error of a Kea call. Only
+// transport failures make it False; an error answer from Kea means it is up.
+func (r *NetworkConfigurationReconciler) reportKeaReachable(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, err error) {
+	if err != nil && errors.Is(err, keainterface.ErrUnreachable) {
+		r.setStage(ctx, nc, conditionTypeKeaReachable, false, conditionReasonUnreachable, err.Error())
+		return
+	}
+	r.setStage(ctx, nc, conditionTypeKeaReachable, true, conditionReasonReachable, "Kea Control Agent answered")
+}
+
+// reportReservationStages sets KeaReachable, ReservationsReady and
+// LeasesObserved from the outcome of processMACReservations.
+func (r *NetworkConfigurationReconciler) reportReservationStages(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, macs []string, res reservationResult) {
+	var keaErr error
+	if res.unreachable {
+		keaErr = fmt.Errorf("%w: %s", keainterface.ErrUnreachable, strings.Join(res.errs, "; "))
+	}
+	r.reportKeaReachable(ctx, nc, keaErr)
+
+	switch {
+	case len(res.errs) > 0 && res.invalid == len(res.errs):
+		r.setStage(ctx, nc, conditionTypeReservationsReady, false, conditionReasonInvalidRequest, strings.Join(res.errs, "; "))
+	case len(res.errs) > 0:
+		r.setStage(ctx, nc, conditionTypeReservationsReady, false, conditionReasonReservationFailed, strings.Join(res.errs, "; "))
+	case len(res.conflicts) > 0:
+		r.setStage(ctx, nc, conditionTypeReservationsReady, false, conditionReasonReservationConflict,
+			fmt.Sprintf("%d reservation conflict(s); see the %s condition", len(res.conflicts), conditionTypeReservationConflict))
+	default:
+		r.setStage(ctx, nc, conditionTypeReservationsReady, true, conditionReasonReserved,
+			fmt.Sprintf("%d of %d interfaces reserved", len(res.macToSubnetID), len(macs)))
+	}
+
+	var waiting []string
+	for _, mac := range macs {
+		if st, ok := res.interfaces[mac]; !ok || !st.Leased {
+			waiting = append(waiting, mac)
+		}
+	}
+	if len(waiting) > 0 {
+		r.setStage(ctx, nc, conditionTypeLeasesObserved, false, conditionReasonAwaitingLease,
+			fmt.Sprintf("no DHCP lease yet for %s", strings.Join(waiting, ", ")))
+		return
+	}
+	r.setStage(ctx, nc, conditionTypeLeasesObserved, true, conditionReasonLeasesObserved,
+		fmt.Sprintf("all %d interfaces hold a lease", len(macs)))
+}
@@ -0,0 +1,176 @@
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/interfaces/keainterface"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReservationStages_KeaUnreachable(t *testing.T) {
+	nc := ncWithAnnotations(nil)
+	r, _ := newDeletionReconciler(t, nc)
+	ctx := context.Background()
+	macs := []string{testMAC0, testMAC1}
+
+	res := r.processMACReservations(ctx, nc, macs, []reservationTarget{{SubnetID: 1, Prefix: testOldPrefix}}, nil, nil, nil, logr.Discard())
+	if !res.unreachable || len(res.errs) != 2 {
+		t.Fatalf("expected unreachable with 2 errors, got %+v", res)
+	}
+	r.reportReservationStages(ctx, nc, macs, res)
+
+	for condType, reason := range map[string]string{
+		conditionTypeKeaReachable:      conditionReasonUnreachable,
+		conditionTypeReservationsReady: conditionReasonReservationFailed,
+		conditionTypeLeasesObserved:    conditionReasonAwaitingLease,
+	} {
+		cond := findCondition(nc.Status.Conditions, condType)
+		if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != reason {
+			t.Errorf("expected %s False/%s, got %+v", condType, reason, cond)
+		}
+	}
+
+	if err := r.writeInterfaceStatuses(ctx, nc, interfaceStatuses(nc, res.interfaces)); err != nil {
+		t.Fatal(err)
+	}
+	var statuses []interfaceStatus
+	if err := json.Unmarshal([]byte(nc.GetAnnotations()[consts.InterfaceStatusAnnotation]), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[0].Name != "eth0" || statuses[0].Reason != interfaceReasonError || statuses[0].Leased {
+		t.Fatalf("unexpected interface status %+v", statuses)
+	}
+}
+
+// leaseLookupDownKea is unreachable for lease4-get-by-hw-address only and
+// records the other commands it receives.
+type leaseLookupDownKea struct {
+	commands []string
+}
+
+func (f *leaseLookupDownKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	if cmd.Command == "lease4-get-by-hw-address" {
+		return keamodels.Response{}, fmt.Errorf("all KEA servers failed: %w", keainterface.ErrUnreachable)
+	}
+	f.commands = append(f.commands, cmd.Command)
+	return keamodels.Response{Result: 3}, nil
+}
+
+// A failed lease lookup is not taken for a missing lease: the interface is
+// skipped and Kea reported unreachable.
+func TestReservationStages_LeaseLookupUnreachable(t *testing.T) {
+	nc := ncWithAnnotations(nil)
+	r, _ := newDeletionReconciler(t, nc)
+	kea := &leaseLookupDownKea{}
+	r.Kea = keaservice.New(kea)
+	ctx := context.Background()
+
+	res := r.processMACReservations(ctx, nc, []string{testMAC0}, []reservationTarget{{SubnetID: 1, Prefix: testOldPrefix}}, nil, nil, nil, logr.Discard())
+	if !res.unreachable || len(res.errs) != 1 {
+		t.Fatalf("expected unreachable with 1 error, got %+v", res)
+	}
+	if st := res.interfaces[testMAC0]; st == nil || st.Reason != interfaceReasonError {
+		t.Fatalf("expected %s, got %+v", interfaceReasonError, st)
+	}
+	if len(kea.commands) != 0 {
+		t.Fatalf("expected no reservation commands, got %v", kea.commands)
+	}
+	r.reportReservationStages(ctx, nc, []string{testMAC0}, res)
+	if cond := findCondition(nc.Status.Conditions, conditionTypeKeaReachable); cond == nil || cond.Reason != conditionReasonUnreachable {
+		t.Fatalf("expected KeaReachable/%s, got %+v", conditionReasonUnreachable, cond)
+	}
+}
+
+func TestReservationStages_InvalidRequest(t *testing.T) {
+	nc := ncWithAnnotations(nil)
+	r, _ := newMigrationReconciler(t, nc)
+	ctx := context.Background()
+	macs := []string{testMAC0}
+	requested := map[string]string{testMAC0: "192.168.1.10"}
+
+	res := r.processMACReservations(ctx, nc, macs, []reservationTarget{{SubnetID: 1, Prefix: testOldPrefix}}, requested, nil, nil, logr.Discard())
+	if st := res.interfaces[testMAC0]; st == nil || st.Reason != interfaceReasonInvalidRequest {
+		t.Fatalf("expected %s, got %+v", interfaceReasonInvalidRequest, st)
+	}
+	r.reportReservationStages(ctx, nc, macs, res)
+
+	if cond := findCondition(nc.Status.Conditions, conditionTypeReservationsReady); cond == nil || cond.Reason != conditionReasonInvalidRequest {
+		t.Fatalf("expected ReservationsReady/%s, got %+v", conditionReasonInvalidRequest, cond)
+	}
+	if cond := findCondition(nc.Status.Conditions, conditionTypeKeaReachable); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Fatalf("expected KeaReachable True, got %+v", cond)
+	}
+}
+
+func TestReportReservationStages_Reserved(t *testing.T) {
+	nc := ncWithAnnotations(nil)
+	r, _ := newMigrationReconciler(t, nc)
+	res := reservationResult{
+		macToIP:       map[string]string{testMAC0: "10.0.0.10"},
+		macToSubnetID: map[string]int{testMAC0: 1},
+		interfaces:    map[string]*interfaceStatus{testMAC0: {MAC: testMAC0, Reason: interfaceReasonReserved, Leased: true}},
+	}
+	r.reportReservationStages(context.Background(), nc, []string{testMAC0}, res)
+
+	for condType, reason := range map[string]string{
+		conditionTypeKeaReachable:      conditionReasonReachable,
+		conditionTypeReservationsReady: conditionReasonReserved,
+		conditionTypeLeasesObserved:    conditionReasonLeasesObserved,
+	} {
+		cond := findCondition(nc.Status.Conditions, condType)
+		if cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != reason {
+			t.Errorf("expected %s True/%s, got %+v", condType, reason, cond)
+		}
+	}
+}
+
+func TestNewLeaseStatus(t *testing.T) {
+	now := time.Unix(10000, 0)
+	lease := &keaservice.LeaseInfo{IPAddress: "10.0.0.10", SubnetID: 1, Hostname: "host", Expires: now.Add(-time.Second), Peer: "http://kea-b"}
+	ls := newLeaseStatus(lease, now)
+	if ls == nil || !ls.Expired || ls.State != "default" || ls.Peer != "http://kea-b" || ls.Hostname != "host" {
+		t.Fatalf("unexpected lease status %+v", ls)
+	}
+	if ls := newLeaseStatus(&keaservice.LeaseInfo{IPAddress: "10.0.0.10"}, now); ls.Expires != nil || ls.Expired {
+		t.Fatalf("expected no expiry without cltt/valid-lft, got %+v", ls)
+	}
+	if newLeaseStatus(nil, now) != nil {
+		t.Fatal("expected nil for no lease")
+	}
+}
+
+// TestProcessMACReservations_SeedsLease verifies that a requested address gets
+// a lease seeded for a MAC without one, and that a MAC with a lease does not.
+func TestProcessMACReservations_SeedsLease(t *testing.T) {
+	nc := ncWithAnnotations(nil)
+	r, _ := newMigrationReconciler(t, nc)
+	kea := &leasesKea{leases: map[string][]map[string]any{
+		testMAC1: {{"hw-address": testMAC1, "ip-address": "10.0.0.11", "subnet-id": 1}},
+	}}
+	r.Kea = keaservice.New(kea)
+	requested := map[string]string{testMAC0: "10.0.0.10", testMAC1: "10.0.0.11"}
+	hosts := map[string]keamodels.HostParams{testMAC0: {Hostname: "node-1"}}
+	targets := []reservationTarget{{SubnetID: 1, Prefix: testOldPrefix, SeedLeases: true, SeedLifetime: 600}}
+
+	res := r.processMACReservations(context.Background(), nc, []string{testMAC0, testMAC1}, targets, requested, hosts, nil, logr.Discard())
+	if len(res.errs) != 0 || len(res.conflicts) != 0 {
+		t.Fatalf("unexpected errors %v, conflicts %v", res.errs, res.conflicts)
+	}
+	var seeded []map[string]any
+	for _, cmd := range kea.commands {
+		if cmd.Command == "lease4-add" {
+			seeded = append(seeded, cmd.Args)
+		}
+	}
+	if len(seeded) != 1 || seeded[0]["hw-address"] != testMAC0 || seeded[0]["hostname"] != "node-1" || seeded[0]["valid-lft"] != 600 {
+		t.Fatalf("expected one lease seeded for %s, got %v", testMAC0, seeded)
+	}
+}
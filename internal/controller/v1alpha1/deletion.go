@@ -0,0 +1,268 @@
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	viticommonconditions "github.com/vitistack/common/pkg/operator/conditions"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// conditionTypeDeleting is True while reservation cleanup for a deleted
+	// NetworkConfiguration is failing and being retried.
+	conditionTypeDeleting          = "Deleting"
+	conditionReasonCleanupRetrying = "CleanupRetrying"
+
+	eventReasonFinalizerForceRemoved = "FinalizerForceRemoved"
+	eventReasonLeaseReleased         = "LeaseReleased"
+	eventReasonLeaseKept             = "LeaseKept"
+	eventReasonReservationKept       = "ReservationKept"
+	eventReasonLeaseReleaseFailed    = "LeaseReleaseFailed"
+	eventReasonInvalidSettings       = "InvalidSettings"
+	eventActionDelete                = "Delete"
+
+	// orphanConfigMapName is the ConfigMap, in the NetworkConfiguration's
+	// namespace, that lists reservations left in Kea by a forced deletion and
+	// leases that could not be released. Each key is a NetworkConfiguration
+	// name; the value is an orphanRecord.
+	orphanConfigMapName = "kea-operator-orphans"
+
+	// orphanReasonLeaseRelease is the orphanRecord reason for leases whose
+	// release failed after the reservations were removed.
+	orphanReasonLeaseRelease = "lease release failed"
+
+	deletionBackoffBase = 5 * time.Second
+	deletionBackoffMax  = 5 * time.Minute
+)
+
+// deletionAttempts counts failed cleanup attempts per NetworkConfiguration so
+// retries back off exponentially. It is in-memory only; a restart starts the
+// backoff over, which is harmless.
+var deletionAttempts sync.Map // map[types.NamespacedName]int
+
+// errNoNetworkNamespace is returned by getNetworkNamespace when the namespace
+// has no NetworkNamespace to fall back to.
+var errNoNetworkNamespace = errors.New("no NetworkNamespace found")
+
+// networkNamespaceGone reports whether err from getNetworkNamespace means the
+// NetworkNamespace does not exist, rather than that it could not be read.
+func networkNamespaceGone(err error) bool {
+	return errors.Is(err, errNoNetworkNamespace) || apierrors.IsNotFound(err)
+}
+
+// deleteOwnedReservations removes, in every subnet, the DHCPv4 reservations of
+// the interfaces of nc that record it as owner. It serves a
+// NetworkConfiguration without an inventory whose NetworkNamespace, and so its
+// subnet, is gone. Reservations without an owner may be someone else's and are
+// left in Kea with a ReservationKept Event. It returns records of the
+// reservations removed.
+func (r *NetworkConfigurationReconciler) deleteOwnedReservations(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration) ([]reservationRecord, error) {
+	owner := ownerKey(nc)
+	identifiers, _ := hostIdentifierByMAC(nc)
+	var records []reservationRecord
+	var errs []error
+	for _, mac := range extractMACsFromTypedNetworkConfiguration(nc) {
+		id := identifiers[mac]
+		cfg := keamodels.ReservationConfig{MAC: mac, IdentifierType: id.Type, Identifier: id.Value}
+		found, err := r.Kea.FindReservations(ctx, cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reservations of %s: %w", mac, err))
+			continue
+		}
+		for _, res := range found {
+			if res.Owner != owner {
+				if res.Owner == "" {
+					r.event(nc, corev1.EventTypeWarning, eventReasonReservationKept, eventActionDelete,
+						fmt.Sprintf("kept reservation of %s in subnet %d: it records no owner and the NetworkNamespace is gone", mac, res.SubnetID))
+				}
+				continue
+			}
+			cfg.SubnetID = res.SubnetID
+			if err := r.Kea.DeleteReservation(ctx, cfg); err != nil {
+				errs = append(errs, fmt.Errorf("reservation of %s in subnet %d: %w", mac, res.SubnetID, err))
+				continue
+			}
+			records = append(records, reservationRecord{MAC: mac, SubnetID: res.SubnetID, IdentifierType: id.Type, Identifier: id.Value})
+		}
+	}
+	return records, errors.Join(errs...)
+}
+
+// orphanRecord describes reservations that may remain in Kea after the
+// finalizer of a NetworkConfiguration was force-removed, or leases its
+// cleanup could not release.
+type orphanRecord struct {
+	Owner        string              `json:"owner"`
+	Reason       string              `json:"reason"`
+	Error        string              `json:"error"`
+	RemovedAt    time.Time           `json:"removedAt"`
+	MACs         []string            `json:"macs,omitempty"`
+	Reservations []reservationRecord `json:"reservations,omitempty"`
+	Leases       []orphanLease       `json:"leases,omitempty"`
+}
+
+// orphanLease is a DHCPv4 lease releaseLeases failed to delete. IPAddress is
+// empty when the lease could not be looked up.
+type orphanLease struct {
+	MAC       string `json:"mac"`
+	SubnetID  int    `json:"subnetID"`
+	IPAddress string `json:"ip,omitempty"`
+}
+
+// forceFinalizeReason returns why the finalizer may be removed despite failed
+// cleanup: the force-finalize annotation, or KEA_DELETION_TIMEOUT elapsed
+// since the deletion was requested. It returns "" when neither applies.
+func forceFinalizeReason(nc *vitistackcrdsv1alpha1.NetworkConfiguration) string {
+	if annotationBool(nc.GetAnnotations(), consts.ForceFinalizeAnnotation) {
+		return fmt.Sprintf("%s annotation is set", consts.ForceFinalizeAnnotation)
+	}
+	timeout := viper.GetDuration(consts.KEA_DELETION_TIMEOUT)
+	if ts := nc.GetDeletionTimestamp(); timeout > 0 && ts != nil && time.Since(ts.Time) >= timeout {
+		return fmt.Sprintf("cleanup did not succeed within %s", timeout)
+	}
+	return ""
+}
+
+// releaseLeasesOnDelete reports whether the leases of a deleted
+// NetworkConfiguration in nn are released with its reservations:
+// KEA_RELEASE_LEASES_ON_DELETE, replaced by the release-leases-on-delete
+// annotation of nn when present. nn is nil when the NetworkNamespace is gone.
+// A value that is not a boolean releases nothing and is returned as an error.
+func releaseLeasesOnDelete(nn *vitistackcrdsv1alpha1.NetworkNamespace) (bool, error) {
+	source, value := consts.KEA_RELEASE_LEASES_ON_DELETE, viper.GetString(consts.KEA_RELEASE_LEASES_ON_DELETE)
+	if nn != nil {
+		if v, ok := nn.GetAnnotations()[consts.ReleaseLeasesOnDeleteAnnotation]; ok {
+			source, value = consts.ReleaseLeasesOnDeleteAnnotation, v
+		}
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false, nil
+	}
+	release, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s: %q is not \"true\" or \"false\"", source, value)
+	}
+	return release, nil
+}
+
+// releaseLeases deletes the DHCPv4 lease the MAC of each record holds in the
+// recorded subnet. A lease renewed after since, when the deletion started, is
+// kept: the host is still up, or the MAC already belongs to another one. The
+// leases it fails to look up or delete are returned with the joined errors.
+func (r *NetworkConfigurationReconciler) releaseLeases(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, records []reservationRecord, since time.Time) ([]orphanLease, error) {
+	var failed []orphanLease
+	var errs []error
+	for _, rec := range records {
+		if rec.Family == familyIPv6 {
+			continue
+		}
+		lease, err := r.recordedLease(ctx, rec)
+		if err != nil {
+			failed = append(failed, orphanLease{MAC: rec.MAC, SubnetID: rec.SubnetID, IPAddress: rec.IPAddress})
+			errs = append(errs, fmt.Errorf("lease of %s: %w", rec.MAC, err))
+			continue
+		}
+		if lease == nil {
+			continue
+		}
+		if lease.Renewed.After(since) {
+			r.event(nc, corev1.EventTypeNormal, eventReasonLeaseKept, eventActionDelete,
+				fmt.Sprintf("kept lease of %s on %s, renewed at %s after deletion started", rec.MAC, lease.IPAddress, lease.Renewed.Format(time.RFC3339)))
+			continue
+		}
+		if err := r.Kea.DeleteLease(ctx, lease.IPAddress); err != nil {
+			failed = append(failed, orphanLease{MAC: rec.MAC, SubnetID: rec.SubnetID, IPAddress: lease.IPAddress})
+			errs = append(errs, fmt.Errorf("lease of %s on %s: %w", rec.MAC, lease.IPAddress, err))
+			continue
+		}
+		r.event(nc, corev1.EventTypeNormal, eventReasonLeaseReleased, eventActionDelete,
+			fmt.Sprintf("released lease of %s on %s in subnet %d", rec.MAC, lease.IPAddress, rec.SubnetID))
+	}
+	return failed, errors.Join(errs...)
+}
+
+// recordOrphans adds an entry for nc to the orphan ConfigMap in its namespace
+// so the reservations it may have left behind can be swept later.
+func (r *NetworkConfigurationReconciler) recordOrphans(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, reason string, cleanupErr error) error {
+	return r.writeOrphanRecord(ctx, nc, orphanRecord{
+		Owner:        ownerKey(nc),
+		Reason:       reason,
+		Error:        cleanupErr.Error(),
+		RemovedAt:    time.Now().UTC().Truncate(time.Second),
+		MACs:         extractMACsFromTypedNetworkConfiguration(nc),
+		Reservations: readReservationRecords(nc),
+	})
+}
+
+// recordOrphanLeases adds an entry for nc to the orphan ConfigMap listing the
+// leases releaseLeases could not delete, so they can be swept later.
+func (r *NetworkConfigurationReconciler) recordOrphanLeases(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, leases []orphanLease, releaseErr error) error {
+	return r.writeOrphanRecord(ctx, nc, orphanRecord{
+		Owner:     ownerKey(nc),
+		Reason:    orphanReasonLeaseRelease,
+		Error:     releaseErr.Error(),
+		RemovedAt: time.Now().UTC().Truncate(time.Second),
+		Leases:    leases,
+	})
+}
+
+// writeOrphanRecord stores rec under the name of nc in the orphan ConfigMap
+// of its namespace, creating the ConfigMap if needed.
+func (r *NetworkConfigurationReconciler) writeOrphanRecord(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, rec orphanRecord) error {
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: nc.GetNamespace(), Name: orphanConfigMapName}
+	if err := r.Get(ctx, key, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: key.Namespace,
+				Name:      key.Name,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "kea-operator"},
+			},
+			Data: map[string]string{nc.GetName(): string(raw)},
+		}
+		return r.Create(ctx, cm)
+	}
+
+	base := cm.DeepCopy()
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[nc.GetName()] = string(raw)
+	return r.Patch(ctx, cm, client.MergeFrom(base))
+}
+
+// setDeletingCondition reports a failed cleanup attempt on the Deleting condition.
+func (r *NetworkConfigurationReconciler) setDeletingCondition(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, attempt int, cleanupErr error) {
+	msg := fmt.Sprintf("reservation cleanup failed (attempt %d), retrying: %v", attempt, cleanupErr)
+	if timeout := viper.GetDuration(consts.KEA_DELETION_TIMEOUT); timeout > 0 {
+		msg += fmt.Sprintf("; the finalizer is removed after %s or when %s is set", timeout, consts.ForceFinalizeAnnotation)
+	} else {
+		msg += fmt.Sprintf("; set %s to remove the finalizer anyway", consts.ForceFinalizeAnnotation)
+	}
+	_ = r.setCondition(ctx, nc, viticommonconditions.New(
+		conditionTypeDeleting, metav1.ConditionTrue, conditionReasonCleanupRetrying, msg, nc.GetGeneration(),
+	))
+}
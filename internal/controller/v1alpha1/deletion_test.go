@@ -0,0 +1,360 @@
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/interfaces/keainterface"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// unreachableKea fails every request, as during a Kea outage.
+type unreachableKea struct{}
+
+func (unreachableKea) Send(context.Context, keamodels.Request) (keamodels.Response, error) {
+	return keamodels.Response{}, fmt.Errorf("all KEA servers failed: %w", keainterface.ErrUnreachable)
+}
+
+func deletingNC(annotations map[string]string) *vitistackcrdsv1alpha1.NetworkConfiguration {
+	now := metav1.Now()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[consts.ReservationsAnnotation] = `[{"mac":"` + testMAC0 + `","subnetID":1,"prefix":"` + testOldPrefix + `","created":"2025-01-01T00:00:00Z"}]`
+	return &vitistackcrdsv1alpha1.NetworkConfiguration{ObjectMeta: metav1.ObjectMeta{
+		Namespace:         testNamespace,
+		Name:              "nc",
+		Finalizers:        []string{finalizerName},
+		DeletionTimestamp: &now,
+		Annotations:       annotations,
+	}}
+}
+
+func newDeletionReconciler(t *testing.T, nc *vitistackcrdsv1alpha1.NetworkConfiguration) (*NetworkConfigurationReconciler, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := vitistackcrdsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(nc).WithStatusSubresource(nc).Build()
+	kea := unreachableKea{}
+	return &NetworkConfigurationReconciler{Client: c, KeaClient: kea, Kea: keaservice.New(kea)}, c
+}
+
+func TestHandleDeletion_RetriesWhileKeaUnavailable(t *testing.T) {
+	nc := deletingNC(nil)
+	r, c := newDeletionReconciler(t, nc)
+	t.Cleanup(func() { deletionAttempts.Delete(client.ObjectKeyFromObject(nc)) })
+
+	res, err := r.handleDeletion(context.Background(), nc, logr.Discard())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.RequeueAfter <= 0 || res.RequeueAfter > deletionBackoffMax {
+		t.Fatalf("expected a backoff requeue, got %+v", res)
+	}
+	if cond := findCondition(nc.Status.Conditions, conditionTypeDeleting); cond == nil || cond.Reason != conditionReasonCleanupRetrying {
+		t.Fatalf("expected Deleting/%s, got %+v", conditionReasonCleanupRetrying, cond)
+	}
+
+	var got vitistackcrdsv1alpha1.NetworkConfiguration
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(nc), &got); err != nil {
+		t.Fatalf("expected NetworkConfiguration to be kept, got %v", err)
+	}
+}
+
+func TestHandleDeletion_ForceFinalize(t *testing.T) {
+	nc := deletingNC(map[string]string{consts.ForceFinalizeAnnotation: "true"})
+	r, c := newDeletionReconciler(t, nc)
+
+	if _, err := r.handleDeletion(context.Background(), nc, logr.Discard()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var cm corev1.ConfigMap
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: orphanConfigMapName}, &cm); err != nil {
+		t.Fatalf("expected orphan ConfigMap: %v", err)
+	}
+	if rec := cm.Data["nc"]; !strings.Contains(rec, testMAC0) || !strings.Contains(rec, testNamespace+"/nc") {
+		t.Fatalf("expected orphan record for %s, got %q", testMAC0, rec)
+	}
+
+	var got vitistackcrdsv1alpha1.NetworkConfiguration
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(nc), &got); err == nil && len(got.Finalizers) > 0 {
+		t.Fatalf("expected finalizer to be removed, got %v", got.Finalizers)
+	}
+}
+
+// leasesKea answers lease4-get-by-hw-address and lease4-get from leases,
+// keyed by MAC, finds nothing for other lookups and records the other
+// commands it receives. failDel fails every lease4-del.
+type leasesKea struct {
+	leases   map[string][]map[string]any
+	commands []keamodels.Request
+	failDel  bool
+}
+
+func (f *leasesKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	switch cmd.Command {
+	case "lease4-get-by-hw-address":
+		leases, ok := f.leases[cmd.Args["hw-address"].(string)]
+		if !ok {
+			return keamodels.Response{Result: 3}, nil
+		}
+		found := make([]any, 0, len(leases))
+		for _, lease := range leases {
+			found = append(found, lease)
+		}
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"leases": found}}, nil
+	case "lease4-get":
+		for _, leases := range f.leases {
+			for _, lease := range leases {
+				if lease["ip-address"] == cmd.Args["ip-address"] {
+					return keamodels.Response{Result: 0, Arguments: lease}, nil
+				}
+			}
+		}
+		return keamodels.Response{Result: 3}, nil
+	}
+	if strings.Contains(cmd.Command, "-get") {
+		return keamodels.Response{Result: 3}, nil
+	}
+	f.commands = append(f.commands, cmd)
+	if f.failDel && cmd.Command == "lease4-del" {
+		return keamodels.Response{Result: 1, Text: "lease database unavailable"}, nil
+	}
+	return keamodels.Response{Result: 0}, nil
+}
+
+func TestCleanupReservations_ReleasesLeases(t *testing.T) {
+	t.Cleanup(func() { viper.Set(consts.KEA_RELEASE_LEASES_ON_DELETE, nil) })
+	viper.Set(consts.KEA_RELEASE_LEASES_ON_DELETE, true)
+	nc := deletingNC(nil)
+	nc.Annotations[consts.ReservationsAnnotation] = `[` +
+		`{"mac":"` + testMAC0 + `","subnetID":1,"prefix":"` + testOldPrefix + `","created":"2025-01-01T00:00:00Z"},` +
+		`{"mac":"` + testMAC1 + `","subnetID":1,"prefix":"` + testOldPrefix + `","created":"2025-01-01T00:00:00Z"}]`
+	r, _ := newDeletionReconciler(t, nc)
+	started := nc.DeletionTimestamp.Unix()
+	kea := &leasesKea{leases: map[string][]map[string]any{
+		testMAC0: {{"hw-address": testMAC0, "ip-address": "10.0.0.10", "subnet-id": 1, "cltt": started - 60, "valid-lft": 4000}},
+		// renewed after the deletion started
+		testMAC1: {{"hw-address": testMAC1, "ip-address": "10.0.0.11", "subnet-id": 1, "cltt": started + 60, "valid-lft": 4000}},
+	}}
+	r.Kea = keaservice.New(kea)
+
+	if err := r.cleanupReservations(context.Background(), nc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var deleted []string
+	for _, cmd := range kea.commands {
+		if cmd.Command == "lease4-del" {
+			deleted = append(deleted, cmd.Args["ip-address"].(string))
+		}
+	}
+	if len(deleted) != 1 || deleted[0] != "10.0.0.10" {
+		t.Fatalf("expected only the lease of %s released, got %v", testMAC0, deleted)
+	}
+}
+
+// Only the lease in the recorded subnet is released, even when the MAC holds
+// a newer one in another subnet.
+func TestCleanupReservations_ReleasesLeaseInRecordedSubnet(t *testing.T) {
+	t.Cleanup(func() { viper.Set(consts.KEA_RELEASE_LEASES_ON_DELETE, nil) })
+	viper.Set(consts.KEA_RELEASE_LEASES_ON_DELETE, true)
+	for name, ip := range map[string]string{"by address": "10.0.0.10", "by hw-address": ""} {
+		t.Run(name, func(t *testing.T) {
+			nc := deletingNC(nil)
+			nc.Annotations[consts.ReservationsAnnotation] = `[{"mac":"` + testMAC0 + `","subnetID":1,"ip":"` + ip +
+				`","prefix":"` + testOldPrefix + `","created":"2025-01-01T00:00:00Z"}]`
+			r, _ := newDeletionReconciler(t, nc)
+			started := nc.DeletionTimestamp.Unix()
+			kea := &leasesKea{leases: map[string][]map[string]any{
+				testMAC0: {
+					{"hw-address": testMAC0, "ip-address": "10.0.0.10", "subnet-id": 1, "cltt": started - 120, "valid-lft": 4000},
+					{"hw-address": testMAC0, "ip-address": "10.1.0.10", "subnet-id": 2, "cltt": started - 60, "valid-lft": 4000},
+				},
+			}}
+			r.Kea = keaservice.New(kea)
+
+			if err := r.cleanupReservations(context.Background(), nc); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var deleted []string
+			for _, cmd := range kea.commands {
+				if cmd.Command == "lease4-del" {
+					deleted = append(deleted, cmd.Args["ip-address"].(string))
+				}
+			}
+			if len(deleted) != 1 || deleted[0] != "10.0.0.10" {
+				t.Fatalf("expected only the lease in subnet 1 released, got %v", deleted)
+			}
+		})
+	}
+}
+
+// A lease4-del failure does not hold up the finalizer: the lease is recorded
+// in the orphan ConfigMap instead.
+func TestCleanupReservations_RecordsUnreleasedLeases(t *testing.T) {
+	t.Cleanup(func() { viper.Set(consts.KEA_RELEASE_LEASES_ON_DELETE, nil) })
+	viper.Set(consts.KEA_RELEASE_LEASES_ON_DELETE, true)
+	nc := deletingNC(nil)
+	r, c := newDeletionReconciler(t, nc)
+	recorder := events.NewFakeRecorder(10)
+	r.Recorder = recorder
+	started := nc.DeletionTimestamp.Unix()
+	r.Kea = keaservice.New(&leasesKea{failDel: true, leases: map[string][]map[string]any{
+		testMAC0: {{"hw-address": testMAC0, "ip-address": "10.0.0.10", "subnet-id": 1, "cltt": started - 60, "valid-lft": 4000}},
+	}})
+
+	if err := r.cleanupReservations(context.Background(), nc); err != nil {
+		t.Fatalf("expected cleanup to succeed despite the failed lease4-del, got %v", err)
+	}
+	var cm corev1.ConfigMap
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: orphanConfigMapName}, &cm); err != nil {
+		t.Fatalf("expected orphan ConfigMap: %v", err)
+	}
+	var rec orphanRecord
+	if err := json.Unmarshal([]byte(cm.Data[nc.Name]), &rec); err != nil {
+		t.Fatalf("unparsable orphan record: %v", err)
+	}
+	if rec.Reason != orphanReasonLeaseRelease || len(rec.Leases) != 1 || rec.Leases[0].IPAddress != "10.0.0.10" || rec.Leases[0].MAC != testMAC0 {
+		t.Fatalf("expected the unreleased lease of %s recorded, got %+v", testMAC0, rec)
+	}
+	if ev := <-recorder.Events; !strings.Contains(ev, eventReasonLeaseReleaseFailed) {
+		t.Fatalf("expected a %s event, got %q", eventReasonLeaseReleaseFailed, ev)
+	}
+}
+
+func TestReleaseLeasesOnDelete(t *testing.T) {
+	t.Cleanup(func() { viper.Set(consts.KEA_RELEASE_LEASES_ON_DELETE, nil) })
+	viper.Set(consts.KEA_RELEASE_LEASES_ON_DELETE, true)
+	if release, err := releaseLeasesOnDelete(nil); !release || err != nil {
+		t.Fatalf("expected the env setting without a NetworkNamespace, got %v, %v", release, err)
+	}
+	nn := testNetworkNamespace(testOldPrefix, 0, map[string]string{consts.ReleaseLeasesOnDeleteAnnotation: "false"})
+	if release, err := releaseLeasesOnDelete(nn); release || err != nil {
+		t.Fatalf("expected the annotation to override the env setting, got %v, %v", release, err)
+	}
+
+	// Non-boolean values release nothing.
+	for _, value := range []string{"always", "sometimes"} {
+		nn.Annotations[consts.ReleaseLeasesOnDeleteAnnotation] = value
+		if release, err := releaseLeasesOnDelete(nn); release || err == nil {
+			t.Fatalf("expected %q to be rejected, got %v, %v", value, release, err)
+		}
+	}
+	viper.Set(consts.KEA_RELEASE_LEASES_ON_DELETE, "sometimes")
+	if release, err := releaseLeasesOnDelete(nil); release || err == nil {
+		t.Fatalf("expected a non-boolean KEA_RELEASE_LEASES_ON_DELETE to be rejected, got %v, %v", release, err)
+	}
+}
+
+// hostsByMACKea answers reservation-get-by-id from hosts, keyed by MAC, and
+// records the other commands it receives.
+type hostsByMACKea struct {
+	hosts    map[string][]any
+	commands []keamodels.Request
+}
+
+func (f *hostsByMACKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	if cmd.Command == "reservation-get-by-id" {
+		hosts, ok := f.hosts[cmd.Args["identifier"].(string)]
+		if !ok {
+			return keamodels.Response{Result: 3}, nil
+		}
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"hosts": hosts}}, nil
+	}
+	f.commands = append(f.commands, cmd)
+	return keamodels.Response{Result: 0}, nil
+}
+
+// Without an inventory and with the NetworkNamespace gone, the reservations
+// recording the NetworkConfiguration as owner are removed wherever they are,
+// and cleanup succeeds so the finalizer can go.
+func TestCleanupReservations_NetworkNamespaceGone(t *testing.T) {
+	nc := ncWithAnnotations(nil)
+	r, _ := newDeletionReconciler(t, nc)
+	recorder := events.NewFakeRecorder(10)
+	r.Recorder = recorder
+	owner := map[string]any{"owner": ownerKey(nc)}
+	kea := &hostsByMACKea{hosts: map[string][]any{
+		testMAC0: {
+			map[string]any{"hw-address": testMAC0, "subnet-id": 3, "user-context": owner},
+			map[string]any{"hw-address": testMAC0, "subnet-id": 5},
+		},
+		testMAC1: {
+			map[string]any{"hw-address": testMAC1, "subnet-id": 3, "user-context": map[string]any{"owner": "default/other"}},
+		},
+	}}
+	r.Kea = keaservice.New(kea)
+
+	if err := r.cleanupReservations(context.Background(), nc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(kea.commands) != 1 || kea.commands[0].Command != "reservation-del" ||
+		kea.commands[0].Args["identifier"] != testMAC0 || kea.commands[0].Args["subnet-id"] != 3 {
+		t.Fatalf("expected only the owned reservation of %s in subnet 3 removed, got %+v", testMAC0, kea.commands)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected one Event for the owner-less reservation, got %d", len(recorder.Events))
+	}
+	if e := <-recorder.Events; !strings.Contains(e, eventReasonReservationKept) || !strings.Contains(e, "subnet 5") {
+		t.Fatalf("expected a %s Event for subnet 5, got %q", eventReasonReservationKept, e)
+	}
+
+	// An unreachable Kea is still retried.
+	r.Kea = keaservice.New(unreachableKea{})
+	if err := r.cleanupReservations(context.Background(), nc); err == nil {
+		t.Fatal("expected an error while Kea is unreachable")
+	}
+}
+
+// failingDelKea has subnet 1 for testOldPrefix and fails every reservation-del.
+type failingDelKea struct{}
+
+func (failingDelKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	switch cmd.Command {
+	case "subnet4-list":
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"subnets": []any{
+			map[string]any{"id": 1, "subnet": testOldPrefix},
+		}}}, nil
+	case "reservation-del":
+		return keamodels.Response{Result: 1, Text: "database error"}, nil
+	}
+	return keamodels.Response{Result: 3}, nil
+}
+
+// Without an inventory, failed deletes in the NetworkNamespace's subnet are
+// returned, so deletion is retried and, when forced, recorded as orphans.
+func TestCleanupReservations_LegacyDeleteErrors(t *testing.T) {
+	nc := deletingNC(nil)
+	delete(nc.Annotations, consts.ReservationsAnnotation)
+	nc.Spec.NetworkInterfaces = []vitistackcrdsv1alpha1.NetworkConfigurationInterface{{Name: "eth0", MacAddress: testMAC0}}
+	r, c := newDeletionReconciler(t, nc)
+	if err := c.Create(context.Background(), testNetworkNamespace(testOldPrefix, 0, nil)); err != nil {
+		t.Fatal(err)
+	}
+	r.Kea = keaservice.New(failingDelKea{})
+
+	err := r.cleanupReservations(context.Background(), nc)
+	if err == nil || !strings.Contains(err.Error(), "database error") {
+		t.Fatalf("expected the reservation-del error, got %v", err)
+	}
+}
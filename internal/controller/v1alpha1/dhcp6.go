@@ -0,0 +1,136 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	subnetutil "github.com/vitistack/kea-operator/internal/util/subnet"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// familyIPv6 marks kea-dhcp6 reservations in the reservation inventory.
+const familyIPv6 = 6
+
+// DHCPv6Ready reports the DHCPv6 side of a dual-stack NetworkNamespace. It is
+// only set with ENABLE_DHCP6 when the NetworkNamespace has status.ipv6Prefix,
+// and does not affect Ready.
+const conditionTypeDHCPv6Ready = "DHCPv6Ready"
+
+// dhcp6Enabled reports whether ENABLE_DHCP6 is set.
+func dhcp6Enabled() bool {
+	return viper.GetBool(consts.ENABLE_DHCP6)
+}
+
+// dhcp6Result is the outcome of reconcileDHCPv6.
+type dhcp6Result struct {
+	prefix  string
+	macToIP map[string]string
+	records []reservationRecord
+	errs    []string
+}
+
+// reconcileDHCPv6 makes the kea-dhcp6 subnet for the NetworkNamespace IPv6
+// prefix and a reservation per interface, keyed on the DUID from the
+// dhcp6-duid annotation or else the MAC. Addresses Kea has leased are pinned
+// like their DHCPv4 counterparts; without a lease the reservation carries no
+// address. It returns nil without ENABLE_DHCP6 or when the NetworkNamespace
+// has no IPv6 prefix.
+func (r *NetworkConfigurationReconciler) reconcileDHCPv6(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, nn *vitistackcrdsv1alpha1.NetworkNamespace, macs []string, log logr.Logger) *dhcp6Result {
+	prefix := strings.TrimSpace(nn.Status.IPv6Prefix)
+	if !dhcp6Enabled() || prefix == "" {
+		return nil
+	}
+	res := &dhcp6Result{prefix: prefix, macToIP: make(map[string]string)}
+	fail := func(reason string, err error) *dhcp6Result {
+		res.errs = append(res.errs, fmt.Sprintf("DHCPv6: %v", err))
+		r.setStage(ctx, nc, conditionTypeDHCPv6Ready, false, reason, err.Error())
+		return res
+	}
+
+	duids, err := dhcp6DUIDByMAC(nc)
+	if err != nil {
+		return fail(conditionReasonInvalidRequest, err)
+	}
+	pools, err := subnetutil.CalculatePool6(prefix)
+	if err != nil {
+		return fail(conditionReasonInvalidSettings, err)
+	}
+	pdPools, err := ipv6PDPools(nn)
+	if err != nil {
+		return fail(conditionReasonInvalidSettings, err)
+	}
+	_, ipnet, _ := net.ParseCIDR(prefix)
+
+	subnetID, created, err := r.Kea.GetOrCreateSubnet6(ctx, keamodels.Subnet6Config{
+		Subnet:  prefix,
+		Pools:   keaPools(pools),
+		PDPools: pdPools,
+	})
+	if err != nil {
+		log.Error(err, "failed to get or create Kea DHCPv6 subnet", "ipv6Prefix", prefix)
+		return fail(conditionReasonSubnetError, err)
+	}
+	if created {
+		log.Info("created new Kea DHCPv6 subnet", "subnet", prefix, "subnetID", subnetID)
+		r.event(nc, corev1.EventTypeNormal, eventReasonSubnetCreated, eventActionCreateSubnet,
+			fmt.Sprintf("created Kea DHCPv6 subnet %d for %s", subnetID, prefix))
+	}
+
+	owner := ownerKey(nc)
+	now := time.Now().UTC().Truncate(time.Second)
+	var failed []string
+	for _, mac := range macs {
+		duid := duids[mac]
+		var ip string
+		if lease, _ := r.Kea.GetLease6(ctx, subnetID, mac, duid); lease != nil {
+			if p := net.ParseIP(lease.IPAddress); p != nil && p.To4() == nil && ipnet.Contains(p) {
+				ip = p.String()
+			}
+		}
+		action, err := r.Kea.EnsureReservation6(ctx, keamodels.ReservationConfig{
+			MAC: mac, DUID: duid, SubnetID: subnetID, IPAddress: ip, Owner: owner,
+		})
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", mac, err))
+			continue
+		}
+		if ip != "" {
+			res.macToIP[mac] = ip
+		}
+		res.records = append(res.records, reservationRecord{
+			MAC: mac, SubnetID: subnetID, IPAddress: ip, Family: familyIPv6, DUID: duid,
+			Prefix: prefix, Peer: r.Kea.Peer6(), Created: now,
+		})
+		switch {
+		case ip == "" || action == keaservice.ReservationUnchanged:
+		case action == keaservice.ReservationCreated:
+			log.Info("configured DHCPv6 reservation with IP", "mac", mac, "duid", duid, "ip", ip, "subnetID", subnetID)
+			r.event(nc, corev1.EventTypeNormal, eventReasonReservationCreated, eventActionReserve,
+				fmt.Sprintf("reserved %s for %s in DHCPv6 subnet %d", ip, mac, subnetID))
+		default:
+			log.Info("pinned existing DHCPv6 reservation to IP", "mac", mac, "duid", duid, "ip", ip, "subnetID", subnetID)
+			r.event(nc, corev1.EventTypeNormal, eventReasonReservationPinned, eventActionReserve,
+				fmt.Sprintf("pinned DHCPv6 reservation for %s in subnet %d to %s", mac, subnetID, ip))
+		}
+	}
+
+	if len(failed) > 0 {
+		for _, f := range failed {
+			res.errs = append(res.errs, "DHCPv6 "+f)
+		}
+		r.setStage(ctx, nc, conditionTypeDHCPv6Ready, false, conditionReasonReservationFailed, strings.Join(failed, "; "))
+		return res
+	}
+	r.setStage(ctx, nc, conditionTypeDHCPv6Ready, true, conditionReasonReserved,
+		fmt.Sprintf("%d reservation(s) in DHCPv6 subnet %d for %s, %d with an address", len(res.records), subnetID, prefix, len(res.macToIP)))
+	return res
+}
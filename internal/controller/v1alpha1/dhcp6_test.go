@@ -0,0 +1,129 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testIPv6Prefix = "2001:db8:1::/64"
+
+// dhcp6Kea is a kea-dhcp6 with no subnets or reservations yet, where only
+// testMAC0 holds a lease.
+type dhcp6Kea struct {
+	commands []keamodels.Request
+}
+
+func (f *dhcp6Kea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	f.commands = append(f.commands, cmd)
+	switch cmd.Command {
+	case "subnet6-add", "reservation-add":
+		return keamodels.Response{Result: 0}, nil
+	case "lease6-get-all":
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"leases": []any{
+			map[string]any{"type": "IA_NA", "ip-address": "2001:db8:1::1:10", "hw-address": testMAC0, "subnet-id": 1, "cltt": 100, "valid-lft": 3600},
+		}}}, nil
+	}
+	return keamodels.Response{Result: 3}, nil
+}
+
+// enableDHCP6 sets ENABLE_DHCP6 for the duration of the test.
+func enableDHCP6(t *testing.T) {
+	t.Helper()
+	viper.Set(consts.ENABLE_DHCP6, true)
+	t.Cleanup(func() { viper.Set(consts.ENABLE_DHCP6, nil) })
+}
+
+func TestReconcileDHCPv6(t *testing.T) {
+	enableDHCP6(t)
+	nc := ncWithAnnotations(map[string]string{consts.DHCP6DUIDAnnotation: "eth1=00:03:00:01:aa:bb:cc:dd:ee:01"})
+	nn := &vitistackcrdsv1alpha1.NetworkNamespace{Status: vitistackcrdsv1alpha1.NetworkNamespaceStatus{IPv6Prefix: testIPv6Prefix}}
+	r, _ := newMigrationReconciler(t, nc)
+	kea := &dhcp6Kea{}
+	r.KeaClient, r.Kea = kea, keaservice.New(kea)
+
+	res := r.reconcileDHCPv6(context.Background(), nc, nn, []string{testMAC0, testMAC1}, logr.Discard())
+	if res == nil || len(res.errs) != 0 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if got := res.macToIP[testMAC0]; got != "2001:db8:1::1:10" {
+		t.Fatalf("expected the leased address pinned for %s, got %q", testMAC0, got)
+	}
+	if len(res.records) != 2 || res.records[1].Family != familyIPv6 || res.records[1].DUID != "00:03:00:01:aa:bb:cc:dd:ee:01" {
+		t.Fatalf("unexpected records %+v", res.records)
+	}
+	for _, cmd := range kea.commands {
+		if cmd.Service != "dhcp6" {
+			t.Fatalf("expected %s to go to dhcp6, got %q", cmd.Command, cmd.Service)
+		}
+	}
+	if cond := findCondition(nc.Status.Conditions, conditionTypeDHCPv6Ready); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Fatalf("expected %s True, got %+v", conditionTypeDHCPv6Ready, cond)
+	}
+
+	statuses := r.buildStatusInterfaces(nc, nil, nil, ipv4Subnets{{Prefix: testOldPrefix}}, res)
+	if statuses[0].IPv6Subnet != testIPv6Prefix || len(statuses[0].IPv6Addresses) != 1 || len(statuses[1].IPv6Addresses) != 0 {
+		t.Fatalf("unexpected status interfaces %+v", statuses)
+	}
+}
+
+func TestReconcileDHCPv6_NoPrefix(t *testing.T) {
+	enableDHCP6(t)
+	nc := ncWithAnnotations(nil)
+	r, kea := newMigrationReconciler(t, nc)
+	if res := r.reconcileDHCPv6(context.Background(), nc, &vitistackcrdsv1alpha1.NetworkNamespace{}, []string{testMAC0}, logr.Discard()); res != nil {
+		t.Fatalf("expected nil without an IPv6 prefix, got %+v", res)
+	}
+	if len(kea.commands) != 0 {
+		t.Fatalf("expected no Kea commands, got %d", len(kea.commands))
+	}
+}
+
+// Without ENABLE_DHCP6 a dual-stack NetworkNamespace is served over DHCPv4
+// only, and DHCPv6 records are dropped without a kea-dhcp6 command.
+func TestReconcileDHCPv6_Disabled(t *testing.T) {
+	nc := ncWithAnnotations(nil)
+	nn := &vitistackcrdsv1alpha1.NetworkNamespace{Status: vitistackcrdsv1alpha1.NetworkNamespaceStatus{IPv6Prefix: testIPv6Prefix}}
+	r, kea := newMigrationReconciler(t, nc)
+	if res := r.reconcileDHCPv6(context.Background(), nc, nn, []string{testMAC0}, logr.Discard()); res != nil {
+		t.Fatalf("expected nil without ENABLE_DHCP6, got %+v", res)
+	}
+	if cond := findCondition(nc.Status.Conditions, conditionTypeDHCPv6Ready); cond != nil {
+		t.Fatalf("expected no %s condition, got %+v", conditionTypeDHCPv6Ready, cond)
+	}
+	if err := r.deleteReservation(context.Background(), reservationRecord{MAC: testMAC0, SubnetID: 1, Family: familyIPv6}); err != nil {
+		t.Fatal(err)
+	}
+	if len(kea.commands) != 0 {
+		t.Fatalf("expected no Kea commands, got %+v", kea.commands)
+	}
+}
+
+// A dual-stack inventory keeps its DHCPv6 records: they are only old once the
+// IPv6 prefix changes.
+func TestMigrateReservations_DualStack(t *testing.T) {
+	enableDHCP6(t)
+	records := []reservationRecord{
+		{MAC: testMAC0, SubnetID: 2, Prefix: testNewPrefix},
+		{MAC: testMAC0, SubnetID: 1, Prefix: testIPv6Prefix, Family: familyIPv6},
+	}
+	nc := ncWithAnnotations(nil)
+	r, kea := newMigrationReconciler(t, nc)
+
+	kept, _ := r.migrateReservations(context.Background(), nc, records, []string{testMAC0}, []string{testNewPrefix, testIPv6Prefix}, logr.Discard())
+	if len(kept) != 2 || len(kea.commands) != 0 {
+		t.Fatalf("expected both records kept and nothing deleted, got %+v and %d command(s)", kept, len(kea.commands))
+	}
+
+	kept, _ = r.migrateReservations(context.Background(), nc, records, []string{testMAC0}, []string{testNewPrefix}, logr.Discard())
+	if len(kept) != 1 || len(kea.commands) != 1 || kea.commands[0].Service != "dhcp6" {
+		t.Fatalf("expected the DHCPv6 record removed from dhcp6, got %+v and %+v", kept, kea.commands)
+	}
+}
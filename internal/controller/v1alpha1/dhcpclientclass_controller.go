@@ -0,0 +1,223 @@
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	viticommonconditions "github.com/vitistack/common/pkg/operator/conditions"
+	viticommonfinalizers "github.com/vitistack/common/pkg/operator/finalizers"
+	reconcileutil "github.com/vitistack/common/pkg/operator/reconcileutil"
+	keav1alpha1 "github.com/vitistack/kea-operator/api/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/interfaces/keainterface"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DHCPClientClassReconciler reconciles kea.vitistack.io/v1alpha1
+// DHCPClientClass resources into kea-dhcp4 client classes through the
+// class_cmds hook (class-add, class-update, class-del).
+type DHCPClientClassReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Kea      *keaservice.Service
+	Recorder events.EventRecorder
+}
+
+const (
+	clientClassFinalizerName = "kea.vitistack.io/dhcpclientclass-finalizer"
+
+	conditionReasonSynced     = "Synced"
+	conditionReasonNotManaged = "NotManaged"
+	conditionReasonInvalid    = "Invalid"
+
+	eventReasonClientClassCreated = "ClientClassCreated"
+	eventReasonClientClassUpdated = "ClientClassUpdated"
+	eventReasonClientClassRemoved = "ClientClassRemoved"
+	eventActionSyncClientClass    = "SyncClientClass"
+)
+
+// +kubebuilder:rbac:groups=kea.vitistack.io,resources=dhcpclientclasses,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=kea.vitistack.io,resources=dhcpclientclasses/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=kea.vitistack.io,resources=dhcpclientclasses/finalizers,verbs=update
+
+// Reconcile brings the Kea client class of a DHCPClientClass in line with its
+// spec and removes it when the resource is deleted. Classes added with
+// class-add live in Kea's running configuration only, so the periodic resync
+// also restores them after a Kea restart.
+func (r *DHCPClientClassReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	cc := &keav1alpha1.DHCPClientClass{}
+	if err := r.Get(ctx, req.NamespacedName, cc); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	owner := clientClassOwner(cc)
+
+	if !cc.GetDeletionTimestamp().IsZero() {
+		if !viticommonfinalizers.Has(cc, clientClassFinalizerName) {
+			return ctrl.Result{}, nil
+		}
+		name := cc.Status.ClassName
+		if name == "" {
+			name = cc.KeaClassName()
+		}
+		removed, err := r.Kea.DeleteClientClass(ctx, name, owner)
+		if err != nil {
+			log.Info("client class cleanup failed, will retry", "class", name, "error", err.Error())
+			r.setClientClassReady(ctx, cc, false, conditionReasonError, fmt.Sprintf("removing class %s: %v", name, err))
+			return ctrl.Result{RequeueAfter: RequeueDelayError}, nil
+		}
+		if removed {
+			log.Info("removed Kea client class", "class", name)
+			r.event(cc, corev1.EventTypeNormal, eventReasonClientClassRemoved, eventActionDelete,
+				fmt.Sprintf("removed Kea client class %s", name))
+		}
+		if err := viticommonfinalizers.Remove(ctx, r.Client, cc, clientClassFinalizerName); err != nil {
+			return reconcileutil.Requeue(err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !viticommonfinalizers.Has(cc, clientClassFinalizerName) {
+		if err := viticommonfinalizers.Ensure(ctx, r.Client, cc, clientClassFinalizerName); err != nil {
+			return reconcileutil.Requeue(err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	cfg, err := clientClassConfig(cc)
+	if err != nil {
+		r.setClientClassReady(ctx, cc, false, conditionReasonInvalid, err.Error())
+		return ctrl.Result{}, nil
+	}
+
+	// A renamed class is added under its new name before the old one goes.
+	action, err := r.Kea.EnsureClientClass(ctx, cfg, annotationBool(cc.GetAnnotations(), consts.AdoptClientClassAnnotation))
+	if errors.Is(err, keaservice.ErrClientClassNotManaged) {
+		log.Info("client class exists in Kea and is not managed by this resource", "class", cfg.Name)
+		r.setClientClassReady(ctx, cc, false, conditionReasonNotManaged, err.Error())
+		return ctrl.Result{RequeueAfter: RequeueDelaySuccess}, nil
+	}
+	if err != nil {
+		log.Error(err, "failed to sync Kea client class", "class", cfg.Name)
+		reason := conditionReasonError
+		if errors.Is(err, keainterface.ErrUnreachable) {
+			reason = conditionReasonUnreachable
+		}
+		r.setClientClassReady(ctx, cc, false, reason, err.Error())
+		return ctrl.Result{RequeueAfter: RequeueDelayError}, nil
+	}
+	switch action {
+	case keaservice.ClientClassCreated:
+		log.Info("created Kea client class", "class", cfg.Name)
+		r.event(cc, corev1.EventTypeNormal, eventReasonClientClassCreated, eventActionSyncClientClass,
+			fmt.Sprintf("created Kea client class %s", cfg.Name))
+	case keaservice.ClientClassUpdated:
+		log.Info("updated Kea client class", "class", cfg.Name)
+		r.event(cc, corev1.EventTypeNormal, eventReasonClientClassUpdated, eventActionSyncClientClass,
+			fmt.Sprintf("updated Kea client class %s", cfg.Name))
+	}
+
+	if prev := cc.Status.ClassName; prev != "" && prev != cfg.Name {
+		if _, err := r.Kea.DeleteClientClass(ctx, prev, owner); err != nil {
+			log.Error(err, "failed to remove renamed Kea client class", "class", prev)
+			r.setClientClassReady(ctx, cc, false, conditionReasonError, fmt.Sprintf("removing previous class %s: %v", prev, err))
+			return ctrl.Result{RequeueAfter: RequeueDelayError}, nil
+		}
+		r.event(cc, corev1.EventTypeNormal, eventReasonClientClassRemoved, eventActionSyncClientClass,
+			fmt.Sprintf("removed Kea client class %s after rename to %s", prev, cfg.Name))
+	}
+
+	r.setClientClassReady(ctx, cc, true, conditionReasonSynced, fmt.Sprintf("class %s is configured in Kea", cfg.Name))
+	return ctrl.Result{RequeueAfter: RequeueDelaySuccess}, nil
+}
+
+// clientClassOwner identifies cc as the owner recorded in the class's
+// user-context. DHCPClientClasses are cluster-scoped.
+func clientClassOwner(cc *keav1alpha1.DHCPClientClass) string {
+	return "DHCPClientClass/" + cc.GetName()
+}
+
+// clientClassConfig converts the spec of cc into the class the Kea service
+// applies. Options without a code get the code of known dhcp4 option names.
+func clientClassConfig(cc *keav1alpha1.DHCPClientClass) (keamodels.ClientClassConfig, error) {
+	cfg := keamodels.ClientClassConfig{
+		Name:           cc.KeaClassName(),
+		Test:           cc.Spec.Test,
+		OnlyIfRequired: cc.Spec.OnlyIfRequired,
+		NextServer:     cc.Spec.NextServer,
+		ServerHostname: cc.Spec.ServerHostname,
+		BootFileName:   cc.Spec.BootFileName,
+		Owner:          clientClassOwner(cc),
+	}
+	if err := validateHostParams(keamodels.HostParams{NextServer: cfg.NextServer}); err != nil {
+		return cfg, fmt.Errorf("spec.nextServer: %w", err)
+	}
+	for i, o := range cc.Spec.OptionData {
+		opt := keamodels.OptionData{Name: o.Name, Code: o.Code, Space: o.Space, Data: o.Data, CSVFormat: o.CSVFormat}
+		if opt.Name == "" && opt.Code == 0 {
+			return cfg, fmt.Errorf("spec.optionData[%d]: name or code is required", i)
+		}
+		if opt.Code == 0 && opt.Space == "" {
+			opt.Code = dhcp4OptionCodes[opt.Name]
+		}
+		cfg.Options = append(cfg.Options, opt)
+	}
+	return cfg, nil
+}
+
+// setClientClassReady sets the Ready condition and, when ready, the applied
+// class name and generation on cc.
+func (r *DHCPClientClassReconciler) setClientClassReady(ctx context.Context, cc *keav1alpha1.DHCPClientClass, ready bool, reason, message string) {
+	status := metav1.ConditionFalse
+	if ready {
+		status = metav1.ConditionTrue
+	}
+	base := cc.DeepCopy()
+	cond := viticommonconditions.New(conditionTypeReady, status, reason, message, cc.GetGeneration())
+	viticommonconditions.SetOrUpdateCondition(&cc.Status.Conditions, &cond)
+	if ready {
+		cc.Status.ClassName = cc.KeaClassName()
+		cc.Status.ObservedGeneration = cc.GetGeneration()
+	}
+	// Failures to patch are ignored; the next reconcile retries.
+	_ = r.Status().Patch(ctx, cc, client.MergeFrom(base))
+}
+
+// event records a Kubernetes Event on obj; a no-op without a recorder.
+func (r *DHCPClientClassReconciler) event(obj runtime.Object, eventtype, reason, action, note string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(obj, nil, eventtype, reason, action, "%s", note)
+}
+
+// NewDHCPClientClassReconciler constructs a DHCPClientClass reconciler that
+// shares kea with the NetworkConfiguration reconciler.
+func NewDHCPClientClassReconciler(mgr ctrl.Manager, kea *keaservice.Service) *DHCPClientClassReconciler {
+	return &DHCPClientClassReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Kea:      kea,
+		Recorder: mgr.GetEventRecorder("kea-operator"),
+	}
+}
+
+// SetupWithManager registers the controller with the manager.
+func (r *DHCPClientClassReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&keav1alpha1.DHCPClientClass{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles()}).
+		Named("dhcpclientclass").
+		Complete(r)
+}
@@ -0,0 +1,40 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	keav1alpha1 "github.com/vitistack/kea-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClientClassConfig(t *testing.T) {
+	cc := &keav1alpha1.DHCPClientClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "uefi"},
+		Spec: keav1alpha1.DHCPClientClassSpec{
+			ClassName:    "ueficlients",
+			NextServer:   "10.0.0.9",
+			BootFileName: "ipxe.efi",
+			OptionData:   []keav1alpha1.DHCPOption{{Name: "tftp-server-name", Data: "10.0.0.9"}},
+		},
+	}
+	cfg, err := clientClassConfig(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "ueficlients" || cfg.Owner != "DHCPClientClass/uefi" || cfg.BootFileName != "ipxe.efi" {
+		t.Fatalf("unexpected class %+v", cfg)
+	}
+	if len(cfg.Options) != 1 || cfg.Options[0].Code != 66 {
+		t.Fatalf("expected the option code to be filled in, got %+v", cfg.Options)
+	}
+
+	cc.Spec.NextServer = "tftp.example.org"
+	if _, err := clientClassConfig(cc); err == nil {
+		t.Error("expected an error for a non-IPv4 next server")
+	}
+	cc.Spec.NextServer = ""
+	cc.Spec.OptionData = []keav1alpha1.DHCPOption{{Data: "x"}}
+	if _, err := clientClassConfig(cc); err == nil {
+		t.Error("expected an error for an option without name or code")
+	}
+}
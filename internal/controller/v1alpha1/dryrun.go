@@ -0,0 +1,118 @@
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+	viticommonconditions "github.com/vitistack/common/pkg/operator/conditions"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// conditionTypePaused is True while the pause annotation makes the
+	// operator leave the NetworkConfiguration alone.
+	conditionTypePaused   = "Paused"
+	conditionReasonPaused = "PauseAnnotation"
+	conditionReasonResume = "Resumed"
+
+	// conditionTypeDryRun is True while Kea writes are recorded instead of
+	// sent; its message lists the commands of the last reconcile.
+	conditionTypeDryRun          = "DryRun"
+	conditionReasonDryRun        = "DryRun"
+	conditionReasonNoWrites      = "NoWrites"
+	conditionReasonDryRunOff     = "Disabled"
+	eventReasonDryRunCommand     = "DryRunCommand"
+	eventActionDryRun            = "DryRun"
+	dryRunConditionMaxCommandLen = 512
+)
+
+// dryRunEnabled reports whether Kea writes for nc are to be recorded instead
+// of sent, by KEA_DRY_RUN or the dry-run annotation.
+func dryRunEnabled(nc *vitistackcrdsv1alpha1.NetworkConfiguration) bool {
+	return viper.GetBool(consts.KEA_DRY_RUN) || annotationBool(nc.GetAnnotations(), consts.DryRunAnnotation)
+}
+
+// reportPaused keeps the Paused condition in line with the pause annotation.
+// Nothing is written for NetworkConfigurations that were never paused.
+func (r *NetworkConfigurationReconciler) reportPaused(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, paused bool) {
+	if paused {
+		_ = r.setCondition(ctx, nc, viticommonconditions.New(conditionTypePaused, metav1.ConditionTrue, conditionReasonPaused,
+			fmt.Sprintf("reconciliation is paused by the %s annotation", consts.PauseAnnotation), nc.GetGeneration()))
+		return
+	}
+	if cond := findCondition(nc.Status.Conditions, conditionTypePaused); cond != nil && cond.Status == metav1.ConditionTrue {
+		_ = r.setCondition(ctx, nc, viticommonconditions.New(conditionTypePaused, metav1.ConditionFalse, conditionReasonResume,
+			"reconciliation resumed", nc.GetGeneration()))
+	}
+}
+
+// reportDryRun publishes the commands recorded by dr on the DryRun condition
+// and, when they differ from the previous reconcile, as one Event each. A nil
+// dr clears a DryRun condition left from an earlier dry run.
+func (r *NetworkConfigurationReconciler) reportDryRun(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, dr *keaservice.DryRun) {
+	prev := findCondition(nc.Status.Conditions, conditionTypeDryRun)
+	if dr == nil {
+		if prev != nil && prev.Status == metav1.ConditionTrue {
+			_ = r.setCondition(ctx, nc, viticommonconditions.New(conditionTypeDryRun, metav1.ConditionFalse, conditionReasonDryRunOff,
+				"dry run disabled; Kea writes are sent", nc.GetGeneration()))
+		}
+		return
+	}
+
+	commands := dr.Commands()
+	if len(commands) == 0 {
+		_ = r.setCondition(ctx, nc, viticommonconditions.New(conditionTypeDryRun, metav1.ConditionTrue, conditionReasonNoWrites,
+			"dry run: no Kea changes needed", nc.GetGeneration()))
+		return
+	}
+	lines := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		lines = append(lines, describeCommand(cmd))
+	}
+	msg := "dry run, would send: " + strings.Join(lines, "; ")
+	if prev != nil && prev.Message == msg {
+		return
+	}
+	for _, line := range lines {
+		r.event(nc, corev1.EventTypeNormal, eventReasonDryRunCommand, eventActionDryRun, "would send "+line)
+	}
+	_ = r.setCondition(ctx, nc, viticommonconditions.New(conditionTypeDryRun, metav1.ConditionTrue, conditionReasonDryRun, msg, nc.GetGeneration()))
+}
+
+// dryRunDeletion reports the cleanup the deletion of nc would do and keeps
+// its finalizer, so the reservations stay in Kea until the dry run is turned
+// off and the deletion is carried out.
+func (r *NetworkConfigurationReconciler) dryRunDeletion(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, dr *keaservice.DryRun, log logr.Logger) ctrl.Result {
+	if err := r.cleanupReservations(ctx, nc); err != nil {
+		log.Info("dry run of reservation cleanup failed", "error", err.Error())
+	}
+	r.reportDryRun(ctx, nc, dr)
+	return ctrl.Result{RequeueAfter: RequeueDelaySuccess}
+}
+
+// describeCommand renders a Kea command as "command [service] {args}", shortened to
+// keep condition messages readable.
+func describeCommand(cmd keamodels.Request) string {
+	args, err := json.Marshal(cmd.Args)
+	if err != nil {
+		return cmd.Command
+	}
+	s := cmd.Command + " " + string(args)
+	if cmd.Service != "" {
+		s = cmd.Command + " [" + cmd.Service + "] " + string(args)
+	}
+	if len(s) > dryRunConditionMaxCommandLen {
+		s = s[:dryRunConditionMaxCommandLen] + "..."
+	}
+	return s
+}
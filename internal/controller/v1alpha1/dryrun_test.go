@@ -0,0 +1,90 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcile_Paused(t *testing.T) {
+	nc := deletingNC(map[string]string{consts.PauseAnnotation: "true"})
+	r, c := newDeletionReconciler(t, nc)
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(nc)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(nc), nc); err != nil {
+		t.Fatal(err)
+	}
+	if len(nc.Finalizers) != 1 {
+		t.Fatalf("expected the finalizer to be kept while paused, got %v", nc.Finalizers)
+	}
+	if cond := findCondition(nc.Status.Conditions, conditionTypePaused); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Fatalf("expected Paused True, got %+v", cond)
+	}
+}
+
+func TestHandleDeletion_DryRun(t *testing.T) {
+	nc := deletingNC(map[string]string{consts.DryRunAnnotation: "true"})
+	r, _ := newMigrationReconciler(t, nc)
+	recorder := events.NewFakeRecorder(10)
+	r.Recorder = recorder
+
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(nc)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Fatalf("expected a requeue while the dry run holds the deletion, got %+v", result)
+	}
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(nc), nc); err != nil {
+		t.Fatal(err)
+	}
+	if len(nc.Finalizers) != 1 {
+		t.Fatalf("expected the finalizer to be kept in a dry run, got %v", nc.Finalizers)
+	}
+	if kea := r.KeaClient.(*recordingKea); len(kea.commands) != 0 {
+		t.Fatalf("expected no Kea writes in a dry run, got %v", kea.commands)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected one dry-run Event, got %d", len(recorder.Events))
+	}
+	if e := <-recorder.Events; !strings.Contains(e, eventReasonDryRunCommand) || !strings.Contains(e, "reservation-del") {
+		t.Fatalf("expected a %s Event for reservation-del, got %q", eventReasonDryRunCommand, e)
+	}
+}
+
+func TestReportDryRun_SkipsUnchanged(t *testing.T) {
+	nc := ncWithAnnotations(nil)
+	r, _ := newMigrationReconciler(t, nc)
+	recorder := events.NewFakeRecorder(10)
+	r.Recorder = recorder
+	ctx := context.Background()
+
+	for range 2 {
+		dr := &keaservice.DryRun{}
+		if err := r.Kea.DeleteReservation(keaservice.WithDryRun(ctx, dr), keamodels.ReservationConfig{MAC: testMAC0, SubnetID: 1}); err != nil {
+			t.Fatal(err)
+		}
+		r.reportDryRun(ctx, nc, dr)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected the Event only once for an unchanged dry run, got %d", len(recorder.Events))
+	}
+	if cond := findCondition(nc.Status.Conditions, conditionTypeDryRun); cond == nil || cond.Reason != conditionReasonDryRun {
+		t.Fatalf("expected DryRun/%s, got %+v", conditionReasonDryRun, cond)
+	}
+
+	r.reportDryRun(ctx, nc, nil)
+	if cond := findCondition(nc.Status.Conditions, conditionTypeDryRun); cond == nil || cond.Status != metav1.ConditionFalse {
+		t.Fatalf("expected DryRun False once disabled, got %+v", cond)
+	}
+}
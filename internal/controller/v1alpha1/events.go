@@ -0,0 +1,54 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"sync"
+
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Event reasons and actions for Kea operations. Conflict Events use the
+// keaservice.ConflictKind as reason; migration and deletion Events are
+// declared next to that code.
+const (
+	eventReasonSubnetCreated      = "SubnetCreated"
+	eventReasonReservationCreated = "ReservationCreated"
+	eventReasonReservationPinned  = "ReservationPinned"
+	eventReasonReservationUpdated = "ReservationUpdated"
+	eventReasonReservationRemoved = "ReservationRemoved"
+	eventReasonLeaseNotFound      = "LeaseNotFound"
+	eventReasonLeaseSeeded        = "LeaseSeeded"
+	eventReasonKeaFailover        = "KeaFailover"
+	eventReasonStrictDefaults     = "StrictDefaultsRefused"
+	eventReasonDeprecatedDefault  = "DeprecatedDefault"
+
+	eventActionCreateSubnet = "CreateSubnet"
+	eventActionObserveLease = "ObserveLease"
+	eventActionConnect      = "Connect"
+	eventActionTriage       = "Triage"
+)
+
+// keaPeer remembers which Kea server answered the previous reconcile, so a
+// switch between HA peers can be reported once instead of only logged.
+var keaPeer struct {
+	sync.Mutex
+	last string
+}
+
+// reportPeerChange emits a KeaFailover Event on nc when Kea requests are now
+// answered by a different peer than in the previous reconcile.
+func (r *NetworkConfigurationReconciler) reportPeerChange(nc *vitistackcrdsv1alpha1.NetworkConfiguration) {
+	peer := r.Kea.Peer()
+	if peer == "" {
+		return
+	}
+	keaPeer.Lock()
+	prev := keaPeer.last
+	keaPeer.last = peer
+	keaPeer.Unlock()
+	if prev != "" && prev != peer {
+		r.event(nc, corev1.EventTypeWarning, eventReasonKeaFailover, eventActionConnect,
+			fmt.Sprintf("Kea requests are now answered by %s (previously %s)", peer, prev))
+	}
+}
@@ -0,0 +1,44 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	"k8s.io/client-go/tools/events"
+)
+
+// peerKea reports a configurable peer, like the HA-aware Kea client.
+type peerKea struct{ peer string }
+
+func (f *peerKea) Send(context.Context, keamodels.Request) (keamodels.Response, error) {
+	return keamodels.Response{Result: 3}, nil
+}
+
+func (f *peerKea) CurrentPeer() string { return f.peer }
+
+func TestReportPeerChange(t *testing.T) {
+	keaPeer.Lock()
+	keaPeer.last = ""
+	keaPeer.Unlock()
+
+	kea := &peerKea{peer: "http://kea-primary:8000"}
+	recorder := events.NewFakeRecorder(10)
+	r := &NetworkConfigurationReconciler{KeaClient: kea, Kea: keaservice.New(kea), Recorder: recorder}
+	nc := &vitistackcrdsv1alpha1.NetworkConfiguration{}
+
+	r.reportPeerChange(nc)
+	r.reportPeerChange(nc)
+	kea.peer = "http://kea-secondary:8000"
+	r.reportPeerChange(nc)
+
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected exactly one Event, got %d", len(recorder.Events))
+	}
+	if e := <-recorder.Events; !strings.Contains(e, eventReasonKeaFailover) || !strings.Contains(e, kea.peer) {
+		t.Fatalf("expected %s Event naming %s, got %q", eventReasonKeaFailover, kea.peer, e)
+	}
+}
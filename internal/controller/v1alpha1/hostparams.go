@@ -0,0 +1,183 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Keys of a host-params entry that set reservation fields rather than
+// option-data.
+const (
+	hostParamHostname       = "hostname"
+	hostParamNextServer     = "next-server"
+	hostParamServerHostname = "server-hostname"
+	hostParamBootFileName   = "boot-file-name"
+	hostParamClientClasses  = "client-classes"
+)
+
+// parseHostParams parses ";"- or newline-separated "<key>=<value>" entries.
+// hostname, next-server, server-hostname and boot-file-name set the
+// reservation fields of that name and client-classes a comma-separated list
+// of classes. Any other key is an option in the dhcp-options format; option 67
+// by code sets the boot-file-name option rather than the field.
+func parseHostParams(raw, source string) (keamodels.HostParams, error) {
+	var h keamodels.HostParams
+	var options []string
+	for entry := range strings.FieldsFuncSeq(raw, func(r rune) bool { return r == ';' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if !ok || key == "" {
+			return h, fmt.Errorf("%s: malformed entry %q, expected <key>=<value>", source, entry)
+		}
+		switch key {
+		case hostParamHostname:
+			h.Hostname = value
+		case hostParamNextServer:
+			h.NextServer = value
+		case hostParamServerHostname:
+			h.ServerHostname = value
+		case hostParamBootFileName:
+			h.BootFileName = value
+		case hostParamClientClasses:
+			h.ClientClasses = []string{}
+			for c := range strings.SplitSeq(value, ",") {
+				if c = strings.TrimSpace(c); c != "" {
+					h.ClientClasses = append(h.ClientClasses, c)
+				}
+			}
+		default:
+			options = append(options, entry)
+		}
+	}
+	var err error
+	h.Options, err = parseDHCPOptions(strings.Join(options, ";"), source)
+	return h, err
+}
+
+// mergeHostParams returns base with the fields over sets written over it.
+// Options are merged option by option.
+func mergeHostParams(base, over keamodels.HostParams) keamodels.HostParams {
+	out := base
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&out.Hostname, over.Hostname},
+		{&out.NextServer, over.NextServer},
+		{&out.ServerHostname, over.ServerHostname},
+		{&out.BootFileName, over.BootFileName},
+	} {
+		if f.src != "" {
+			*f.dst = f.src
+		}
+	}
+	if over.ClientClasses != nil {
+		out.ClientClasses = over.ClientClasses
+	}
+	out.Options = slices.Clone(base.Options)
+	for _, o := range over.Options {
+		if i := slices.IndexFunc(out.Options, o.Matches); i >= 0 {
+			out.Options[i] = o
+			continue
+		}
+		out.Options = append(out.Options, o)
+	}
+	return out
+}
+
+// hostParamsByMAC resolves the per-host reservation fields of each interface
+// of nc, keyed by normalized MAC: KEA_HOST_PARAMS, overridden by the
+// host-params annotation and then by the interface's own
+// host-params.<interface> annotation. Returns nil when none of them is set.
+func hostParamsByMAC(nc *vitistackcrdsv1alpha1.NetworkConfiguration) (map[string]keamodels.HostParams, error) {
+	annotations := nc.GetAnnotations()
+	global, err := parseHostParams(viper.GetString(consts.KEA_HOST_PARAMS), consts.KEA_HOST_PARAMS)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := parseHostParams(annotations[consts.HostParamsAnnotation], consts.HostParamsAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	base := mergeHostParams(global, shared)
+
+	resolve := interfaceMACResolver(nc)
+	perInterface := make(map[string]keamodels.HostParams)
+	for key, raw := range annotations {
+		name, ok := strings.CutPrefix(key, consts.HostParamsAnnotation+".")
+		if !ok {
+			continue
+		}
+		mac, found := resolve(name)
+		if !found {
+			return nil, fmt.Errorf("%s: %q does not match any interface name or MAC", key, name)
+		}
+		if perInterface[mac], err = parseHostParams(raw, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if isZeroHostParams(base) && len(perInterface) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]keamodels.HostParams, len(nc.Spec.NetworkInterfaces))
+	for _, iface := range nc.Spec.NetworkInterfaces {
+		mac := normalizeMAC(iface.MacAddress)
+		if mac == "" {
+			continue
+		}
+		ifaceName := iface.Name
+		if ifaceName == "" {
+			ifaceName = strings.ReplaceAll(mac, ":", "-")
+		}
+		h := expandHostParams(mergeHostParams(base, perInterface[mac]), nc.Name, ifaceName)
+		if err := validateHostParams(h); err != nil {
+			return nil, fmt.Errorf("%s: interface %s: %w", consts.HostParamsAnnotation, ifaceName, err)
+		}
+		out[mac] = h
+	}
+	return out, nil
+}
+
+// expandHostParams replaces {name} and {interface} in the string fields of h.
+func expandHostParams(h keamodels.HostParams, name, iface string) keamodels.HostParams {
+	r := strings.NewReplacer("{name}", name, "{interface}", iface)
+	h.Hostname = strings.ToLower(r.Replace(h.Hostname))
+	h.ServerHostname = r.Replace(h.ServerHostname)
+	h.BootFileName = r.Replace(h.BootFileName)
+	return h
+}
+
+// validateHostParams checks the fields Kea would reject: the hostname must be
+// a DNS name and next-server an IPv4 address.
+func validateHostParams(h keamodels.HostParams) error {
+	if h.Hostname != "" {
+		if errs := validation.IsDNS1123Subdomain(h.Hostname); len(errs) > 0 {
+			return fmt.Errorf("hostname %q: %s", h.Hostname, strings.Join(errs, ", "))
+		}
+	}
+	if h.NextServer != "" {
+		if ip := net.ParseIP(h.NextServer); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("next-server %q is not an IPv4 address", h.NextServer)
+		}
+	}
+	return nil
+}
+
+// isZeroHostParams reports whether h sets no field.
+func isZeroHostParams(h keamodels.HostParams) bool {
+	return h.Hostname == "" && h.NextServer == "" && h.ServerHostname == "" && h.BootFileName == "" &&
+		len(h.ClientClasses) == 0 && len(h.Options) == 0
+}
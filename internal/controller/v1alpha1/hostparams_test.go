@@ -0,0 +1,64 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseHostParams(t *testing.T) {
+	h, err := parseHostParams("hostname=node-1; boot-file-name=http://boot/ipxe?mac=${mac}\nclient-classes=uefi, ipxe;ntp-servers=10.0.0.5", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Hostname != "node-1" || h.BootFileName != "http://boot/ipxe?mac=${mac}" {
+		t.Fatalf("unexpected fields %+v", h)
+	}
+	if len(h.ClientClasses) != 2 || h.ClientClasses[1] != "ipxe" {
+		t.Fatalf("unexpected client classes %v", h.ClientClasses)
+	}
+	if len(h.Options) != 1 || h.Options[0].Code != 42 {
+		t.Fatalf("unexpected options %+v", h.Options)
+	}
+	if _, err := parseHostParams("hostname", "test"); err == nil {
+		t.Error("expected an error for an entry without a value")
+	}
+}
+
+func TestHostParamsByMAC_Overrides(t *testing.T) {
+	viper.Set(consts.KEA_HOST_PARAMS, "hostname={name}-{interface};next-server=10.0.0.9")
+	t.Cleanup(func() { viper.Set(consts.KEA_HOST_PARAMS, nil) })
+	nc := &vitistackcrdsv1alpha1.NetworkConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Annotations: map[string]string{
+			consts.HostParamsAnnotation:                        "boot-file-name=bios/{name}.ipxe",
+			consts.HostParamsAnnotation + ".aa-bb-cc-dd-ee-02": "hostname=ipmi-{name};boot-file-name=uefi/{name}.ipxe",
+		}},
+		Spec: vitistackcrdsv1alpha1.NetworkConfigurationSpec{NetworkInterfaces: []vitistackcrdsv1alpha1.NetworkConfigurationInterface{
+			{Name: "eth0", MacAddress: "AA:BB:CC:DD:EE:01"},
+			{MacAddress: "aa:bb:cc:dd:ee:02"},
+		}},
+	}
+	hosts, err := hostParamsByMAC(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := hosts["aa:bb:cc:dd:ee:01"]; h.Hostname != "node-1-eth0" || h.BootFileName != "bios/node-1.ipxe" || h.NextServer != "10.0.0.9" {
+		t.Fatalf("unexpected host params for eth0 %+v", h)
+	}
+	if h := hosts["aa:bb:cc:dd:ee:02"]; h.Hostname != "ipmi-node-1" || h.BootFileName != "uefi/node-1.ipxe" || h.NextServer != "10.0.0.9" {
+		t.Fatalf("unexpected host params for the second interface %+v", h)
+	}
+
+	nc.Annotations[consts.HostParamsAnnotation+".eth9"] = "hostname=x"
+	if _, err := hostParamsByMAC(nc); err == nil {
+		t.Error("expected an error for an unknown interface")
+	}
+	delete(nc.Annotations, consts.HostParamsAnnotation+".eth9")
+	nc.Annotations[consts.HostParamsAnnotation] = "hostname=not_a_hostname"
+	if _, err := hostParamsByMAC(nc); err == nil {
+		t.Error("expected an error for an invalid hostname")
+	}
+}
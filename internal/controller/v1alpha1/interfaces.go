@@ -0,0 +1,132 @@
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Per-interface reasons published in the interface-status annotation.
+const (
+	interfaceReasonReserved       = "Reserved"
+	interfaceReasonAwaitingLease  = "AwaitingLease"
+	interfaceReasonConflict       = "Conflict"
+	interfaceReasonInvalidRequest = "InvalidRequest"
+	interfaceReasonVLANMismatch   = "VLANMismatch"
+	interfaceReasonError          = "Error"
+)
+
+// interfaceStatus is the per-interface outcome of a reconcile. The shared
+// NetworkConfiguration status type has no room for it, so it is published as
+// JSON in the interface-status annotation.
+type interfaceStatus struct {
+	Name    string `json:"name,omitempty"`
+	MAC     string `json:"mac"`
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+	// Leased reports whether Kea currently has an active lease for the MAC.
+	Leased bool `json:"leased"`
+	// Lease describes the newest lease Kea holds for the MAC, active or not.
+	Lease *leaseStatus `json:"lease,omitempty"`
+}
+
+// leaseStatus is the lease metadata reported per interface, taken from
+// lease4-get-by-hw-address.
+type leaseStatus struct {
+	IPAddress string     `json:"ip"`
+	SubnetID  int        `json:"subnetID,omitempty"`
+	Expires   *time.Time `json:"expires,omitempty"`
+	// Expired is set once Expires has passed without a renewal.
+	Expired  bool   `json:"expired,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	State    string `json:"state"`
+	ClientID string `json:"clientID,omitempty"`
+	Peer     string `json:"peer,omitempty"`
+}
+
+// newLeaseStatus converts a Kea lease into its reported form, or returns nil
+// for no lease.
+func newLeaseStatus(lease *keaservice.LeaseInfo, now time.Time) *leaseStatus {
+	if lease == nil {
+		return nil
+	}
+	ls := &leaseStatus{
+		IPAddress: lease.IPAddress,
+		SubnetID:  lease.SubnetID,
+		Hostname:  lease.Hostname,
+		State:     lease.StateName(),
+		ClientID:  lease.ClientID,
+		Peer:      lease.Peer,
+	}
+	if !lease.Expires.IsZero() {
+		expires := lease.Expires
+		ls.Expires = &expires
+		ls.Expired = !now.Before(expires)
+	}
+	return ls
+}
+
+// interfaceStatuses orders the per-MAC results by spec.networkInterfaces and
+// fills in interface names.
+func interfaceStatuses(nc *vitistackcrdsv1alpha1.NetworkConfiguration, byMAC map[string]*interfaceStatus) []interfaceStatus {
+	out := make([]interfaceStatus, 0, len(byMAC))
+	seen := make(map[string]bool, len(byMAC))
+	for _, iface := range nc.Spec.NetworkInterfaces {
+		mac := normalizeMAC(iface.MacAddress)
+		st, ok := byMAC[mac]
+		if !ok || seen[mac] {
+			continue
+		}
+		seen[mac] = true
+		st.Name = iface.Name
+		out = append(out, *st)
+	}
+	return out
+}
+
+// writeManagedAnnotation stores value as JSON in annotation key on nc, removing
+// the annotation when value is empty, and skips the patch when nothing changed.
+func (r *NetworkConfigurationReconciler) writeManagedAnnotation(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, key string, value any, empty bool) error {
+	encoded := ""
+	if !empty {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		encoded = string(raw)
+	}
+	if nc.GetAnnotations()[key] == encoded {
+		return nil
+	}
+
+	base := nc.DeepCopy()
+	updated := nc.DeepCopy()
+	annotations := updated.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	if encoded == "" {
+		delete(annotations, key)
+	} else {
+		annotations[key] = encoded
+	}
+	updated.SetAnnotations(annotations)
+
+	if err := r.Patch(ctx, updated, client.MergeFrom(base)); err != nil {
+		return err
+	}
+	nc.SetAnnotations(updated.GetAnnotations())
+	nc.SetResourceVersion(updated.GetResourceVersion())
+	return nil
+}
+
+// writeInterfaceStatuses publishes the per-interface outcome in the
+// interface-status annotation.
+func (r *NetworkConfigurationReconciler) writeInterfaceStatuses(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, statuses []interfaceStatus) error {
+	return r.writeManagedAnnotation(ctx, nc, consts.InterfaceStatusAnnotation, statuses, len(statuses) == 0)
+}
@@ -0,0 +1,187 @@
+package v1alpha1
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	reconcileutil "github.com/vitistack/common/pkg/operator/reconcileutil"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// MACAddressIndex indexes NetworkConfigurations by the normalized MAC of each
+// interface so the lease watcher can find the owner of a lease.
+const MACAddressIndex = "spec.networkInterfaces.macAddress"
+
+// IndexMACAddresses is the indexer func for MACAddressIndex.
+func IndexMACAddresses(obj client.Object) []string {
+	nc, ok := obj.(*vitistackcrdsv1alpha1.NetworkConfiguration)
+	if !ok {
+		return nil
+	}
+	return extractMACsFromTypedNetworkConfiguration(nc)
+}
+
+// leaseWatcher reads, on an interval, one lease4-get-page page of Kea's IPv4
+// leases, continuing where the previous page ended and starting over after
+// the last one. It sends a GenericEvent for every NetworkConfiguration still
+// waiting for an address (see awaitingLeaseAttempts) whose MAC holds a lease
+// in one of its recorded subnets with a cltt later than last seen. It feeds a
+// channel source on the controller, so a host that just got a lease is
+// reconciled right away instead of on the next poll.
+type leaseWatcher struct {
+	client   client.Reader
+	kea      *keaservice.Service
+	interval time.Duration
+	pageSize int
+	events   chan<- event.GenericEvent
+
+	// from is the address the next page follows; "" starts at the lowest.
+	from string
+	// started is the cltt a lease must pass when its MAC has none in renewed;
+	// older leases were seen by the reconciles at startup.
+	started time.Time
+	// renewed maps each awaited MAC to the cltt of the last lease enqueued
+	// for it.
+	renewed map[string]time.Time
+}
+
+// NeedLeaderElection keeps the watcher on the leader, where the reconciles it
+// triggers run.
+func (w *leaseWatcher) NeedLeaderElection() bool { return true }
+
+// Start implements manager.Runnable.
+func (w *leaseWatcher) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("lease-watcher")
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.scan(ctx); err != nil {
+			log.V(1).Info("lease scan failed, retrying on the next interval", "error", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan reads the next page of leases and enqueues the owners of the awaited
+// MACs whose lease in a recorded subnet was renewed since it was last seen.
+// Nothing is sent to Kea while no NetworkConfiguration is waiting for an
+// address.
+func (w *leaseWatcher) scan(ctx context.Context) error {
+	if w.started.IsZero() {
+		w.started = time.Now()
+	}
+	awaited := w.awaitedSubnets(ctx)
+	for mac := range w.renewed {
+		if _, ok := awaited[mac]; !ok {
+			delete(w.renewed, mac)
+		}
+	}
+	if len(awaited) == 0 {
+		return nil
+	}
+	leases, next, err := w.kea.GetLeasePage(ctx, w.from, w.pageSize)
+	if err != nil {
+		return err
+	}
+	w.from = next
+	for _, l := range leases {
+		if l.State != keaservice.LeaseStateDefault || !slices.Contains(awaited[l.HWAddress], l.SubnetID) {
+			continue
+		}
+		last, ok := w.renewed[l.HWAddress]
+		if !ok {
+			last = w.started
+		}
+		if !l.Renewed.After(last) {
+			continue
+		}
+		if w.renewed == nil {
+			w.renewed = make(map[string]time.Time)
+		}
+		w.renewed[l.HWAddress] = l.Renewed
+		w.enqueue(ctx, l.HWAddress)
+	}
+	return nil
+}
+
+// awaitedSubnets maps the MACs of the NetworkConfigurations waiting for an
+// address to the DHCPv4 subnet-ids recorded for them in the reservation
+// inventory, forgetting NetworkConfigurations that no longer exist.
+func (w *leaseWatcher) awaitedSubnets(ctx context.Context) map[string][]int {
+	awaited := make(map[string][]int)
+	awaitingLeaseAttempts.Range(func(k, _ any) bool {
+		key, _ := k.(types.NamespacedName)
+		var nc vitistackcrdsv1alpha1.NetworkConfiguration
+		if err := w.client.Get(ctx, key, &nc); err != nil {
+			if apierrors.IsNotFound(err) {
+				awaitingLeaseAttempts.Delete(key)
+			}
+			return true
+		}
+		for mac, subnetIDs := range recordedSubnetIDs(readReservationRecords(&nc)) {
+			awaited[mac] = append(awaited[mac], subnetIDs...)
+		}
+		return true
+	})
+	return awaited
+}
+
+// leaseWatchPageSize returns KEA_LEASE_WATCH_PAGE_SIZE, or its default when
+// it is not a positive number.
+func leaseWatchPageSize() int {
+	const defaultPageSize = 1000
+	if n := viper.GetInt(consts.KEA_LEASE_WATCH_PAGE_SIZE); n > 0 {
+		return n
+	}
+	return defaultPageSize
+}
+
+// enqueue sends an event for each NetworkConfiguration listing mac.
+func (w *leaseWatcher) enqueue(ctx context.Context, mac string) {
+	var ncs vitistackcrdsv1alpha1.NetworkConfigurationList
+	if err := w.client.List(ctx, &ncs, client.MatchingFields{MACAddressIndex: mac}); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list NetworkConfigurations for lease", "mac", mac)
+		return
+	}
+	for i := range ncs.Items {
+		select {
+		case w.events <- event.GenericEvent{Object: &ncs.Items[i]}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// awaitingLeaseAttempts counts consecutive reconciles per NetworkConfiguration
+// that ended with interfaces still waiting for an address, so the fallback
+// poll backs off while the lease watcher is running.
+var awaitingLeaseAttempts sync.Map // map[types.NamespacedName]int
+
+// awaitingLeaseRequeue returns the requeue for a NetworkConfiguration whose
+// interfaces are still waiting for an address. Without the lease watcher it
+// polls every RequeueDelayError; with it the poll is only a safety net and
+// backs off exponentially up to RequeueDelaySuccess.
+func awaitingLeaseRequeue(key types.NamespacedName) (ctrl.Result, error) {
+	if viper.GetDuration(consts.KEA_LEASE_WATCH_INTERVAL) <= 0 {
+		return ctrl.Result{RequeueAfter: RequeueDelayError}, nil
+	}
+	n, _ := awaitingLeaseAttempts.Load(key)
+	attempt, _ := n.(int)
+	awaitingLeaseAttempts.Store(key, attempt+1)
+	return reconcileutil.RequeueBackoff(attempt, RequeueDelayError, RequeueDelaySuccess, nil)
+}
@@ -0,0 +1,127 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// pagedLeasesKea serves leases, sorted by address, through lease4-get-page.
+type pagedLeasesKea struct {
+	leases   []map[string]any
+	commands []keamodels.Request
+}
+
+func (f *pagedLeasesKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	f.commands = append(f.commands, cmd)
+	if cmd.Command != "lease4-get-page" {
+		return keamodels.Response{Result: 3}, nil
+	}
+	from, _ := cmd.Args["from"].(string)
+	limit, _ := cmd.Args["limit"].(int)
+	var page []any
+	for _, l := range f.leases {
+		if from != "start" && l["ip-address"].(string) <= from {
+			continue
+		}
+		if len(page) < limit {
+			page = append(page, l)
+		}
+	}
+	if len(page) == 0 {
+		return keamodels.Response{Result: 3}, nil
+	}
+	return keamodels.Response{Result: 0, Arguments: map[string]any{"leases": page, "count": len(page)}}, nil
+}
+
+func TestLeaseWatcherScan(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := vitistackcrdsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	nc := ncWithAnnotations(map[string]string{
+		consts.ReservationsAnnotation: `[{"mac":"` + testMAC0 + `","subnetID":1,"prefix":"` + testOldPrefix + `"}]`,
+	})
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithIndex(&vitistackcrdsv1alpha1.NetworkConfiguration{}, MACAddressIndex, IndexMACAddresses).
+		WithObjects(nc).
+		Build()
+	started := time.Now().Add(-time.Minute)
+	kea := &pagedLeasesKea{leases: []map[string]any{
+		// from before the watcher started
+		{"ip-address": "10.0.0.4", "hw-address": testMAC0, "subnet-id": 1, "state": 0, "cltt": started.Unix() - 60},
+		// another MAC, not awaited
+		{"ip-address": "10.0.0.5", "hw-address": "aa:bb:cc:dd:ee:99", "subnet-id": 1, "state": 0, "cltt": started.Unix() + 10},
+	}}
+	events := make(chan event.GenericEvent, 4)
+	w := &leaseWatcher{client: c, kea: keaservice.New(kea), pageSize: 2, events: events, started: started}
+	ctx := context.Background()
+
+	// Nothing is waiting for a lease: Kea is not asked.
+	if err := w.scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(kea.commands) != 0 {
+		t.Fatalf("expected no Kea commands while no NetworkConfiguration waits, got %v", kea.commands)
+	}
+
+	key := client.ObjectKeyFromObject(nc)
+	awaitingLeaseAttempts.Store(key, 1)
+	t.Cleanup(func() { awaitingLeaseAttempts.Delete(key) })
+	if err := w.scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no events for leases older than the watcher or of other MACs, got %d", len(events))
+	}
+	if got := kea.commands[0].Args; got["from"] != "start" || got["limit"] != 2 || w.from != "10.0.0.5" {
+		t.Fatalf("expected the first page of 2 continuing after 10.0.0.5, got %+v, cursor %q", got, w.from)
+	}
+
+	// The host renews in subnet 1; a lease of the MAC in subnet 2 and a
+	// reclaimed one are not what it waits for.
+	kea.leases = append(kea.leases,
+		map[string]any{"ip-address": "10.0.0.6", "hw-address": "AA:BB:CC:DD:EE:00", "subnet-id": 1, "state": 0, "cltt": started.Unix() + 30},
+		map[string]any{"ip-address": "10.0.0.7", "hw-address": testMAC0, "subnet-id": 1, "state": 2, "cltt": started.Unix() + 40},
+		map[string]any{"ip-address": "10.0.1.8", "hw-address": testMAC0, "subnet-id": 2, "state": 0, "cltt": started.Unix() + 50},
+	)
+	if err := w.scan(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected one event for the renewed lease, got %d", len(events))
+	}
+	if got := (<-events).Object.GetName(); got != nc.Name {
+		t.Fatalf("expected event for %s, got %s", nc.Name, got)
+	}
+	if got := kea.commands[1].Args["from"]; got != "10.0.0.5" {
+		t.Fatalf("expected the second page to continue after 10.0.0.5, got %v", got)
+	}
+
+	// The last page wraps the cursor, and a pass over the same leases sends
+	// nothing.
+	if err := w.scan(ctx); err != nil || w.from != "" {
+		t.Fatalf("expected the cursor to start over after the last page, got %q, %v", w.from, err)
+	}
+	for range 3 {
+		if err := w.scan(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.from != "" {
+		t.Fatalf("expected a full pass in 3 pages, cursor at %q", w.from)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no events without lease changes, got %d", len(events))
+	}
+}
@@ -0,0 +1,165 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+	viticommonconditions "github.com/vitistack/common/pkg/operator/conditions"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// conditionTypeMigrating is True while reservations made for a previous
+	// NetworkNamespace prefix are still in Kea.
+	conditionTypeMigrating                 = "Migrating"
+	conditionReasonWaitingForReservations  = "WaitingForNewReservations"
+	conditionReasonGracePeriod             = "GracePeriod"
+	conditionReasonRemovingOldReservations = "RemovingOldReservations"
+	conditionReasonMigrationComplete       = "MigrationComplete"
+
+	eventReasonPrefixMigrated = "PrefixMigrated"
+	eventActionMigrate        = "Migrate"
+)
+
+// migrateReservations moves a NetworkConfiguration off reservations recorded
+// for an older prefix. The new reservations are made first by the regular
+// reconcile; once every interface still in the spec has one, the old
+// reservations (and, with KEA_MIGRATION_RELEASE_LEASES, their leases) are
+// removed after KEA_MIGRATION_GRACE_PERIOD, counted from when the last of the
+// new reservations was made. prefixes holds the current prefixes in use
+// (DHCPv4 first); an interface counts as migrated once it has a reservation
+// in one of them for every address family they cover. It returns the
+// remaining records and, while the grace period is running, how long until
+// it ends.
+func (r *NetworkConfigurationReconciler) migrateReservations(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, records []reservationRecord, macs []string, prefixes []string, log logr.Logger) ([]reservationRecord, time.Duration) {
+	var current, old []reservationRecord
+	families := make(map[int]bool)
+	for _, p := range prefixes {
+		if strings.Contains(p, ":") {
+			families[familyIPv6] = true
+		} else {
+			families[0] = true
+		}
+	}
+	inPrefix := make(map[string]map[int]bool)
+	for _, rec := range records {
+		if slices.Contains(prefixes, rec.Prefix) {
+			current = append(current, rec)
+			if inPrefix[rec.MAC] == nil {
+				inPrefix[rec.MAC] = make(map[int]bool)
+			}
+			inPrefix[rec.MAC][rec.Family] = true
+		} else {
+			old = append(old, rec)
+		}
+	}
+	prefix := strings.Join(prefixes, " and ")
+
+	if len(old) == 0 {
+		if cond := findCondition(nc.Status.Conditions, conditionTypeMigrating); cond != nil && cond.Status == metav1.ConditionTrue {
+			_ = r.setCondition(ctx, nc, viticommonconditions.New(
+				conditionTypeMigrating, metav1.ConditionFalse, conditionReasonMigrationComplete,
+				fmt.Sprintf("all reservations are in %s", prefix), nc.GetGeneration(),
+			))
+		}
+		return records, 0
+	}
+
+	var pending []string
+	for _, mac := range macs {
+		if len(inPrefix[mac]) < len(families) {
+			pending = append(pending, mac)
+		}
+	}
+	if len(pending) > 0 {
+		_ = r.setCondition(ctx, nc, viticommonconditions.New(
+			conditionTypeMigrating, metav1.ConditionTrue, conditionReasonWaitingForReservations,
+			fmt.Sprintf("waiting for reservations in %s for %s before removing %d old reservation(s)", prefix, strings.Join(pending, ", "), len(old)),
+			nc.GetGeneration(),
+		))
+		return records, 0
+	}
+
+	grace := viper.GetDuration(consts.KEA_MIGRATION_GRACE_PERIOD)
+	started := newestCreated(current)
+	if started.IsZero() {
+		// Records written before Created was kept.
+		started = time.Now()
+		if cond := findCondition(nc.Status.Conditions, conditionTypeMigrating); cond != nil && cond.Status == metav1.ConditionTrue {
+			started = cond.LastTransitionTime.Time
+		}
+	}
+	if remaining := time.Until(started.Add(grace)); remaining > 0 {
+		_ = r.setCondition(ctx, nc, viticommonconditions.New(
+			conditionTypeMigrating, metav1.ConditionTrue, conditionReasonGracePeriod,
+			fmt.Sprintf("%d reservation(s) from a previous prefix will be removed after %s", len(old), started.Add(grace).UTC().Format(time.RFC3339)),
+			nc.GetGeneration(),
+		))
+		return records, remaining
+	}
+
+	releaseLeases := viper.GetBool(consts.KEA_MIGRATION_RELEASE_LEASES)
+	var kept []reservationRecord
+	var errs []string
+	for _, rec := range old {
+		if err := r.removeOldReservation(ctx, rec, releaseLeases); err != nil {
+			errs = append(errs, fmt.Sprintf("%s in subnet %d: %v", rec.MAC, rec.SubnetID, err))
+			kept = append(kept, rec)
+			continue
+		}
+		log.Info("removed reservation from previous prefix", "mac", rec.MAC, "subnetID", rec.SubnetID, "prefix", rec.Prefix)
+	}
+
+	if len(kept) > 0 {
+		_ = r.setCondition(ctx, nc, viticommonconditions.New(
+			conditionTypeMigrating, metav1.ConditionTrue, conditionReasonRemovingOldReservations,
+			fmt.Sprintf("failed to remove %d old reservation(s): %s", len(kept), strings.Join(errs, "; ")),
+			nc.GetGeneration(),
+		))
+		return append(current, kept...), RequeueDelayError
+	}
+
+	msg := fmt.Sprintf("migrated reservations to %s and removed %d old reservation(s)", prefix, len(old))
+	r.event(nc, corev1.EventTypeNormal, eventReasonPrefixMigrated, eventActionMigrate, msg)
+	_ = r.setCondition(ctx, nc, viticommonconditions.New(
+		conditionTypeMigrating, metav1.ConditionFalse, conditionReasonMigrationComplete, msg, nc.GetGeneration(),
+	))
+	return current, 0
+}
+
+// newestCreated returns when the newest of records was made, or the zero time
+// when none records it.
+func newestCreated(records []reservationRecord) time.Time {
+	var newest time.Time
+	for _, rec := range records {
+		if rec.Created.After(newest) {
+			newest = rec.Created
+		}
+	}
+	return newest
+}
+
+// removeOldReservation deletes rec from Kea and, when releaseLeases is set,
+// the DHCPv4 lease its MAC still holds in the old subnet, even after it got a
+// newer one in the new subnet. DHCPv6 leases are left to expire.
+func (r *NetworkConfigurationReconciler) removeOldReservation(ctx context.Context, rec reservationRecord, releaseLeases bool) error {
+	if err := r.deleteReservation(ctx, rec); err != nil {
+		return err
+	}
+	if !releaseLeases || rec.Family == familyIPv6 {
+		return nil
+	}
+	lease, err := r.recordedLease(ctx, rec)
+	if err != nil || lease == nil {
+		return err
+	}
+	return r.Kea.DeleteLease(ctx, lease.IPAddress)
+}
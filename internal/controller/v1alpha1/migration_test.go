@@ -0,0 +1,264 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	testOldPrefix = "10.0.0.0/24"
+	testNewPrefix = "10.1.0.0/24"
+)
+
+// recordingKea answers every command with "nothing found" except
+// reservation-del, and records the commands it receives.
+type recordingKea struct {
+	commands []keamodels.Request
+}
+
+func (f *recordingKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	f.commands = append(f.commands, cmd)
+	if cmd.Command == "reservation-del" {
+		return keamodels.Response{Result: 0}, nil
+	}
+	return keamodels.Response{Result: 3}, nil
+}
+
+func newMigrationReconciler(t *testing.T, nc *vitistackcrdsv1alpha1.NetworkConfiguration) (*NetworkConfigurationReconciler, *recordingKea) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := vitistackcrdsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(nc).WithStatusSubresource(nc).Build()
+	kea := &recordingKea{}
+	return &NetworkConfigurationReconciler{Client: c, KeaClient: kea, Kea: keaservice.New(kea)}, kea
+}
+
+func TestMigrateReservations(t *testing.T) {
+	t.Cleanup(func() { viper.Set(consts.KEA_MIGRATION_GRACE_PERIOD, nil) })
+	records := []reservationRecord{
+		{MAC: testMAC0, SubnetID: 1, Prefix: testOldPrefix},
+		{MAC: testMAC0, SubnetID: 2, Prefix: testNewPrefix},
+	}
+	nc := &vitistackcrdsv1alpha1.NetworkConfiguration{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "nc"}}
+	r, kea := newMigrationReconciler(t, nc)
+	ctx := context.Background()
+
+	viper.Set(consts.KEA_MIGRATION_GRACE_PERIOD, time.Hour)
+	kept, remaining := r.migrateReservations(ctx, nc, records, []string{testMAC0}, []string{testNewPrefix}, logr.Discard())
+	if len(kept) != 2 || remaining <= 0 {
+		t.Fatalf("expected old reservation kept during grace period, got %v (remaining %v)", kept, remaining)
+	}
+	if cond := findCondition(nc.Status.Conditions, conditionTypeMigrating); cond == nil || cond.Reason != conditionReasonGracePeriod {
+		t.Fatalf("expected Migrating/%s, got %+v", conditionReasonGracePeriod, cond)
+	}
+	if len(kea.commands) != 0 {
+		t.Fatalf("expected no Kea calls during grace period, got %v", kea.commands)
+	}
+
+	viper.Set(consts.KEA_MIGRATION_GRACE_PERIOD, time.Duration(0))
+	kept, _ = r.migrateReservations(ctx, nc, records, []string{testMAC0}, []string{testNewPrefix}, logr.Discard())
+	if len(kept) != 1 || kept[0].Prefix != testNewPrefix {
+		t.Fatalf("expected only the new reservation to remain, got %v", kept)
+	}
+	if len(kea.commands) != 1 || kea.commands[0].Command != "reservation-del" || kea.commands[0].Args["subnet-id"] != 1 {
+		t.Fatalf("expected reservation-del in subnet 1, got %v", kea.commands)
+	}
+	if cond := findCondition(nc.Status.Conditions, conditionTypeMigrating); cond == nil || cond.Status != metav1.ConditionFalse {
+		t.Fatalf("expected Migrating False after removal, got %+v", cond)
+	}
+}
+
+func TestMigrateReservations_WaitsForNewReservations(t *testing.T) {
+	records := []reservationRecord{{MAC: testMAC0, SubnetID: 1, Prefix: testOldPrefix}}
+	nc := &vitistackcrdsv1alpha1.NetworkConfiguration{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "nc"}}
+	r, kea := newMigrationReconciler(t, nc)
+
+	kept, _ := r.migrateReservations(context.Background(), nc, records, []string{testMAC0}, []string{testNewPrefix}, logr.Discard())
+	if len(kept) != 1 || len(kea.commands) != 0 {
+		t.Fatalf("expected old reservation kept until the new one exists, got %v, commands %v", kept, kea.commands)
+	}
+	if cond := findCondition(nc.Status.Conditions, conditionTypeMigrating); cond == nil || cond.Reason != conditionReasonWaitingForReservations {
+		t.Fatalf("expected Migrating/%s, got %+v", conditionReasonWaitingForReservations, cond)
+	}
+}
+
+// prefixMigrationKea keeps subnets, host reservations and leases in memory
+// and records every command it receives.
+type prefixMigrationKea struct {
+	subnets  map[int]string
+	hosts    []map[string]any
+	leases   []map[string]any
+	commands []keamodels.Request
+}
+
+func (f *prefixMigrationKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	f.commands = append(f.commands, cmd)
+	found := func(key string, items []any) (keamodels.Response, error) {
+		if len(items) == 0 {
+			return keamodels.Response{Result: 3, Text: "0 IPv4 host(s) found"}, nil
+		}
+		return keamodels.Response{Result: 0, Arguments: map[string]any{key: items}}, nil
+	}
+	switch cmd.Command {
+	case "subnet4-list":
+		var list []any
+		for id, prefix := range f.subnets {
+			list = append(list, map[string]any{"id": id, "subnet": prefix})
+		}
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"subnets": list}}, nil
+	case "subnet4-get":
+		id := cmd.Args["id"].(int)
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"subnet4": []any{map[string]any{
+			"id": id, "subnet": f.subnets[id], "user-context": map[string]any{"managed-by": "kea-operator"},
+		}}}}, nil
+	case "reservation-get-by-id":
+		var hosts []any
+		for _, h := range f.hosts {
+			if h["hw-address"] == cmd.Args["identifier"] {
+				hosts = append(hosts, h)
+			}
+		}
+		return found("hosts", hosts)
+	case "reservation-add":
+		f.hosts = append(f.hosts, cmd.Args["reservation"].(map[string]any))
+		return keamodels.Response{Result: 0}, nil
+	case "reservation-del":
+		for i, h := range f.hosts {
+			if h["hw-address"] == cmd.Args["identifier"] && h["subnet-id"] == cmd.Args["subnet-id"] {
+				f.hosts = append(f.hosts[:i], f.hosts[i+1:]...)
+				return keamodels.Response{Result: 0}, nil
+			}
+		}
+		return keamodels.Response{Result: 3}, nil
+	case "lease4-get-by-hw-address":
+		var leases []any
+		for _, l := range f.leases {
+			if l["hw-address"] == cmd.Args["hw-address"] {
+				leases = append(leases, l)
+			}
+		}
+		return found("leases", leases)
+	case "lease4-get":
+		for _, l := range f.leases {
+			if l["ip-address"] == cmd.Args["ip-address"] {
+				return keamodels.Response{Result: 0, Arguments: l}, nil
+			}
+		}
+		return keamodels.Response{Result: 3}, nil
+	case "lease4-del":
+		for i, l := range f.leases {
+			if l["ip-address"] == cmd.Args["ip-address"] {
+				f.leases = append(f.leases[:i], f.leases[i+1:]...)
+				return keamodels.Response{Result: 0}, nil
+			}
+		}
+		return keamodels.Response{Result: 3}, nil
+	}
+	if strings.Contains(cmd.Command, "-get") {
+		return keamodels.Response{Result: 3}, nil
+	}
+	return keamodels.Response{Result: 0}, nil
+}
+
+// A host still leasing an address in the previous prefix gets its
+// reservation in the new subnet, and the old reservation and lease are
+// removed once it exists.
+func TestReconcile_PrefixMigrationWithLiveLease(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Set(consts.KEA_MIGRATION_GRACE_PERIOD, nil)
+		viper.Set(consts.KEA_MIGRATION_RELEASE_LEASES, nil)
+	})
+	viper.Set(consts.KEA_MIGRATION_GRACE_PERIOD, time.Duration(0))
+	viper.Set(consts.KEA_MIGRATION_RELEASE_LEASES, true)
+
+	nc := &vitistackcrdsv1alpha1.NetworkConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace, Name: "nc", Finalizers: []string{finalizerName},
+			Annotations: map[string]string{consts.ReservationsAnnotation: `[{"mac":"` + testMAC0 +
+				`","subnetID":1,"ip":"10.0.0.10","prefix":"` + testOldPrefix + `","created":"2025-01-01T00:00:00Z"}]`},
+		},
+		Spec: vitistackcrdsv1alpha1.NetworkConfigurationSpec{
+			NetworkNamespaceName: "nn",
+			NetworkInterfaces:    []vitistackcrdsv1alpha1.NetworkConfigurationInterface{{Name: "eth0", MacAddress: testMAC0}},
+		},
+	}
+	nn := testNetworkNamespace(testNewPrefix, 0, nil)
+	scheme := runtime.NewScheme()
+	if err := vitistackcrdsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(nc, nn).WithStatusSubresource(nc).Build()
+	owner := map[string]any{"owner": ownerKey(nc)}
+	kea := &prefixMigrationKea{
+		subnets: map[int]string{1: testOldPrefix, 2: testNewPrefix},
+		hosts:   []map[string]any{{"hw-address": testMAC0, "subnet-id": 1, "ip-address": "10.0.0.10", "user-context": owner}},
+		leases: []map[string]any{{
+			"hw-address": testMAC0, "ip-address": "10.0.0.10", "subnet-id": 1,
+			"cltt": int(time.Now().Unix()) - 60, "valid-lft": 4000, "state": 0,
+		}},
+	}
+	r := &NetworkConfigurationReconciler{Client: c, KeaClient: kea, Kea: keaservice.New(kea)}
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(nc)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, cmd := range kea.commands {
+		if cmd.Command == "reservation-add" && cmd.Args["reservation"].(map[string]any)["subnet-id"] != 2 {
+			t.Fatalf("expected reservations only in the new subnet, got %v", cmd.Args)
+		}
+	}
+	if len(kea.hosts) != 1 || kea.hosts[0]["subnet-id"] != 2 {
+		t.Fatalf("expected the host reserved in subnet 2 only, got %v", kea.hosts)
+	}
+	if len(kea.leases) != 0 {
+		t.Fatalf("expected the lease in the old prefix released, got %v", kea.leases)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(nc), nc); err != nil {
+		t.Fatal(err)
+	}
+	records := readReservationRecords(nc)
+	if len(records) != 1 || records[0].SubnetID != 2 || records[0].Prefix != testNewPrefix {
+		t.Fatalf("expected the inventory to hold the subnet 2 reservation, got %+v", records)
+	}
+}
+
+// The grace period runs from when the new reservations were made, not from
+// when the migration started waiting for them.
+func TestMigrateReservations_GraceFromNewReservation(t *testing.T) {
+	t.Cleanup(func() { viper.Set(consts.KEA_MIGRATION_GRACE_PERIOD, nil) })
+	viper.Set(consts.KEA_MIGRATION_GRACE_PERIOD, time.Hour)
+	nc := &vitistackcrdsv1alpha1.NetworkConfiguration{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "nc"}}
+	nc.Status.Conditions = []metav1.Condition{{
+		Type: conditionTypeMigrating, Status: metav1.ConditionTrue, Reason: conditionReasonWaitingForReservations,
+		LastTransitionTime: metav1.NewTime(time.Now().Add(-2 * time.Hour)),
+	}}
+	r, kea := newMigrationReconciler(t, nc)
+	records := []reservationRecord{
+		{MAC: testMAC0, SubnetID: 1, Prefix: testOldPrefix, Created: time.Now().Add(-3 * time.Hour)},
+		{MAC: testMAC0, SubnetID: 2, Prefix: testNewPrefix, Created: time.Now().Add(-10 * time.Minute)},
+	}
+
+	kept, remaining := r.migrateReservations(context.Background(), nc, records, []string{testMAC0}, []string{testNewPrefix}, logr.Discard())
+	if len(kept) != 2 || remaining <= 0 || remaining > time.Hour || len(kea.commands) != 0 {
+		t.Fatalf("expected the old reservation kept for the rest of the grace period, got %v (remaining %v, commands %v)", kept, remaining, kea.commands)
+	}
+}
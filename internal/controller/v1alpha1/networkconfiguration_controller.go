@@ -18,10 +18,12 @@ package v1alpha1
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -39,12 +41,18 @@ import (
 	subnetutil "github.com/vitistack/kea-operator/internal/util/subnet"
 	"github.com/vitistack/kea-operator/pkg/interfaces/keainterface"
 	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // NetworkConfigurationReconciler reconciles vitistack.io/v1alpha1 NetworkConfiguration
@@ -55,6 +63,7 @@ type NetworkConfigurationReconciler struct {
 	Scheme    *runtime.Scheme
 	KeaClient keainterface.KeaClient
 	Kea       *keaservice.Service
+	Recorder  events.EventRecorder
 }
 
 const (
@@ -64,6 +73,15 @@ const (
 	conditionReasonConfigured  = "Configured"
 	conditionReasonError       = "Error"
 
+	// conditionTypeReservationConflict is True while a MAC or IP clashes with a
+	// reservation held by someone else. Its reason is the keaservice.ConflictKind.
+	conditionTypeReservationConflict   = "ReservationConflict"
+	conditionReasonReservationConflict = "ReservationConflict"
+	conditionReasonNoConflict          = "NoConflict"
+
+	// eventActionReserve is the Event action for reservation operations.
+	eventActionReserve = "Reserve"
+
 	// RequeueDelaySuccess is the resync interval after a successful reconcile.
 	// The watch on NetworkConfiguration already triggers a reconcile on spec
 	// changes, so this is just a periodic safety net to catch out-of-band
@@ -84,6 +102,8 @@ var deprecationWarned sync.Map
 // +kubebuilder:rbac:groups=vitistack.io,resources=networkconfigurations/finalizers,verbs=update
 // +kubebuilder:rbac:groups=vitistack.io,resources=networknamespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;patch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 
 // Reconcile fetches the NetworkConfiguration Custom Resource, reads MAC addresses
 // from spec.networkInterfaces[].macAddress, looks up the NetworkNamespace IPv4
@@ -108,9 +128,29 @@ func (r *NetworkConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{}, nil
 	}
 
+	// Paused NetworkConfigurations are left alone, deletion included: the
+	// finalizer stays until the annotation is removed.
+	paused := annotationBool(nc.GetAnnotations(), consts.PauseAnnotation)
+	r.reportPaused(ctx, nc, paused)
+	if paused {
+		log.V(1).Info("reconciliation paused by annotation", "name", nc.Name, "namespace", nc.Namespace)
+		return ctrl.Result{}, nil
+	}
+
+	// In a dry run the Kea service records write commands instead of sending
+	// them; they are reported on the DryRun condition and as Events.
+	var dryRun *keaservice.DryRun
+	if dryRunEnabled(nc) {
+		dryRun = &keaservice.DryRun{}
+		ctx = keaservice.WithDryRun(ctx, dryRun)
+	}
+
 	// Handle deletion before triage: if the NC has our finalizer, we must
 	// run cleanup regardless of current NN state.
 	if !nc.GetDeletionTimestamp().IsZero() {
+		if dryRun != nil {
+			return r.dryRunDeletion(ctx, nc, dryRun, log), nil
+		}
 		return r.handleDeletion(ctx, nc, log)
 	}
 
@@ -122,6 +162,9 @@ func (r *NetworkConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 		// spam for resources that likely aren't ours; the user will see the
 		// error via the NC status if we've already claimed it.
 		log.V(1).Info("unable to fetch NetworkNamespace for triage", "namespace", req.Namespace, "error", err.Error())
+		if viticommonfinalizers.Has(nc, finalizerName) {
+			r.setStage(ctx, nc, conditionTypeNetworkNamespaceResolved, false, conditionReasonNotFound, err.Error())
+		}
 		return ctrl.Result{}, nil
 	}
 
@@ -136,6 +179,8 @@ func (r *NetworkConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 
 	// --- Past triage: this NC is ours (type=dhcp or defaulting to DHCP). ---
 
+	defer r.reportDryRun(ctx, nc, dryRun)
+
 	// Warn (once) about the fallback NN lookup when we're actually handling
 	// this NC, so we don't emit it for resources that belong elsewhere.
 	if fallbackUsed {
@@ -145,6 +190,10 @@ func (r *NetworkConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 				"Please set spec.networkNamespaceName explicitly.",
 				"namespace", req.Namespace)
 		}
+		if _, alreadyWarned := deprecationWarned.LoadOrStore("nnname-event-"+nc.Namespace+"/"+nc.Name, true); !alreadyWarned {
+			r.event(nc, corev1.EventTypeWarning, eventReasonDeprecatedDefault, eventActionTriage,
+				fmt.Sprintf("spec.networkNamespaceName is not set; using NetworkNamespace %s found by listing the namespace. Set spec.networkNamespaceName explicitly.", nn.Name))
+		}
 	}
 
 	// Strict / warning for unset spec.provider on NC.
@@ -152,6 +201,7 @@ func (r *NetworkConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 		if viper.GetBool(consts.KEA_STRICT_DEFAULTS) {
 			msg := "spec.provider is not set and KEA_STRICT_DEFAULTS is enabled; refusing to default to 'kea'. Set spec.provider to 'kea' explicitly."
 			log.Info("WARNING: "+msg, "name", nc.Name, "namespace", nc.Namespace)
+			r.event(nc, corev1.EventTypeWarning, eventReasonStrictDefaults, eventActionTriage, msg)
 			_ = r.setCondition(ctx, nc, viticommonconditions.New(
 				conditionTypeReady, metav1.ConditionFalse, conditionReasonError, msg, nc.GetGeneration(),
 			))
@@ -164,6 +214,8 @@ func (r *NetworkConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 				"Please set spec.provider to 'kea' explicitly. "+
 				"Set KEA_STRICT_DEFAULTS=true to force migration.",
 				"name", nc.Name, "namespace", nc.Namespace)
+			r.event(nc, corev1.EventTypeWarning, eventReasonDeprecatedDefault, eventActionTriage,
+				"spec.provider is not set; handling it as 'kea' for backward compatibility. Set spec.provider to 'kea' explicitly.")
 		}
 	}
 
@@ -172,6 +224,8 @@ func (r *NetworkConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 		if viper.GetBool(consts.KEA_STRICT_DEFAULTS) {
 			msg := fmt.Sprintf("NetworkNamespace %s has no spec.ipAllocation and KEA_STRICT_DEFAULTS is enabled; refusing to default to DHCP. Set spec.ipAllocation.type to 'dhcp' explicitly.", nn.Name)
 			log.Info("WARNING: "+msg, "networkNamespace", nn.Name, "namespace", req.Namespace)
+			r.event(nc, corev1.EventTypeWarning, eventReasonStrictDefaults, eventActionTriage, msg)
+			r.setStage(ctx, nc, conditionTypeNetworkNamespaceResolved, false, conditionReasonIPAllocationUnset, msg)
 			_ = r.setCondition(ctx, nc, viticommonconditions.New(
 				conditionTypeReady, metav1.ConditionFalse, conditionReasonError, msg, nc.GetGeneration(),
 			))
@@ -185,6 +239,10 @@ func (r *NetworkConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 				"Set KEA_STRICT_DEFAULTS=true to force migration.",
 				"networkNamespace", nn.Name, "namespace", req.Namespace)
 		}
+		if _, alreadyWarned := deprecationWarned.LoadOrStore("ipalloc-event-"+nc.Namespace+"/"+nc.Name, true); !alreadyWarned {
+			r.event(nc, corev1.EventTypeWarning, eventReasonDeprecatedDefault, eventActionTriage,
+				fmt.Sprintf("NetworkNamespace %s has no spec.ipAllocation; defaulting to DHCP for backward compatibility. Set spec.ipAllocation.type to 'dhcp' explicitly.", nn.Name))
+		}
 	}
 
 	// Ensure finalizer
@@ -206,7 +264,9 @@ func (r *NetworkConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 	ipv4Prefix := nn.Status.IPv4Prefix
 	if ipv4Prefix == "" {
 		log.Error(nil, "NetworkNamespace missing status.IPv4Prefix", "namespace", req.Namespace, "networkNamespace", nn.Name)
-		_ = r.updateStatus(ctx, nc, "Error", "Failed", fmt.Sprintf("NetworkNamespace %s missing status.IPv4Prefix", nn.Name), nil)
+		msg := fmt.Sprintf("NetworkNamespace %s missing status.IPv4Prefix", nn.Name)
+		r.setStage(ctx, nc, conditionTypeNetworkNamespaceResolved, false, conditionReasonPrefixMissing, msg)
+		_ = r.updateStatus(ctx, nc, "Error", "Failed", msg, nil)
 		return ctrl.Result{RequeueAfter: RequeueDelayError}, nil
 	}
 
@@ -214,57 +274,167 @@ func (r *NetworkConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 	macs := extractMACsFromTypedNetworkConfiguration(nc)
 	if len(macs) == 0 {
 		log.Info("no MAC addresses found on NetworkConfiguration; skipping reservation", "name", nc.GetName(), "namespace", nc.GetNamespace())
+		r.setStage(ctx, nc, conditionTypeLeasesObserved, false, conditionReasonNoInterfaces, "no interfaces with a MAC address")
 		_ = r.updateStatus(ctx, nc, "Ready", "Success", "No MAC addresses to configure", nil)
 		return ctrl.Result{}, nil
 	}
 
-	// Resolve or create Kea subnet
-	poolCfg, err := subnetutil.CalculatePoolFromCIDR(ipv4Prefix)
+	// Pool layout and allocation settings come from the NetworkNamespace and are
+	// validated up front so a bad annotation fails the whole reconcile clearly.
+	// Secondary prefixes share the pool policy, limited to the explicit
+	// ranges that fall inside them.
+	var subnets ipv4Subnets
+	var network string
+	prefixes, err := ipv4Prefixes(nn)
+	policy := subnetutil.PoolPolicy{}
+	if err == nil {
+		policy, err = poolPolicy(nn)
+	}
+	for i, prefix := range prefixes {
+		if err != nil {
+			break
+		}
+		p := policy
+		if i > 0 {
+			p = prefixPolicy(policy, prefix)
+		}
+		var poolCfg *subnetutil.PoolConfig
+		if poolCfg, err = subnetutil.CalculatePool(prefix, p); err == nil {
+			subnets = append(subnets, ipv4Subnet{Prefix: prefix, Pool: poolCfg})
+		}
+	}
+	if err == nil {
+		network, err = sharedNetworkName(nn, prefixes)
+	}
+	var allocMode string
+	if err == nil {
+		allocMode, err = ipAllocationMode(nn)
+	}
+	var options []keamodels.OptionData
+	if err == nil {
+		options, err = subnetOptions(nn)
+	}
+	var params keamodels.SubnetConfig
+	if err == nil {
+		params, err = subnetParams(nn)
+	}
+	var relay subnetRelay
+	if err == nil {
+		relay, err = subnetRelaySettings(nn)
+	}
 	if err != nil {
-		log.Error(err, "failed to calculate pool from CIDR", "ipv4Prefix", ipv4Prefix)
-		_ = r.updateStatus(ctx, nc, "Error", "Failed", fmt.Sprintf("Invalid CIDR: %v", err), nil)
+		log.Info("invalid pool or IP allocation settings on NetworkNamespace", "networkNamespace", nn.Name, "ipv4Prefix", ipv4Prefix, "error", err.Error())
+		r.setStage(ctx, nc, conditionTypeNetworkNamespaceResolved, false, conditionReasonInvalidSettings, err.Error())
+		_ = r.setCondition(ctx, nc, viticommonconditions.New(
+			conditionTypeReady, metav1.ConditionFalse, conditionReasonError, err.Error(), nc.GetGeneration(),
+		))
+		_ = r.updateStatus(ctx, nc, "Error", "Failed", err.Error(), nil)
 		return ctrl.Result{RequeueAfter: RequeueDelayError}, nil
 	}
+	r.setStage(ctx, nc, conditionTypeNetworkNamespaceResolved, true, conditionReasonResolved,
+		fmt.Sprintf("NetworkNamespace %s, prefix %s", nn.Name, strings.Join(prefixes, ", ")))
+	for i := range subnets {
+		subnets[i].Relay = relay.addresses(subnets[i].Pool.Gateway)
+	}
 
-	// Get require-client-classes from configuration
-	var requireClientClasses []string
-	if classes := viper.GetString(consts.KEA_REQUIRE_CLIENT_CLASSES); classes != "" {
-		for c := range strings.SplitSeq(classes, ",") {
-			if trimmed := strings.TrimSpace(c); trimmed != "" {
-				requireClientClasses = append(requireClientClasses, trimmed)
-			}
-		}
+	// Requested static IPs and host settings are user input; a malformed
+	// annotation won't fix itself, so report it and wait for the next edit
+	// instead of requeueing.
+	requested, err := requestedIPv4ByMAC(nc)
+	var hosts map[string]keamodels.HostParams
+	if err == nil {
+		hosts, err = hostParamsByMAC(nc)
+	}
+	var identifiers map[string]hostIdentifier
+	if err == nil {
+		identifiers, err = hostIdentifierByMAC(nc)
+	}
+	if err != nil {
+		log.Info("invalid requested IPv4, host-params or host-identifier annotation", "error", err.Error())
+		r.setStage(ctx, nc, conditionTypeReservationsReady, false, conditionReasonInvalidRequest, err.Error())
+		_ = r.setCondition(ctx, nc, viticommonconditions.New(
+			conditionTypeReady, metav1.ConditionFalse, conditionReasonError, err.Error(), nc.GetGeneration(),
+		))
+		_ = r.updateStatus(ctx, nc, "Error", "Failed", err.Error(), nil)
+		return ctrl.Result{}, nil
 	}
 
+	dns, extraOptions := splitSubnetDNS(options)
 	subnetCfg := keamodels.SubnetConfig{
-		Subnet:               ipv4Prefix,
-		Gateway:              poolCfg.Gateway,
-		PoolStart:            poolCfg.PoolStart,
-		PoolEnd:              poolCfg.PoolEnd,
-		RequireClientClasses: requireClientClasses,
+		DNS:                  dns,
+		ValidLife:            params.ValidLife,
+		RenewTimer:           params.RenewTimer,
+		RebindTimer:          params.RebindTimer,
+		RequireClientClasses: params.RequireClientClasses,
+		Options:              extraOptions,
+		Interface:            relay.Interface,
 	}
-	subnetID, created, err := r.Kea.GetOrCreateSubnet(ctx, subnetCfg)
+	subnets, err = r.ensureIPv4Subnets(ctx, nc, subnets, subnetCfg, options, network, log)
 	if err != nil {
-		log.Error(err, "failed to get or create Kea subnet", "ipv4Prefix", ipv4Prefix)
+		log.Error(err, "failed to get or create Kea subnets", "ipv4Prefixes", prefixes)
+		r.reportKeaReachable(ctx, nc, err)
+		r.setStage(ctx, nc, conditionTypeSubnetReady, false, conditionReasonSubnetError, err.Error())
 		_ = r.setCondition(ctx, nc, viticommonconditions.New(
 			conditionTypeReady, metav1.ConditionFalse, conditionReasonError, fmt.Sprintf("subnet error: %v", err), nc.GetGeneration(),
 		))
 		_ = r.updateStatus(ctx, nc, "Error", "Failed", fmt.Sprintf("Subnet error: %v", err), nil)
 		return ctrl.Result{RequeueAfter: RequeueDelayError}, nil
 	}
-	if created {
-		log.Info("created new Kea subnet", "subnet", ipv4Prefix, "subnetID", subnetID)
+	if msg, created := subnetStage(subnets, network); created {
+		r.setStage(ctx, nc, conditionTypeSubnetReady, true, conditionReasonSubnetCreated, msg)
+	} else {
+		r.setStage(ctx, nc, conditionTypeSubnetReady, true, conditionReasonSubnetFound, msg)
+	}
+	if err := r.writeSubnetOptions(ctx, nc, subnets[0].Info); err != nil {
+		log.Error(err, "failed to record subnet options on NetworkConfiguration")
 	}
 
-	// Get subnet details (gateway, DNS, etc.). Subnet info lookup is non-fatal —
-	// reservations still proceed without gateway/DNS, just with less status detail.
-	subnetID, subnetInfo := r.resolveSubnetInfo(ctx, subnetID, ipv4Prefix, log)
+	// Reservations keyed on an identifier the interface no longer uses would
+	// hold on to its address, so they go first.
+	recorded := r.retireChangedIdentifiers(ctx, nc, readReservationRecords(nc), identifiers, log)
 
 	// Process MAC reservations
-	macToIP, macToSubnetID, errs := r.processMACReservations(ctx, macs, subnetID, ipv4Prefix, log)
+	targets := reservationTargets(subnets, allocMode, nn.Status.VlanID)
+	if seed, lifetime := leaseSeeding(nn); seed {
+		for i := range targets {
+			targets[i].SeedLeases, targets[i].SeedLifetime = true, lifetime
+		}
+	}
+	res := r.processMACReservations(ctx, nc, macs, targets, requested, hosts, identifiers, log)
+	macToIP, macToSubnetID, errs, conflicts := res.macToIP, res.macToSubnetID, res.errs, res.conflicts
+	r.reportReservationConflicts(ctx, nc, conflicts)
+	r.reportPeerChange(nc)
+	r.reportReservationStages(ctx, nc, macs, res)
+	if err := r.writeInterfaceStatuses(ctx, nc, interfaceStatuses(nc, res.interfaces)); err != nil {
+		log.Error(err, "failed to record interface status on NetworkConfiguration")
+	}
+
+	// With ENABLE_DHCP6, dual-stack NetworkNamespaces also get a kea-dhcp6
+	// subnet and reservations. Their failures are reported on DHCPv6Ready
+	// only, so they do not take down Ready for working DHCPv4.
+	made := reservationsMade(macToSubnetID, macToIP, identifiers, subnets.prefixOf, r.Kea.Peer(), time.Now().UTC().Truncate(time.Second))
+	prefixes = subnets.prefixes()
+	v6 := r.reconcileDHCPv6(ctx, nc, nn, macs, log)
+	if v6 != nil {
+		made = append(made, v6.records...)
+		prefixes = append(prefixes, v6.prefix)
+	}
+
+	// Update the reservation inventory, drop reservations of interfaces no
+	// longer in the spec, and retire reservations left in a previous prefix
+	// once the new ones exist.
+	records := mergeReservationRecords(recorded, made)
+	records = r.pruneRemovedInterfaces(ctx, nc, records, macs, log)
+	records, migrationRemaining := r.migrateReservations(ctx, nc, records, macs, prefixes, log)
+	// A dry run made no reservations, so the inventory is left as it was.
+	if dryRun == nil {
+		if err := r.writeReservationRecords(ctx, nc, records); err != nil {
+			log.Error(err, "failed to record reservations on NetworkConfiguration")
+		}
+	}
 
 	// Build status interfaces
-	statusInterfaces := r.buildStatusInterfaces(nc, macToIP, macToSubnetID, ipv4Prefix, subnetInfo)
+	statusInterfaces := r.buildStatusInterfaces(nc, macToIP, macToSubnetID, subnets, v6)
 
 	// Handle errors
 	if len(errs) > 0 {
@@ -275,6 +445,17 @@ func (r *NetworkConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{RequeueAfter: RequeueDelayError}, nil
 	}
 
+	// Conflicts with reservations held by others are reported on their own
+	// condition and as Events rather than folded into the status message.
+	if len(conflicts) > 0 {
+		msg := fmt.Sprintf("%d reservation conflict(s); see the %s condition", len(conflicts), conditionTypeReservationConflict)
+		_ = r.setCondition(ctx, nc, viticommonconditions.New(
+			conditionTypeReady, metav1.ConditionFalse, conditionReasonReservationConflict, msg, nc.GetGeneration(),
+		))
+		_ = r.updateStatus(ctx, nc, "Error", "Failed", msg, statusInterfaces)
+		return ctrl.Result{RequeueAfter: RequeueDelayError}, nil
+	}
+
 	// Build success message
 	statusMsg := r.buildSuccessMessage(len(macs), len(macToIP))
 
@@ -284,23 +465,57 @@ func (r *NetworkConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 	_ = r.updateStatus(ctx, nc, "Ready", "Success", statusMsg, statusInterfaces)
 
 	// If not every MAC has resolved to an IP yet, the reservation is still
-	// settling. Re-check on the short interval instead of waiting the full
-	// success resync, so the IP lands in status — and therefore in the
-	// downstream Machine's public IPs — in seconds rather than minutes.
+	// settling. The lease watcher enqueues this NetworkConfiguration as soon
+	// as Kea leases one of its MACs, so the IP lands in status — and therefore
+	// in the downstream Machine's public IPs — in seconds; the requeue here is
+	// only the fallback poll.
 	if len(macToIP) < len(macs) {
+		return awaitingLeaseRequeue(req.NamespacedName)
+	}
+	awaitingLeaseAttempts.Delete(req.NamespacedName)
+	// Failed DHCPv6 is retried sooner, though Ready stays True.
+	if v6 != nil && len(v6.errs) > 0 {
 		return ctrl.Result{RequeueAfter: RequeueDelayError}, nil
 	}
+	// Come back when a prefix migration's grace period ends.
+	if migrationRemaining > 0 && migrationRemaining < RequeueDelaySuccess {
+		return ctrl.Result{RequeueAfter: migrationRemaining}, nil
+	}
 	return ctrl.Result{RequeueAfter: RequeueDelaySuccess}, nil
 }
 
-// handleDeletion handles the deletion of a NetworkConfiguration
+// handleDeletion removes the reservations of a deleted NetworkConfiguration
+// and then its finalizer. While Kea is unavailable cleanup is retried with
+// exponential backoff and reported on the Deleting condition; the finalizer is
+// only removed without a successful cleanup when forced (see
+// forceFinalizeReason), in which case a Warning Event and an orphan record are
+// left behind.
 func (r *NetworkConfigurationReconciler) handleDeletion(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, log logr.Logger) (ctrl.Result, error) {
+	key := client.ObjectKeyFromObject(nc)
 	if err := r.cleanupReservations(ctx, nc); err != nil {
-		log.V(1).Info("reservation cleanup during deletion encountered an issue", "error", err)
+		reason := forceFinalizeReason(nc)
+		if reason == "" {
+			n, _ := deletionAttempts.Load(key)
+			attempt, _ := n.(int)
+			attempt++
+			deletionAttempts.Store(key, attempt)
+			log.Info("reservation cleanup failed, will retry", "attempt", attempt, "error", err.Error())
+			r.setDeletingCondition(ctx, nc, attempt, err)
+			return reconcileutil.RequeueBackoff(attempt, deletionBackoffBase, deletionBackoffMax, nil)
+		}
+
+		msg := fmt.Sprintf("removing finalizer without reservation cleanup (%s): %v", reason, err)
+		log.Info("WARNING: "+msg, "networkConfiguration", key.String())
+		r.event(nc, corev1.EventTypeWarning, eventReasonFinalizerForceRemoved, eventActionDelete, msg)
+		if oerr := r.recordOrphans(ctx, nc, reason, err); oerr != nil {
+			log.Error(oerr, "failed to record orphaned reservations", "configMap", orphanConfigMapName)
+		}
 	}
 	if err := viticommonfinalizers.Remove(ctx, r.Client, nc, finalizerName); err != nil {
 		return reconcileutil.Requeue(err)
 	}
+	deletionAttempts.Delete(key)
+	awaitingLeaseAttempts.Delete(key)
 	return ctrl.Result{}, nil
 }
 
@@ -328,65 +543,297 @@ func (r *NetworkConfigurationReconciler) resolveSubnetInfo(ctx context.Context,
 	return subnetID, nil
 }
 
-// processMACReservations processes all MAC address reservations
-func (r *NetworkConfigurationReconciler) processMACReservations(ctx context.Context, macs []string, subnetID int, ipv4Prefix string, log logr.Logger) (map[string]string, map[string]int, []string) {
-	macToIP := make(map[string]string)
-	macToSubnetID := make(map[string]int)
-	var errs []string
+// reservationTarget describes the subnet processMACReservations places
+// reservations in.
+type reservationTarget struct {
+	SubnetID int
+	Prefix   string
+	Gateway  string
+	// VLAN is status.vlanId of the NetworkNamespace, 0 when unknown;
+	// interfaces on another VLAN are not reserved.
+	VLAN int
+	// AllocRanges bound operator-side allocation for MACs without a usable
+	// lease. Empty in lease mode, where such MACs get a MAC-only reservation
+	// instead.
+	AllocRanges []subnetutil.IPRange
+	// SeedLeases adds a lease for each address reserved for a MAC without
+	// one, with SeedLifetime seconds or, when 0, the subnet's valid-lifetime.
+	SeedLeases   bool
+	SeedLifetime int
+}
+
+// reservationResult is the outcome of processMACReservations.
+type reservationResult struct {
+	macToIP       map[string]string
+	macToSubnetID map[string]int
+	errs          []string
+	conflicts     []*keaservice.ReservationConflictError
+	// interfaces holds the per-MAC outcome reported in the interface-status
+	// annotation.
+	interfaces map[string]*interfaceStatus
+	// invalid counts errs caused by invalid requested addresses or interface
+	// VLANs, and unreachable is set when any error came from Kea being
+	// unreachable.
+	invalid     int
+	unreachable bool
+}
 
-	var ipnet *net.IPNet
-	if _, n, e := net.ParseCIDR(strings.TrimSpace(ipv4Prefix)); e == nil {
-		ipnet = n
+// fail records err for the interface st with the given reason.
+func (res *reservationResult) fail(st *interfaceStatus, reason string, err error) {
+	res.errs = append(res.errs, fmt.Sprintf("%s: %v", st.MAC, err))
+	st.Reason, st.Message = reason, err.Error()
+	if reason == interfaceReasonInvalidRequest || reason == interfaceReasonVLANMismatch {
+		res.invalid++
+	}
+	if errors.Is(err, keainterface.ErrUnreachable) {
+		res.unreachable = true
 	}
+}
+
+// conflict records a reservation conflict for the interface st.
+func (res *reservationResult) conflict(st *interfaceStatus, c *keaservice.ReservationConflictError) {
+	res.conflicts = append(res.conflicts, c)
+	st.Reason, st.Message = interfaceReasonConflict, c.Error()
+}
+
+// processMACReservations processes all MAC address reservations. Interfaces
+// on a VLAN other than the targets' are reported and skipped. MACs with an
+// entry in requested are pinned to that address in the target whose prefix
+// holds it; the others pin whatever Kea has leased them or, in operator
+// allocation mode, an address the operator picks itself from the first target
+// with a free one, and get a lease pre-seeded on it when the targets ask for
+// that. targets holds the primary subnet first. hosts carries the
+// per-host fields kept in sync on each reservation and identifiers the host
+// identifier it is keyed on when not the MAC. Conflicts with
+// reservations or leases held by others are returned separately from other
+// errors.
+func (r *NetworkConfigurationReconciler) processMACReservations(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, macs []string, targets []reservationTarget, requested map[string]string, hosts map[string]keamodels.HostParams, identifiers map[string]hostIdentifier, log logr.Logger) reservationResult {
+	res := reservationResult{
+		macToIP:       make(map[string]string),
+		macToSubnetID: make(map[string]int),
+		interfaces:    make(map[string]*interfaceStatus, len(macs)),
+	}
+	macToIP, macToSubnetID := res.macToIP, res.macToSubnetID
+
+	ipnets := make([]*net.IPNet, len(targets))
+	prefixes := make([]string, len(targets))
+	for i, t := range targets {
+		if _, n, e := net.ParseCIDR(strings.TrimSpace(t.Prefix)); e == nil {
+			ipnets[i] = n
+		}
+		prefixes[i] = t.Prefix
+	}
+	// targetFor returns the index of the target whose prefix holds ip, or -1.
+	targetFor := func(ip string) int {
+		p := net.ParseIP(ip)
+		if p == nil || p.To4() == nil {
+			return -1
+		}
+		return slices.IndexFunc(ipnets, func(n *net.IPNet) bool { return n != nil && n.Contains(p) })
+	}
+
+	owner := ownerKey(nc)
+	releaseLeases := annotationBool(nc.GetAnnotations(), consts.ReleaseConflictingLeasesAnnotation)
+	// Reservations the inventory records are this NetworkConfiguration's own,
+	// even when they predate the owner in user-context.
+	recorded := recordedSubnetIDs(readReservationRecords(nc))
 
 	for _, mac := range macs {
-		ip, leaseSubnetID, _ := r.Kea.GetLeaseIPv4ForMAC(ctx, mac)
+		var ip string
+		sid, prefix := targets[0].SubnetID, targets[0].Prefix
+		id := identifiers[mac]
+		// config is the reservation of mac in subnetID, pinned to addr if set.
+		config := func(subnetID int, addr string) keamodels.ReservationConfig {
+			return keamodels.ReservationConfig{
+				MAC: mac, SubnetID: subnetID, IPAddress: addr, Owner: owner, Host: hosts[mac], IdentifierType: id.Type, Identifier: id.Value,
+				RecordedSubnetIDs: recorded[mac],
+			}
+		}
+
+		lease, err := r.Kea.GetLeaseForMAC(ctx, mac)
+		if err != nil {
+			res.interfaces[mac] = &interfaceStatus{MAC: mac}
+			res.fail(res.interfaces[mac], interfaceReasonError, fmt.Errorf("lease lookup: %w", err))
+			continue
+		}
+		st := &interfaceStatus{MAC: mac, Leased: lease != nil && lease.State == keaservice.LeaseStateDefault, Lease: newLeaseStatus(lease, time.Now())}
+		res.interfaces[mac] = st
 
-		sid := subnetID
-		if leaseSubnetID > 0 {
-			sid = leaseSubnetID
+		if err := checkInterfaceVLAN(nc, mac, targets[0].VLAN); err != nil {
+			res.fail(st, interfaceReasonVLANMismatch, err)
+			continue
 		}
 
-		if ip != "" && ipnet != nil {
-			if p := net.ParseIP(ip); p == nil || p.To4() == nil || !ipnet.Contains(p) {
-				log.Info("lease IP not within expected prefix, will create MAC-only reservation",
-					"mac", mac, "leaseIP", ip, "expectedPrefix", ipv4Prefix)
-				ip = ""
+		if reqIP, ok := requested[mac]; ok {
+			t := max(targetFor(reqIP), 0)
+			if err := validateRequestedIPv4(reqIP, ipnets[t], targets[t].Gateway); err != nil {
+				res.fail(st, interfaceReasonInvalidRequest, err)
+				continue
+			}
+			released, err := r.Kea.EnsureIPNotLeasedToOther(ctx, reqIP, mac, releaseLeases)
+			if conflict, ok := keaservice.AsReservationConflict(err); ok {
+				log.Info("requested IP is leased to another MAC", "mac", mac, "conflict", conflict.Error())
+				res.conflict(st, conflict)
+				continue
+			}
+			if err != nil {
+				res.fail(st, interfaceReasonError, err)
+				continue
+			}
+			if released {
+				log.Info("released conflicting dynamic lease on requested IP", "mac", mac, "ip", reqIP)
+			}
+			ip, sid, prefix = reqIP, targets[t].SubnetID, targets[t].Prefix
+		} else {
+			var leaseSubnetID int
+			if lease != nil {
+				ip, leaseSubnetID = lease.IPAddress, lease.SubnetID
+			} else {
+				// No lease: fall back to an address already reserved for the MAC.
+				ip, leaseSubnetID, _ = r.Kea.GetReservedIPv4(ctx, config(0, ""))
+			}
+			if ip != "" {
+				if t := targetFor(ip); t >= 0 {
+					sid, prefix = targets[t].SubnetID, targets[t].Prefix
+				} else {
+					// A lease left in a previous prefix must not keep the
+					// reservation in its old subnet either.
+					log.Info("lease IP not within expected prefix, will not pin it",
+						"mac", mac, "leaseIP", ip, "expectedPrefix", strings.Join(prefixes, ", "))
+					ip, leaseSubnetID = "", 0
+				}
+			}
+			if leaseSubnetID > 0 {
+				sid = leaseSubnetID
 			}
 		}
 
-		created, err := r.Kea.EnsureReservationForMACIP(ctx, mac, sid, ip)
+		var action keaservice.ReservationAction
+		if ip == "" && len(targets[0].AllocRanges) > 0 {
+			// Operator allocation: pick and reserve an address now so it can be
+			// published before the host ever sends a DHCPDISCOVER. A full subnet
+			// moves on to the next one.
+			for _, t := range targets {
+				sid, prefix = t.SubnetID, t.Prefix
+				ip, action, err = r.Kea.AllocateReservation(ctx, config(sid, ""), t.AllocRanges)
+				if !errors.Is(err, keaservice.ErrNoFreeAddress) {
+					break
+				}
+			}
+		} else {
+			action, err = r.Kea.EnsureReservation(ctx, config(sid, ip))
+		}
+		if conflict, ok := keaservice.AsReservationConflict(err); ok {
+			log.Info("reservation conflicts with an existing reservation", "mac", mac, "conflict", conflict.Error())
+			res.conflict(st, conflict)
+			continue
+		}
 		if err != nil {
-			errs = append(errs, fmt.Sprintf("%s: %v", mac, err))
+			res.fail(st, interfaceReasonError, err)
 			continue
 		}
 
 		macToSubnetID[mac] = sid
 		if ip != "" {
 			macToIP[mac] = ip
-			if created {
-				log.Info("configured DHCP reservation with IP", "mac", mac, "ip", ip, "subnetID", sid, "subnet", ipv4Prefix)
-			} else {
-				log.V(1).Info("DHCP reservation already exists", "mac", mac, "ip", ip, "subnetID", sid, "subnet", ipv4Prefix)
+			st.Reason = interfaceReasonReserved
+			st.Message = fmt.Sprintf("reserved %s in subnet %d", ip, sid)
+			switch action {
+			case keaservice.ReservationCreated:
+				log.Info("configured DHCP reservation with IP", "mac", mac, "ip", ip, "subnetID", sid, "subnet", prefix)
+				r.event(nc, corev1.EventTypeNormal, eventReasonReservationCreated, eventActionReserve,
+					fmt.Sprintf("reserved %s for %s in subnet %d", ip, mac, sid))
+			case keaservice.ReservationUpdated:
+				log.Info("pinned existing DHCP reservation to IP", "mac", mac, "ip", ip, "subnetID", sid, "subnet", prefix)
+				r.event(nc, corev1.EventTypeNormal, eventReasonReservationPinned, eventActionReserve,
+					fmt.Sprintf("pinned reservation for %s in subnet %d to %s", mac, sid, ip))
+			case keaservice.ReservationHostUpdated:
+				r.reportHostUpdated(nc, mac, sid, log)
+			default:
+				log.V(1).Info("DHCP reservation already exists", "mac", mac, "ip", ip, "subnetID", sid, "subnet", prefix)
+			}
+			if lease == nil && targets[0].SeedLeases {
+				r.seedLease(ctx, nc, &res, st, keamodels.LeaseConfig{
+					MAC: mac, IPAddress: ip, SubnetID: sid, Hostname: hosts[mac].Hostname, ValidLife: targets[0].SeedLifetime, Owner: owner,
+				}, log)
 			}
 		} else {
-			if created {
-				log.Info("created MAC-only reservation, IP will be auto-allocated on DHCP request", "mac", mac, "subnetID", sid, "subnet", ipv4Prefix)
-			} else {
-				log.V(1).Info("MAC-only reservation already exists", "mac", mac, "subnetID", sid, "subnet", ipv4Prefix)
+			st.Reason = interfaceReasonAwaitingLease
+			st.Message = fmt.Sprintf("MAC-only reservation in subnet %d; the IP is pinned once the host obtains a lease", sid)
+			switch action {
+			case keaservice.ReservationCreated:
+				log.Info("created MAC-only reservation, IP will be auto-allocated on DHCP request", "mac", mac, "subnetID", sid, "subnet", prefix)
+				r.event(nc, corev1.EventTypeNormal, eventReasonLeaseNotFound, eventActionObserveLease,
+					fmt.Sprintf("no DHCP lease for %s yet; created a MAC-only reservation in subnet %d, the IP is pinned once the host obtains a lease", mac, sid))
+			case keaservice.ReservationHostUpdated:
+				r.reportHostUpdated(nc, mac, sid, log)
+			default:
+				log.V(1).Info("MAC-only reservation already exists", "mac", mac, "subnetID", sid, "subnet", prefix)
 			}
 		}
 	}
 
-	return macToIP, macToSubnetID, errs
+	return res
+}
+
+// seedLease adds the lease cfg for the interface st, which holds none yet,
+// and records a lease of another MAC on the address as a conflict.
+func (r *NetworkConfigurationReconciler) seedLease(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, res *reservationResult, st *interfaceStatus, cfg keamodels.LeaseConfig, log logr.Logger) {
+	added, err := r.Kea.SeedLease(ctx, cfg)
+	if conflict, ok := keaservice.AsReservationConflict(err); ok {
+		log.Info("reserved IP is leased to another MAC, not seeding a lease", "mac", cfg.MAC, "conflict", conflict.Error())
+		res.conflict(st, conflict)
+		return
+	}
+	if err != nil {
+		res.fail(st, interfaceReasonError, fmt.Errorf("seed lease: %w", err))
+		return
+	}
+	if added {
+		log.Info("seeded DHCP lease for reserved IP", "mac", cfg.MAC, "ip", cfg.IPAddress, "subnetID", cfg.SubnetID)
+		r.event(nc, corev1.EventTypeNormal, eventReasonLeaseSeeded, eventActionReserve,
+			fmt.Sprintf("seeded a lease of %s for %s in subnet %d", cfg.IPAddress, cfg.MAC, cfg.SubnetID))
+	}
+}
+
+// reportHostUpdated logs and records an Event for a reservation whose host
+// settings were brought in line with the host-params.
+func (r *NetworkConfigurationReconciler) reportHostUpdated(nc *vitistackcrdsv1alpha1.NetworkConfiguration, mac string, sid int, log logr.Logger) {
+	log.Info("updated host settings of DHCP reservation", "mac", mac, "subnetID", sid)
+	r.event(nc, corev1.EventTypeNormal, eventReasonReservationUpdated, eventActionReserve,
+		fmt.Sprintf("updated the host settings or owner of the reservation for %s in subnet %d", mac, sid))
+}
+
+// reportReservationConflicts reflects conflicts in the ReservationConflict
+// condition and emits a Warning Event per conflict naming the other owner.
+func (r *NetworkConfigurationReconciler) reportReservationConflicts(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, conflicts []*keaservice.ReservationConflictError) {
+	if len(conflicts) == 0 {
+		if findCondition(nc.Status.Conditions, conditionTypeReservationConflict) != nil {
+			_ = r.setCondition(ctx, nc, viticommonconditions.New(
+				conditionTypeReservationConflict, metav1.ConditionFalse, conditionReasonNoConflict, "no reservation conflicts", nc.GetGeneration(),
+			))
+		}
+		return
+	}
+
+	msgs := make([]string, 0, len(conflicts))
+	for _, c := range conflicts {
+		msgs = append(msgs, c.Error())
+		r.event(nc, corev1.EventTypeWarning, string(c.Kind), eventActionReserve, c.Error())
+	}
+	reason := string(conflicts[0].Kind)
+	_ = r.setCondition(ctx, nc, viticommonconditions.New(
+		conditionTypeReservationConflict, metav1.ConditionTrue, reason, strings.Join(msgs, "; "), nc.GetGeneration(),
+	))
 }
 
-// buildStatusInterfaces builds the status interface array with all available information
-func (r *NetworkConfigurationReconciler) buildStatusInterfaces(nc *vitistackcrdsv1alpha1.NetworkConfiguration, macToIP map[string]string, macToSubnetID map[string]int, ipv4Prefix string, subnetInfo *keaservice.SubnetInfo) []vitistackcrdsv1alpha1.NetworkConfigurationInterface {
+// buildStatusInterfaces builds the status interface array with all available
+// information. v6, when not nil, adds the DHCPv6 subnet and addresses.
+func (r *NetworkConfigurationReconciler) buildStatusInterfaces(nc *vitistackcrdsv1alpha1.NetworkConfiguration, macToIP map[string]string, macToSubnetID map[string]int, subnets ipv4Subnets, v6 *dhcp6Result) []vitistackcrdsv1alpha1.NetworkConfigurationInterface {
 	statusInterfaces := make([]vitistackcrdsv1alpha1.NetworkConfigurationInterface, 0, len(nc.Spec.NetworkInterfaces))
 
 	for _, iface := range nc.Spec.NetworkInterfaces {
-		normalizedMAC := strings.ToLower(strings.TrimSpace(strings.ReplaceAll(iface.MacAddress, "-", ":")))
+		normalizedMAC := normalizeMAC(iface.MacAddress)
 		statusIface := vitistackcrdsv1alpha1.NetworkConfigurationInterface{
 			Name:         iface.Name,
 			MacAddress:   iface.MacAddress,
@@ -395,26 +842,31 @@ func (r *NetworkConfigurationReconciler) buildStatusInterfaces(nc *vitistackcrds
 		}
 
 		// Check if reservation was successfully created
-		if _, ok := macToSubnetID[normalizedMAC]; ok {
+		sid, ok := macToSubnetID[normalizedMAC]
+		if ok {
 			statusIface.DHCPReserved = true
 		}
 
-		// Set IP and subnet info
+		// Set IP and subnet info; the subnet is set even without an IP yet
+		subnet := subnets.byID(sid)
+		statusIface.IPv4Subnet = subnet.Prefix
 		if ip, ok := macToIP[normalizedMAC]; ok {
 			statusIface.IPv4Addresses = []string{ip}
-			statusIface.IPv4Subnet = ipv4Prefix
-		} else {
-			// Still set subnet even if no IP yet
-			statusIface.IPv4Subnet = ipv4Prefix
+		}
+		if v6 != nil {
+			statusIface.IPv6Subnet = v6.prefix
+			if ip, ok := v6.macToIP[normalizedMAC]; ok {
+				statusIface.IPv6Addresses = []string{ip}
+			}
 		}
 
 		// Add gateway and DNS from subnet info if available
-		if subnetInfo != nil {
-			if subnetInfo.Gateway != "" {
-				statusIface.IPv4Gateway = subnetInfo.Gateway
+		if info := subnet.Info; info != nil {
+			if info.Gateway != "" {
+				statusIface.IPv4Gateway = info.Gateway
 			}
-			if len(subnetInfo.DNS) > 0 {
-				statusIface.DNS = subnetInfo.DNS
+			if len(info.DNS) > 0 {
+				statusIface.DNS = info.DNS
 			}
 		}
 
@@ -436,20 +888,55 @@ func (r *NetworkConfigurationReconciler) buildSuccessMessage(totalMACs, resolved
 
 // NewNetworkConfigurationReconciler constructs a new reconciler, wiring the
 // controller-runtime client/scheme and a Kea service wrapper around the given client.
-func NewNetworkConfigurationReconciler(mgr ctrl.Manager, keaClient keainterface.KeaClient) *NetworkConfigurationReconciler {
+// kea6Client, when not nil, receives the kea-dhcp6 commands.
+func NewNetworkConfigurationReconciler(mgr ctrl.Manager, keaClient, kea6Client keainterface.KeaClient) *NetworkConfigurationReconciler {
+	kea := keaservice.New(keaClient)
+	kea.Client6 = kea6Client
 	return &NetworkConfigurationReconciler{
 		Client:    mgr.GetClient(),
 		Scheme:    mgr.GetScheme(),
 		KeaClient: keaClient,
-		Kea:       keaservice.New(keaClient),
+		Kea:       kea,
+		Recorder:  mgr.GetEventRecorder("kea-operator"),
 	}
 }
 
 // SetupWithManager registers the controller with the manager using the typed
-// NetworkConfiguration resource.
+// NetworkConfiguration resource. NetworkNamespace changes enqueue the
+// NetworkConfigurations that depend on them, found via a field index. Unless
+// KEA_LEASE_WATCH_INTERVAL is 0, a lease watcher is added that enqueues
+// NetworkConfigurations when Kea leases one of their MACs.
 func (r *NetworkConfigurationReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(),
+		&vitistackcrdsv1alpha1.NetworkConfiguration{}, networkNamespaceNameIndex, indexNetworkNamespaceName); err != nil {
+		return fmt.Errorf("failed to index NetworkConfigurations by %s: %w", networkNamespaceNameIndex, err)
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(),
+		&vitistackcrdsv1alpha1.NetworkConfiguration{}, MACAddressIndex, IndexMACAddresses); err != nil {
+		return fmt.Errorf("failed to index NetworkConfigurations by %s: %w", MACAddressIndex, err)
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&vitistackcrdsv1alpha1.NetworkConfiguration{}).
+		Watches(&vitistackcrdsv1alpha1.NetworkNamespace{},
+			handler.EnqueueRequestsFromMapFunc(r.networkConfigurationsForNetworkNamespace),
+			builder.WithPredicates(networkNamespaceChanged))
+
+	if interval := viper.GetDuration(consts.KEA_LEASE_WATCH_INTERVAL); interval > 0 {
+		leaseEvents := make(chan event.GenericEvent, 128)
+		if err := mgr.Add(&leaseWatcher{
+			client:   mgr.GetClient(),
+			kea:      r.Kea,
+			interval: interval,
+			pageSize: leaseWatchPageSize(),
+			events:   leaseEvents,
+		}); err != nil {
+			return fmt.Errorf("failed to add lease watcher: %w", err)
+		}
+		b = b.WatchesRawSource(source.Channel(leaseEvents, &handler.EnqueueRequestForObject{}))
+	}
+
+	return b.
 		WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles()}).
 		Named("networkconfiguration").
 		Complete(r)
@@ -495,7 +982,7 @@ func (r *NetworkConfigurationReconciler) getNetworkNamespace(ctx context.Context
 		return nil, true, err
 	}
 	if len(nnList.Items) == 0 {
-		return nil, true, fmt.Errorf("no NetworkNamespace found in namespace %s", namespace)
+		return nil, true, fmt.Errorf("%w in namespace %s", errNoNetworkNamespace, namespace)
 	}
 	return &nnList.Items[0], true, nil
 }
@@ -537,27 +1024,81 @@ func extractMACsFromTypedNetworkConfiguration(networkconf *vitistackcrdsv1alpha1
 	return out
 }
 
-// cleanupReservations performs a best-effort removal of reservations on delete.
-// It reads MACs from the typed NetworkConfiguration, resolves the subnet-id for
-// the namespace prefix, and issues reservation deletions in Kea.
+// cleanupReservations removes the reservations of a NetworkConfiguration on
+// delete and, when releaseLeasesOnDelete says so, the leases of its MACs. It
+// deletes exactly what the reservation inventory records; for resources
+// without one it falls back to the MACs in the spec and the subnet-id of the
+// namespace prefix.
 func (r *NetworkConfigurationReconciler) cleanupReservations(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration) error {
+	records := readReservationRecords(nc)
+	if len(records) > 0 {
+		if err := r.deleteRecordedReservations(ctx, records); err != nil {
+			return err
+		}
+	} else {
+		// No inventory (created before it was recorded): derive the subnet from
+		// the NetworkNamespace prefix, or look the reservations up by owner
+		// once the NetworkNamespace is gone.
+		nn, _, err := r.getNetworkNamespace(ctx, nc.GetNamespace(), nc.Spec.NetworkNamespaceName)
+		switch {
+		case networkNamespaceGone(err):
+			if records, err = r.deleteOwnedReservations(ctx, nc); err != nil {
+				return err
+			}
+		case err != nil:
+			vlog.Debug("skipping reservation cleanup, NetworkNamespace not available",
+				"namespace", nc.GetNamespace(), "error", err)
+			return err
+		default:
+			subnetID, err := r.Kea.GetSubnetID(ctx, nn.Status.IPv4Prefix)
+			if err != nil {
+				vlog.Debug("skipping reservation cleanup, subnet not found in KEA",
+					"ipv4Prefix", nn.Status.IPv4Prefix, "error", err)
+				return err
+			}
+			identifiers, _ := hostIdentifierByMAC(nc)
+			var errs []error
+			for _, mac := range extractMACsFromTypedNetworkConfiguration(nc) {
+				id := identifiers[mac]
+				if err := r.Kea.DeleteReservation(ctx, keamodels.ReservationConfig{MAC: mac, SubnetID: subnetID, IdentifierType: id.Type, Identifier: id.Value}); err != nil {
+					errs = append(errs, fmt.Errorf("%s in subnet %d: %w", mac, subnetID, err))
+					continue
+				}
+				records = append(records, reservationRecord{MAC: mac, SubnetID: subnetID})
+			}
+			if len(errs) > 0 {
+				return errors.Join(errs...)
+			}
+		}
+	}
+
+	// The NetworkNamespace may be gone by now; KEA_RELEASE_LEASES_ON_DELETE
+	// decides then.
 	nn, _, err := r.getNetworkNamespace(ctx, nc.GetNamespace(), nc.Spec.NetworkNamespaceName)
 	if err != nil {
-		vlog.Debug("skipping reservation cleanup, NetworkNamespace not available",
-			"namespace", nc.GetNamespace(), "error", err)
-		return err
+		nn = nil
 	}
-	subnetID, err := r.Kea.GetSubnetID(ctx, nn.Status.IPv4Prefix)
+	release, err := releaseLeasesOnDelete(nn)
 	if err != nil {
-		vlog.Debug("skipping reservation cleanup, subnet not found in KEA",
-			"ipv4Prefix", nn.Status.IPv4Prefix, "error", err)
-		return err
+		r.event(nc, corev1.EventTypeWarning, eventReasonInvalidSettings, eventActionDelete,
+			fmt.Sprintf("leaving leases to expire: %v", err))
 	}
-	macs := extractMACsFromTypedNetworkConfiguration(nc)
-	for _, mac := range macs {
-		_ = r.Kea.DeleteReservationForMAC(ctx, mac, subnetID)
+	if !release {
+		return nil
 	}
-	return nil
+	since := time.Now()
+	if ts := nc.GetDeletionTimestamp(); ts != nil {
+		since = ts.Time
+	}
+	// The reservations are gone; leases that cannot be released are recorded
+	// for a later sweep rather than holding up the finalizer.
+	failed, err := r.releaseLeases(ctx, nc, records, since)
+	if len(failed) == 0 {
+		return nil
+	}
+	r.event(nc, corev1.EventTypeWarning, eventReasonLeaseReleaseFailed, eventActionDelete,
+		fmt.Sprintf("recorded %d unreleased leases in ConfigMap %s: %v", len(failed), orphanConfigMapName, err))
+	return r.recordOrphanLeases(ctx, nc, failed, err)
 }
 
 // setCondition patches the status.conditions on the provided Unstructured object
@@ -586,6 +1127,29 @@ func (r *NetworkConfigurationReconciler) setCondition(ctx context.Context, nc *v
 	return nil
 }
 
+// event records a Kubernetes Event on obj. It is a no-op when no recorder is
+// wired, which keeps unit tests that construct the reconciler directly simple.
+func (r *NetworkConfigurationReconciler) event(obj runtime.Object, eventtype, reason, action, note string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(obj, nil, eventtype, reason, action, "%s", note)
+}
+
+// keaPools formats pool ranges in Kea's "start - end" pool syntax.
+func keaPools(ranges []subnetutil.IPRange) []string {
+	out := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		out = append(out, fmt.Sprintf("%s - %s", r.Start, r.End))
+	}
+	return out
+}
+
+// ownerKey identifies nc as the owner recorded in Kea user-context.
+func ownerKey(nc *vitistackcrdsv1alpha1.NetworkConfiguration) string {
+	return nc.GetNamespace() + "/" + nc.GetName()
+}
+
 func findCondition(conds []metav1.Condition, condType string) *metav1.Condition {
 	for i := range conds {
 		if conds[i].Type == condType {
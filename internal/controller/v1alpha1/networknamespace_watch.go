@@ -0,0 +1,71 @@
+package v1alpha1
+
+import (
+	"context"
+	"maps"
+
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// networkNamespaceNameIndex indexes NetworkConfigurations by
+// spec.networkNamespaceName so a NetworkNamespace change can find its
+// dependents without listing every NetworkConfiguration in the namespace.
+const networkNamespaceNameIndex = "spec.networkNamespaceName"
+
+// indexNetworkNamespaceName is the indexer func for networkNamespaceNameIndex.
+// NetworkConfigurations without a name are indexed under "" so the legacy
+// list-first fallback can be found too.
+func indexNetworkNamespaceName(obj client.Object) []string {
+	nc, ok := obj.(*vitistackcrdsv1alpha1.NetworkConfiguration)
+	if !ok {
+		return nil
+	}
+	return []string{nc.Spec.NetworkNamespaceName}
+}
+
+// networkNamespaceChanged passes NetworkNamespace updates that can change the
+// outcome of a NetworkConfiguration reconcile: the IPv4 prefix, the spec
+// (ipAllocation) and annotations (pool and allocation settings). Status-only
+// churn such as condition timestamps is dropped.
+var networkNamespaceChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldNN, okOld := e.ObjectOld.(*vitistackcrdsv1alpha1.NetworkNamespace)
+		newNN, okNew := e.ObjectNew.(*vitistackcrdsv1alpha1.NetworkNamespace)
+		if !okOld || !okNew {
+			return false
+		}
+		return oldNN.Status.IPv4Prefix != newNN.Status.IPv4Prefix ||
+			oldNN.GetGeneration() != newNN.GetGeneration() ||
+			!maps.Equal(oldNN.GetAnnotations(), newNN.GetAnnotations())
+	},
+}
+
+// networkConfigurationsForNetworkNamespace maps a NetworkNamespace to the
+// NetworkConfigurations that depend on it: those naming it in
+// spec.networkNamespaceName, plus those in the same namespace that leave the
+// name empty and fall back to the first NetworkNamespace listed.
+func (r *NetworkConfigurationReconciler) networkConfigurationsForNetworkNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx)
+
+	var requests []reconcile.Request
+	for _, name := range []string{obj.GetName(), ""} {
+		var ncs vitistackcrdsv1alpha1.NetworkConfigurationList
+		if err := r.List(ctx, &ncs,
+			client.InNamespace(obj.GetNamespace()),
+			client.MatchingFields{networkNamespaceNameIndex: name},
+		); err != nil {
+			log.Error(err, "failed to list NetworkConfigurations for NetworkNamespace",
+				"networkNamespace", obj.GetName(), "namespace", obj.GetNamespace())
+			continue
+		}
+		for i := range ncs.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&ncs.Items[i])})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,79 @@
+package v1alpha1
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+const testNamespace = "tenant-a"
+
+func testNC(namespace, name, networkNamespaceName string) *vitistackcrdsv1alpha1.NetworkConfiguration {
+	return &vitistackcrdsv1alpha1.NetworkConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       vitistackcrdsv1alpha1.NetworkConfigurationSpec{NetworkNamespaceName: networkNamespaceName},
+	}
+}
+
+func TestNetworkConfigurationsForNetworkNamespace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := vitistackcrdsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithIndex(&vitistackcrdsv1alpha1.NetworkConfiguration{}, networkNamespaceNameIndex, indexNetworkNamespaceName).
+		WithObjects(
+			testNC(testNamespace, "named", "nn-1"),
+			testNC(testNamespace, "legacy", ""),
+			testNC(testNamespace, "other-nn", "nn-2"),
+			testNC("tenant-b", "other-namespace", "nn-1"),
+		).
+		Build()
+	r := &NetworkConfigurationReconciler{Client: c}
+
+	nn := &vitistackcrdsv1alpha1.NetworkNamespace{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "nn-1"}}
+	var got []string
+	for _, req := range r.networkConfigurationsForNetworkNamespace(context.Background(), nn) {
+		got = append(got, req.Name)
+	}
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "legacy" || got[1] != "named" {
+		t.Fatalf("expected [legacy named], got %v", got)
+	}
+}
+
+func TestNetworkNamespaceChanged(t *testing.T) {
+	base := vitistackcrdsv1alpha1.NetworkNamespace{ObjectMeta: metav1.ObjectMeta{Name: "nn-1", Generation: 1}}
+
+	prefix := base.DeepCopy()
+	prefix.Status.IPv4Prefix = "10.0.0.0/24"
+	annotated := base.DeepCopy()
+	annotated.Annotations = map[string]string{"kea.vitistack.io/pool-gateway": "last"}
+	statusOnly := base.DeepCopy()
+	statusOnly.Status.Message = "ready"
+
+	tests := []struct {
+		name string
+		new  *vitistackcrdsv1alpha1.NetworkNamespace
+		want bool
+	}{
+		{"prefix populated", prefix, true},
+		{"annotations changed", annotated, true},
+		{"status churn", statusOnly, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := networkNamespaceChanged.Update(event.UpdateEvent{ObjectOld: base.DeepCopy(), ObjectNew: tt.new})
+			if got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
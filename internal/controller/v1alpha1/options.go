@@ -0,0 +1,184 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const eventReasonSubnetOptionsUpdated = "SubnetOptionsUpdated"
+
+// dhcp4OptionCodes are the standard dhcp4 options commonly set per subnet.
+// Other option names are passed to Kea as they are.
+var dhcp4OptionCodes = map[string]int{
+	"routers":                     3,
+	"domain-name-servers":         6,
+	"domain-name":                 15,
+	"interface-mtu":               26,
+	"ntp-servers":                 42,
+	"vendor-encapsulated-options": 43,
+	"vendor-class-identifier":     60,
+	"tftp-server-name":            66,
+	"boot-file-name":              67,
+	"domain-search":               119,
+	"classless-static-route":      121,
+}
+
+// parseDHCPOptions parses "<option>=<data>" entries separated by ";" or
+// newlines. The option is a Kea option name or a numeric code, optionally
+// prefixed with "<space>/" for options outside the dhcp4 space. Data starting
+// with "0x" is sent as hex with csv-format disabled.
+func parseDHCPOptions(raw, source string) ([]keamodels.OptionData, error) {
+	var out []keamodels.OptionData
+	for entry := range strings.FieldsFuncSeq(raw, func(r rune) bool { return r == ';' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, data, ok := strings.Cut(entry, "=")
+		key, data = strings.TrimSpace(key), strings.TrimSpace(data)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s: malformed entry %q, expected <option>=<data>", source, entry)
+		}
+
+		opt := keamodels.OptionData{Data: data}
+		if space, name, found := strings.Cut(key, "/"); found {
+			opt.Space, key = strings.TrimSpace(space), strings.TrimSpace(name)
+		}
+		if code, err := strconv.Atoi(key); err == nil {
+			if code < 1 || code > 254 {
+				return nil, fmt.Errorf("%s: option code %d is out of range", source, code)
+			}
+			opt.Code = code
+		} else {
+			opt.Name = strings.ToLower(key)
+			if opt.Space == "" {
+				opt.Code = dhcp4OptionCodes[opt.Name]
+			}
+		}
+		if hex, isHex := strings.CutPrefix(strings.ToLower(data), "0x"); isHex {
+			csv := false
+			opt.Data, opt.CSVFormat = hex, &csv
+		}
+		if i := slices.IndexFunc(out, opt.Matches); i >= 0 {
+			out[i] = opt
+			continue
+		}
+		out = append(out, opt)
+	}
+	return out, nil
+}
+
+// subnetOptions resolves the option-data for a NetworkNamespace's subnet: the
+// global KEA_DHCP_OPTIONS, overridden option by option by the NetworkNamespace
+// dhcp-options annotation.
+func subnetOptions(nn *vitistackcrdsv1alpha1.NetworkNamespace) ([]keamodels.OptionData, error) {
+	options, err := parseDHCPOptions(viper.GetString(consts.KEA_DHCP_OPTIONS), consts.KEA_DHCP_OPTIONS)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := parseDHCPOptions(nn.GetAnnotations()[consts.DHCPOptionsAnnotation], consts.DHCPOptionsAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		if i := slices.IndexFunc(options, o.Matches); i >= 0 {
+			options[i] = o
+			continue
+		}
+		options = append(options, o)
+	}
+	return options, nil
+}
+
+// splitSubnetDNS moves the domain-name-servers option out of options and
+// returns its servers, which SubnetConfig carries as DNS.
+func splitSubnetDNS(options []keamodels.OptionData) ([]string, []keamodels.OptionData) {
+	var dns []string
+	rest := make([]keamodels.OptionData, 0, len(options))
+	for _, o := range options {
+		if o.Space == "" && o.Code == dhcp4OptionCodes["domain-name-servers"] {
+			for s := range strings.SplitSeq(o.Data, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					dns = append(dns, s)
+				}
+			}
+			continue
+		}
+		rest = append(rest, o)
+	}
+	return dns, rest
+}
+
+// syncSubnetOptions brings the option-data of an operator-created subnet in
+// line with want after it was created, so option changes on the
+// NetworkNamespace or in KEA_DHCP_OPTIONS reach existing subnets. Options
+// removed from want are left in Kea. Subnets configured by hand are not
+// touched. It returns info with the options now in Kea.
+func (r *NetworkConfigurationReconciler) syncSubnetOptions(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, info *keaservice.SubnetInfo, want []keamodels.OptionData, log logr.Logger) *keaservice.SubnetInfo {
+	if info == nil || !info.Managed {
+		return info
+	}
+	missing := keaservice.MissingOptions(want, info.Options)
+	if len(missing) == 0 {
+		return info
+	}
+	if err := r.Kea.UpdateSubnetOptions(ctx, info.ID, info.Subnet, missing); err != nil {
+		log.Error(err, "failed to update subnet options", "subnetID", info.ID)
+		return info
+	}
+	names := make([]string, 0, len(missing))
+	for _, o := range missing {
+		names = append(names, optionLabel(o))
+	}
+	log.Info("updated subnet options", "subnetID", info.ID, "options", names)
+	r.event(nc, corev1.EventTypeNormal, eventReasonSubnetOptionsUpdated, eventActionCreateSubnet,
+		fmt.Sprintf("set %s on Kea subnet %d", strings.Join(names, ", "), info.ID))
+
+	updated := *info
+	updated.Options = slices.Clone(info.Options)
+	for _, o := range missing {
+		if i := slices.IndexFunc(updated.Options, o.Matches); i >= 0 {
+			updated.Options[i] = o
+		} else {
+			updated.Options = append(updated.Options, o)
+		}
+		if o.Space != "" {
+			continue
+		}
+		switch o.Code {
+		case dhcp4OptionCodes["routers"]:
+			updated.Gateway = o.Data
+		case dhcp4OptionCodes["domain-name-servers"]:
+			updated.DNS, _ = splitSubnetDNS([]keamodels.OptionData{o})
+		}
+	}
+	return &updated
+}
+
+// optionLabel names o for messages: its name, or its code when it has none.
+func optionLabel(o keamodels.OptionData) string {
+	if o.Name != "" {
+		return o.Name
+	}
+	return "option " + strconv.Itoa(o.Code)
+}
+
+// writeSubnetOptions stores the subnet's option-data in the managed
+// subnet-options annotation.
+func (r *NetworkConfigurationReconciler) writeSubnetOptions(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, info *keaservice.SubnetInfo) error {
+	if info == nil {
+		return nil
+	}
+	return r.writeManagedAnnotation(ctx, nc, consts.SubnetOptionsAnnotation, info.Options, len(info.Options) == 0)
+}
@@ -0,0 +1,94 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseDHCPOptions(t *testing.T) {
+	opts, err := parseDHCPOptions("ntp-servers=10.0.0.5, 10.0.0.6; interface-mtu=9000\n252=http://wpad/;vendor-space/2=0x0A0B;ntp-servers=10.0.0.7", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 4 {
+		t.Fatalf("expected 4 options, got %+v", opts)
+	}
+	if opts[0].Name != "ntp-servers" || opts[0].Code != 42 || opts[0].Data != "10.0.0.7" {
+		t.Fatalf("expected the later ntp-servers entry to win, got %+v", opts[0])
+	}
+	if opts[2].Code != 252 || opts[2].Name != "" {
+		t.Fatalf("unexpected code-only option %+v", opts[2])
+	}
+	if o := opts[3]; o.Space != "vendor-space" || o.Code != 2 || o.Data != "0a0b" || o.CSVFormat == nil || *o.CSVFormat {
+		t.Fatalf("unexpected hex option %+v", o)
+	}
+	for _, bad := range []string{"ntp-servers", "=1", "300=x"} {
+		if _, err := parseDHCPOptions(bad, "test"); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestSubnetOptions_AnnotationOverridesDefaults(t *testing.T) {
+	viper.Set(consts.KEA_DHCP_OPTIONS, "domain-name=example.org;domain-name-servers=10.0.0.2")
+	t.Cleanup(func() { viper.Set(consts.KEA_DHCP_OPTIONS, nil) })
+	nn := &vitistackcrdsv1alpha1.NetworkNamespace{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		consts.DHCPOptionsAnnotation: "domain-name=lab.example.org;tftp-server-name=10.0.0.9",
+	}}}
+	opts, err := subnetOptions(nn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 3 || opts[0].Data != "lab.example.org" {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+	dns, rest := splitSubnetDNS(opts)
+	if len(dns) != 1 || dns[0] != "10.0.0.2" || len(rest) != 2 {
+		t.Fatalf("expected DNS split out, got %v and %+v", dns, rest)
+	}
+}
+
+// acceptingKea accepts every command and records it.
+type acceptingKea struct {
+	commands []keamodels.Request
+}
+
+func (f *acceptingKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	f.commands = append(f.commands, cmd)
+	return keamodels.Response{Result: 0}, nil
+}
+
+func TestSyncSubnetOptions(t *testing.T) {
+	nc := ncWithAnnotations(nil)
+	r, _ := newMigrationReconciler(t, nc)
+	kea := &acceptingKea{}
+	r.KeaClient, r.Kea = kea, keaservice.New(kea)
+	info := &keaservice.SubnetInfo{ID: 7, Subnet: testOldPrefix, Managed: true, Options: []keamodels.OptionData{
+		{Name: "domain-name", Code: 15, Space: "dhcp4", Data: "example.org"},
+	}}
+	want := []keamodels.OptionData{
+		{Name: "domain-name", Code: 15, Data: "example.org"},
+		{Name: "domain-name-servers", Code: 6, Data: "10.0.0.2, 10.0.0.3"},
+	}
+
+	got := r.syncSubnetOptions(context.Background(), nc, info, want, logr.Discard())
+	if len(kea.commands) != 1 || kea.commands[0].Command != "subnet4-delta-add" {
+		t.Fatalf("expected one subnet4-delta-add, got %+v", kea.commands)
+	}
+	if len(got.Options) != 2 || len(got.DNS) != 2 {
+		t.Fatalf("expected the new option reflected, got %+v", got)
+	}
+
+	info.Managed = false
+	if r.syncSubnetOptions(context.Background(), nc, info, want, logr.Discard()); len(kea.commands) != 1 {
+		t.Fatal("expected subnets not created by the operator to be left alone")
+	}
+}
@@ -0,0 +1,113 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+)
+
+// relayGateway is the relay-addresses value that relays each subnet via its
+// own gateway.
+const relayGateway = "gateway"
+
+// maxInterfaceNameLen is the longest Linux interface name (IFNAMSIZ - 1).
+const maxInterfaceNameLen = 15
+
+// subnetRelay is how Kea selects the subnets of a NetworkNamespace: by the
+// relay agent address of relayed requests or by the interface directly
+// attached clients are on.
+type subnetRelay struct {
+	// FromGateway relays each subnet via its gateway; otherwise Addresses
+	// are set on every subnet.
+	FromGateway bool
+	Addresses   []string
+	Interface   string
+}
+
+// addresses returns the relay addresses of a subnet with the given gateway.
+func (s subnetRelay) addresses(gateway string) []string {
+	if s.FromGateway {
+		if gateway == "" {
+			return nil
+		}
+		return []string{gateway}
+	}
+	return s.Addresses
+}
+
+// subnetRelaySettings resolves the relay addresses and interface of the
+// subnets of nn: KEA_RELAY_ADDRESSES and KEA_SUBNET_INTERFACE, each replaced
+// by the NetworkNamespace annotation of the same setting when present.
+func subnetRelaySettings(nn *vitistackcrdsv1alpha1.NetworkNamespace) (subnetRelay, error) {
+	annotations := nn.GetAnnotations()
+	setting := func(annotation, env string) (string, string) {
+		if v, ok := annotations[annotation]; ok {
+			return strings.TrimSpace(v), annotation
+		}
+		return strings.TrimSpace(viper.GetString(env)), env
+	}
+
+	var out subnetRelay
+	raw, source := setting(consts.RelayAddressesAnnotation, consts.KEA_RELAY_ADDRESSES)
+	if strings.EqualFold(raw, relayGateway) {
+		out.FromGateway = true
+	} else {
+		for a := range strings.SplitSeq(raw, ",") {
+			if a = strings.TrimSpace(a); a == "" {
+				continue
+			}
+			ip := net.ParseIP(a)
+			if ip == nil || ip.To4() == nil {
+				return out, fmt.Errorf("%s: %q is neither %q nor an IPv4 address", source, a, relayGateway)
+			}
+			out.Addresses = append(out.Addresses, ip.String())
+		}
+	}
+
+	out.Interface, source = setting(consts.SubnetInterfaceAnnotation, consts.KEA_SUBNET_INTERFACE)
+	if len(out.Interface) > maxInterfaceNameLen || strings.ContainsAny(out.Interface, " \t/,") {
+		return out, fmt.Errorf("%s: %q is not a valid interface name", source, out.Interface)
+	}
+	return out, nil
+}
+
+// parseVLAN parses the VLAN of a NetworkConfiguration interface, given as the
+// id ("120") or with a vlan prefix ("vlan120", "vlan-120", "VLAN.120").
+func parseVLAN(raw string) (int, error) {
+	v := strings.TrimSpace(raw)
+	if len(v) >= 4 && strings.EqualFold(v[:4], "vlan") {
+		v = strings.TrimLeft(v[4:], "-._ ")
+	}
+	id, err := strconv.Atoi(v)
+	if err != nil || id < 1 || id > 4094 {
+		return 0, fmt.Errorf("interface VLAN %q is not a VLAN id", raw)
+	}
+	return id, nil
+}
+
+// checkInterfaceVLAN reports an error when the interface of nc with mac is on
+// a VLAN other than vlan, the VLAN of its NetworkNamespace. Interfaces without
+// a VLAN, and NetworkNamespaces without one (vlan 0), are not checked.
+func checkInterfaceVLAN(nc *vitistackcrdsv1alpha1.NetworkConfiguration, mac string, vlan int) error {
+	if vlan == 0 {
+		return nil
+	}
+	for _, iface := range nc.Spec.NetworkInterfaces {
+		if normalizeMAC(iface.MacAddress) != mac || strings.TrimSpace(iface.Vlan) == "" {
+			continue
+		}
+		got, err := parseVLAN(iface.Vlan)
+		if err != nil {
+			return err
+		}
+		if got != vlan {
+			return fmt.Errorf("interface is on VLAN %d but its NetworkNamespace is on VLAN %d", got, vlan)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package v1alpha1
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSubnetRelaySettings(t *testing.T) {
+	viper.Set(consts.KEA_RELAY_ADDRESSES, relayGateway)
+	t.Cleanup(func() { viper.Set(consts.KEA_RELAY_ADDRESSES, nil) })
+	nn := &vitistackcrdsv1alpha1.NetworkNamespace{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+
+	relay, err := subnetRelaySettings(nn)
+	if err != nil || !relay.FromGateway || !slices.Equal(relay.addresses("10.0.0.1"), []string{"10.0.0.1"}) {
+		t.Fatalf("expected the gateway default, got %+v, %v", relay, err)
+	}
+
+	nn.Annotations[consts.RelayAddressesAnnotation] = "10.0.0.254, 10.9.0.1"
+	nn.Annotations[consts.SubnetInterfaceAnnotation] = "eth1"
+	relay, err = subnetRelaySettings(nn)
+	if err != nil || relay.FromGateway || !slices.Equal(relay.addresses("10.0.0.1"), []string{"10.0.0.254", "10.9.0.1"}) || relay.Interface != "eth1" {
+		t.Fatalf("expected the annotations to win, got %+v, %v", relay, err)
+	}
+
+	nn.Annotations[consts.RelayAddressesAnnotation] = ""
+	if relay, err = subnetRelaySettings(nn); err != nil || relay.addresses("10.0.0.1") != nil {
+		t.Fatalf("expected an empty annotation to set no relay, got %+v, %v", relay, err)
+	}
+
+	for annotation, bad := range map[string]string{
+		consts.RelayAddressesAnnotation:  "2001:db8::1",
+		consts.SubnetInterfaceAnnotation: "a-very-long-interface-name",
+	} {
+		nn.Annotations = map[string]string{annotation: bad}
+		if _, err := subnetRelaySettings(nn); err == nil {
+			t.Errorf("%s=%q: expected an error", annotation, bad)
+		}
+	}
+}
+
+func TestParseVLAN(t *testing.T) {
+	for raw, want := range map[string]int{"120": 120, "vlan120": 120, "VLAN-7": 7, " vlan.4094 ": 4094} {
+		if got, err := parseVLAN(raw); err != nil || got != want {
+			t.Errorf("%q: expected %d, got %d, %v", raw, want, got, err)
+		}
+	}
+	for _, bad := range []string{"0", "4095", "vlan", "trunk"} {
+		if _, err := parseVLAN(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+// TestProcessMACReservations_VLANMismatch verifies that an interface on
+// another VLAN than its NetworkNamespace is reported and not reserved.
+func TestProcessMACReservations_VLANMismatch(t *testing.T) {
+	nc := ncWithAnnotations(nil)
+	nc.Spec.NetworkInterfaces[0].Vlan = "vlan121"
+	nc.Spec.NetworkInterfaces[1].Vlan = "120"
+	r, kea := newMigrationReconciler(t, nc)
+	ctx := context.Background()
+	macs := []string{testMAC0}
+
+	res := r.processMACReservations(ctx, nc, macs, []reservationTarget{{SubnetID: 1, Prefix: testOldPrefix, VLAN: 120}}, nil, nil, nil, logr.Discard())
+	if st := res.interfaces[testMAC0]; st == nil || st.Reason != interfaceReasonVLANMismatch {
+		t.Fatalf("expected %s, got %+v", interfaceReasonVLANMismatch, st)
+	}
+	for _, cmd := range kea.commands {
+		if cmd.Command == "reservation-add" {
+			t.Fatalf("expected no reservation for a mismatched interface, got %v", kea.commands)
+		}
+	}
+	r.reportReservationStages(ctx, nc, macs, res)
+	if cond := findCondition(nc.Status.Conditions, conditionTypeReservationsReady); cond == nil || cond.Reason != conditionReasonInvalidRequest {
+		t.Fatalf("expected ReservationsReady/%s, got %+v", conditionReasonInvalidRequest, cond)
+	}
+
+	if err := checkInterfaceVLAN(nc, testMAC1, 120); err != nil {
+		t.Fatalf("expected a matching VLAN to pass, got %v", err)
+	}
+	if err := checkInterfaceVLAN(nc, testMAC0, 0); err != nil {
+		t.Fatalf("expected no check without a NetworkNamespace VLAN, got %v", err)
+	}
+}
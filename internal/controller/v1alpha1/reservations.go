@@ -0,0 +1,248 @@
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// reservationRecord is one Kea reservation the operator made for a
+// NetworkConfiguration. The records are the authoritative inventory used for
+// cleanup, for dropping interfaces removed from the spec and for prefix
+// migration, so none of those depend on re-deriving the subnet later.
+type reservationRecord struct {
+	MAC       string `json:"mac"`
+	SubnetID  int    `json:"subnetID"`
+	IPAddress string `json:"ip,omitempty"`
+	// Family is 6 for kea-dhcp6 reservations and unset for DHCPv4 ones. DUID
+	// is the DHCPv6 identifier the reservation is keyed on, when not the MAC.
+	Family int    `json:"family,omitempty"`
+	DUID   string `json:"duid,omitempty"`
+	// IdentifierType and Identifier are the DHCPv4 host identifier the
+	// reservation is keyed on when not the MAC; Identifier is empty when it
+	// is derived from the MAC.
+	IdentifierType string `json:"identifierType,omitempty"`
+	Identifier     string `json:"identifier,omitempty"`
+	// Prefix is the NetworkNamespace prefix the reservation was made for.
+	Prefix string `json:"prefix"`
+	// Peer is the Kea server that accepted the reservation, when known.
+	Peer    string    `json:"peer,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// sameReservation reports whether a and b describe the same Kea reservation.
+func (a reservationRecord) sameReservation(b reservationRecord) bool {
+	return a.MAC == b.MAC && a.Family == b.Family && a.DUID == b.DUID && a.hostIdentifier() == b.hostIdentifier() &&
+		a.SubnetID == b.SubnetID && a.IPAddress == b.IPAddress
+}
+
+// hostIdentifier returns the DHCPv4 host identifier rec is keyed on.
+func (rec reservationRecord) hostIdentifier() hostIdentifier {
+	return hostIdentifier{Type: rec.IdentifierType, Value: rec.Identifier}
+}
+
+// deleteReservation removes the Kea reservation rec describes. Without
+// ENABLE_DHCP6 a DHCPv6 record is dropped and its kea-dhcp6 reservation left
+// alone, as there is no kea-dhcp6 to send the command to.
+func (r *NetworkConfigurationReconciler) deleteReservation(ctx context.Context, rec reservationRecord) error {
+	if rec.Family == familyIPv6 {
+		if !dhcp6Enabled() {
+			return nil
+		}
+		return r.Kea.DeleteReservation6(ctx, rec.MAC, rec.DUID, rec.SubnetID)
+	}
+	return r.Kea.DeleteReservation(ctx, keamodels.ReservationConfig{
+		MAC: rec.MAC, SubnetID: rec.SubnetID, IdentifierType: rec.IdentifierType, Identifier: rec.Identifier,
+	})
+}
+
+// recordedSubnetIDs maps each MAC to the subnets its DHCPv4 records are in.
+func recordedSubnetIDs(records []reservationRecord) map[string][]int {
+	out := make(map[string][]int)
+	for _, rec := range records {
+		if rec.Family != familyIPv6 {
+			out[rec.MAC] = append(out[rec.MAC], rec.SubnetID)
+		}
+	}
+	return out
+}
+
+// InventorySubnetIDs returns the Kea subnets the DHCPv4 reservations in the
+// reservation inventory of nc are in, for callers outside the controller.
+func InventorySubnetIDs(nc *vitistackcrdsv1alpha1.NetworkConfiguration) []int {
+	var out []int
+	for _, ids := range recordedSubnetIDs(readReservationRecords(nc)) {
+		for _, id := range ids {
+			if !slices.Contains(out, id) {
+				out = append(out, id)
+			}
+		}
+	}
+	return out
+}
+
+// recordedLease returns the DHCPv4 lease the MAC of rec holds in the subnet
+// of rec, or nil when it holds none there. It is looked up by the recorded
+// address when there is one and among all leases of the MAC otherwise, so a
+// newer lease in another subnet does not hide it.
+func (r *NetworkConfigurationReconciler) recordedLease(ctx context.Context, rec reservationRecord) (*keaservice.LeaseInfo, error) {
+	if rec.IPAddress != "" {
+		lease, err := r.Kea.GetLeaseForIP(ctx, rec.IPAddress)
+		if err != nil || lease == nil {
+			return nil, err
+		}
+		if lease.HWAddress != rec.MAC || lease.SubnetID != rec.SubnetID {
+			return nil, nil
+		}
+		return lease, nil
+	}
+	leases, err := r.Kea.GetLeasesForMAC(ctx, rec.MAC)
+	if err != nil {
+		return nil, err
+	}
+	for i := range leases {
+		if leases[i].SubnetID == rec.SubnetID {
+			return &leases[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// readReservationRecords returns the records kept in the managed reservations
+// annotation. A missing or unparsable annotation yields no records.
+func readReservationRecords(nc *vitistackcrdsv1alpha1.NetworkConfiguration) []reservationRecord {
+	raw := nc.GetAnnotations()[consts.ReservationsAnnotation]
+	if raw == "" {
+		return nil
+	}
+	var records []reservationRecord
+	if err := json.Unmarshal([]byte(raw), &records); err != nil {
+		return nil
+	}
+	return records
+}
+
+// reservationsMade builds records for the DHCPv4 reservations one reconcile
+// made; prefixOf maps a subnet id to the prefix recorded for it and
+// identifiers holds the host identifier of MACs not keyed on their MAC.
+func reservationsMade(macToSubnetID map[string]int, macToIP map[string]string, identifiers map[string]hostIdentifier, prefixOf func(subnetID int) string, peer string, now time.Time) []reservationRecord {
+	out := make([]reservationRecord, 0, len(macToSubnetID))
+	for mac, sid := range macToSubnetID {
+		id := identifiers[mac]
+		out = append(out, reservationRecord{
+			MAC: mac, SubnetID: sid, IPAddress: macToIP[mac], IdentifierType: id.Type, Identifier: id.Value,
+			Prefix: prefixOf(sid), Peer: peer, Created: now,
+		})
+	}
+	return out
+}
+
+// mergeReservationRecords adds made to records, replacing earlier records for
+// the same MAC, family and prefix and keeping everything else, notably records for
+// older prefixes that are still waiting to be migrated away. A record that
+// still describes the same reservation keeps its original peer and timestamp.
+func mergeReservationRecords(records, made []reservationRecord) []reservationRecord {
+	out := make([]reservationRecord, 0, len(records)+len(made))
+	for _, rec := range records {
+		if !slices.ContainsFunc(made, func(m reservationRecord) bool {
+			return m.MAC == rec.MAC && m.Family == rec.Family && m.Prefix == rec.Prefix
+		}) {
+			out = append(out, rec)
+		}
+	}
+	for _, m := range made {
+		if i := slices.IndexFunc(records, func(rec reservationRecord) bool { return rec.Prefix == m.Prefix && rec.sameReservation(m) }); i >= 0 {
+			m.Peer, m.Created = records[i].Peer, records[i].Created
+		}
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].MAC != out[j].MAC {
+			return out[i].MAC < out[j].MAC
+		}
+		if out[i].Family != out[j].Family {
+			return out[i].Family < out[j].Family
+		}
+		return out[i].SubnetID < out[j].SubnetID
+	})
+	return out
+}
+
+// pruneRemovedInterfaces deletes the recorded reservations of MACs no longer
+// in the spec and returns the records that remain. Records whose deletion
+// fails are kept so the next reconcile retries them.
+func (r *NetworkConfigurationReconciler) pruneRemovedInterfaces(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, records []reservationRecord, macs []string, log logr.Logger) []reservationRecord {
+	out := make([]reservationRecord, 0, len(records))
+	for _, rec := range records {
+		if slices.Contains(macs, rec.MAC) {
+			out = append(out, rec)
+			continue
+		}
+		if err := r.deleteReservation(ctx, rec); err != nil {
+			log.Error(err, "failed to remove reservation for interface no longer in spec", "mac", rec.MAC, "subnetID", rec.SubnetID)
+			out = append(out, rec)
+			continue
+		}
+		log.Info("removed reservation for interface no longer in spec", "mac", rec.MAC, "subnetID", rec.SubnetID, "ip", rec.IPAddress)
+		r.event(nc, corev1.EventTypeNormal, eventReasonReservationRemoved, eventActionDelete,
+			fmt.Sprintf("removed reservation for %s in subnet %d, the interface is no longer in spec", rec.MAC, rec.SubnetID))
+	}
+	return out
+}
+
+// retireChangedIdentifiers deletes the recorded DHCPv4 reservations of MACs
+// whose host identifier no longer matches identifiers and returns the records
+// that remain. It runs before reservations are made, as Kea refuses a second
+// reservation of the same address under the new identifier. Records whose
+// deletion fails are kept so the next reconcile retries them.
+func (r *NetworkConfigurationReconciler) retireChangedIdentifiers(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, records []reservationRecord, identifiers map[string]hostIdentifier, log logr.Logger) []reservationRecord {
+	out := make([]reservationRecord, 0, len(records))
+	for _, rec := range records {
+		if rec.Family == familyIPv6 || rec.hostIdentifier() == identifiers[rec.MAC] {
+			out = append(out, rec)
+			continue
+		}
+		old := keaservice.IdentifierHWAddress
+		if rec.IdentifierType != "" {
+			old = rec.IdentifierType
+		}
+		if err := r.deleteReservation(ctx, rec); err != nil {
+			log.Error(err, "failed to remove reservation keyed on a previous host identifier", "mac", rec.MAC, "subnetID", rec.SubnetID, "identifierType", old)
+			out = append(out, rec)
+			continue
+		}
+		log.Info("removed reservation keyed on a previous host identifier", "mac", rec.MAC, "subnetID", rec.SubnetID, "identifierType", old)
+		r.event(nc, corev1.EventTypeNormal, eventReasonReservationRemoved, eventActionDelete,
+			fmt.Sprintf("removed %s reservation for %s in subnet %d, the host identifier changed", old, rec.MAC, rec.SubnetID))
+	}
+	return out
+}
+
+// deleteRecordedReservations deletes every recorded reservation from Kea. It
+// needs neither the NetworkNamespace nor a subnet lookup, so it works after
+// the NetworkNamespace is gone.
+func (r *NetworkConfigurationReconciler) deleteRecordedReservations(ctx context.Context, records []reservationRecord) error {
+	var errs []error
+	for _, rec := range records {
+		if err := r.deleteReservation(ctx, rec); err != nil {
+			errs = append(errs, fmt.Errorf("%s in subnet %d: %w", rec.MAC, rec.SubnetID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeReservationRecords stores records in the managed reservations annotation.
+func (r *NetworkConfigurationReconciler) writeReservationRecords(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, records []reservationRecord) error {
+	return r.writeManagedAnnotation(ctx, nc, consts.ReservationsAnnotation, records, len(records) == 0)
+}
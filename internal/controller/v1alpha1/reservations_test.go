@@ -0,0 +1,100 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMergeReservationRecords(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := created.Add(time.Hour)
+	records := []reservationRecord{
+		{MAC: testMAC0, SubnetID: 1, Prefix: testOldPrefix, Created: created},
+		{MAC: testMAC0, SubnetID: 2, IPAddress: "10.1.0.10", Prefix: testNewPrefix, Peer: "primary", Created: created},
+		{MAC: testMAC1, SubnetID: 2, Prefix: testNewPrefix, Created: created},
+	}
+	made := reservationsMade(
+		map[string]int{testMAC0: 2, testMAC1: 2},
+		map[string]string{testMAC0: "10.1.0.10", testMAC1: "10.1.0.11"},
+		nil,
+		func(int) string { return testNewPrefix }, "secondary", now,
+	)
+	got := mergeReservationRecords(records, made)
+	want := []reservationRecord{
+		{MAC: testMAC0, SubnetID: 1, Prefix: testOldPrefix, Created: created},
+		// unchanged reservation keeps where and when it was made
+		{MAC: testMAC0, SubnetID: 2, IPAddress: "10.1.0.10", Prefix: testNewPrefix, Peer: "primary", Created: created},
+		// pinned to an IP since it was recorded
+		{MAC: testMAC1, SubnetID: 2, IPAddress: "10.1.0.11", Prefix: testNewPrefix, Peer: "secondary", Created: now},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("record %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestPruneRemovedInterfaces(t *testing.T) {
+	nc := &vitistackcrdsv1alpha1.NetworkConfiguration{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "nc"}}
+	r, kea := newMigrationReconciler(t, nc)
+	records := []reservationRecord{
+		{MAC: testMAC0, SubnetID: 2, Prefix: testNewPrefix},
+		{MAC: testMAC1, SubnetID: 7, Prefix: testNewPrefix},
+	}
+
+	got := r.pruneRemovedInterfaces(context.Background(), nc, records, []string{testMAC0}, logr.Discard())
+	if len(got) != 1 || got[0].MAC != testMAC0 {
+		t.Fatalf("expected only %s to remain, got %v", testMAC0, got)
+	}
+	if len(kea.commands) != 1 || kea.commands[0].Args["identifier"] != testMAC1 || kea.commands[0].Args["subnet-id"] != 7 {
+		t.Fatalf("expected reservation-del for %s in subnet 7, got %v", testMAC1, kea.commands)
+	}
+}
+
+func TestRetireChangedIdentifiers(t *testing.T) {
+	nc := &vitistackcrdsv1alpha1.NetworkConfiguration{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "nc"}}
+	r, kea := newMigrationReconciler(t, nc)
+	records := []reservationRecord{
+		{MAC: testMAC0, SubnetID: 2, Prefix: testNewPrefix},
+		{MAC: testMAC1, SubnetID: 2, IdentifierType: keaservice.IdentifierClientID, Prefix: testNewPrefix},
+		{MAC: testMAC1, SubnetID: 3, Family: familyIPv6, Prefix: "2001:db8::/64"},
+	}
+	identifiers := map[string]hostIdentifier{testMAC0: {Type: keaservice.IdentifierClientID}, testMAC1: {Type: keaservice.IdentifierClientID}}
+
+	got := r.retireChangedIdentifiers(context.Background(), nc, records, identifiers, logr.Discard())
+	if len(got) != 2 || got[0].MAC != testMAC1 || got[1].Family != familyIPv6 {
+		t.Fatalf("expected the hw-address record of %s retired, got %v", testMAC0, got)
+	}
+	if len(kea.commands) != 1 || kea.commands[0].Args["identifier-type"] != keaservice.IdentifierHWAddress || kea.commands[0].Args["identifier"] != testMAC0 {
+		t.Fatalf("expected reservation-del for hw-address %s, got %v", testMAC0, kea.commands)
+	}
+}
+
+// TestCleanupReservations_UsesInventory verifies that deletion removes the
+// recorded reservations without looking up the (absent) NetworkNamespace.
+func TestCleanupReservations_UsesInventory(t *testing.T) {
+	nc := &vitistackcrdsv1alpha1.NetworkConfiguration{ObjectMeta: metav1.ObjectMeta{
+		Namespace: testNamespace, Name: "nc",
+		Annotations: map[string]string{
+			consts.ReservationsAnnotation: `[{"mac":"` + testMAC0 + `","subnetID":4,"prefix":"` + testOldPrefix + `","created":"2025-01-01T00:00:00Z"}]`,
+		},
+	}}
+	r, kea := newMigrationReconciler(t, nc)
+
+	if err := r.cleanupReservations(context.Background(), nc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(kea.commands) != 1 || kea.commands[0].Command != "reservation-del" || kea.commands[0].Args["subnet-id"] != 4 {
+		t.Fatalf("expected reservation-del in subnet 4, got %v", kea.commands)
+	}
+}
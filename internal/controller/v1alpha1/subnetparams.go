@@ -0,0 +1,158 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const eventReasonSubnetSettingsUpdated = "SubnetSettingsUpdated"
+
+// subnetParams resolves the require-client-classes and lease times of a
+// NetworkNamespace's subnet: the KEA_REQUIRE_CLIENT_CLASSES, KEA_VALID_LIFETIME,
+// KEA_RENEW_TIMER and KEA_REBIND_TIMER defaults, each replaced by the
+// NetworkNamespace annotation of the same setting when present. Only those
+// fields of the returned config are set.
+func subnetParams(nn *vitistackcrdsv1alpha1.NetworkNamespace) (keamodels.SubnetConfig, error) {
+	annotations := nn.GetAnnotations()
+	setting := func(annotation, env string) (string, string) {
+		if v, ok := annotations[annotation]; ok {
+			return v, annotation
+		}
+		return viper.GetString(env), env
+	}
+
+	var cfg keamodels.SubnetConfig
+	var err error
+	raw, source := setting(consts.RequireClientClassesAnnotation, consts.KEA_REQUIRE_CLIENT_CLASSES)
+	if cfg.RequireClientClasses, err = parseClientClasses(raw, source); err != nil {
+		return cfg, err
+	}
+	for _, f := range []struct {
+		dst         *int
+		annotation  string
+		environment string
+	}{
+		{&cfg.ValidLife, consts.ValidLifetimeAnnotation, consts.KEA_VALID_LIFETIME},
+		{&cfg.RenewTimer, consts.RenewTimerAnnotation, consts.KEA_RENEW_TIMER},
+		{&cfg.RebindTimer, consts.RebindTimerAnnotation, consts.KEA_REBIND_TIMER},
+	} {
+		raw, source := setting(f.annotation, f.environment)
+		if *f.dst, err = parseSeconds(raw, source); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, validateLeaseTimes(cfg)
+}
+
+// parseClientClasses parses a comma-separated list of Kea client class names.
+func parseClientClasses(raw, source string) ([]string, error) {
+	var out []string
+	for c := range strings.SplitSeq(raw, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if strings.ContainsFunc(c, func(r rune) bool { return r == ' ' || r == '\t' || r == '\'' || r == '"' }) {
+			return nil, fmt.Errorf("%s: invalid client class name %q", source, c)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// parseSeconds parses a lease time given as whole seconds or as a duration
+// such as "30m". An empty value returns 0.
+func parseSeconds(raw, source string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	secs, err := strconv.Atoi(raw)
+	if err != nil {
+		d, derr := time.ParseDuration(raw)
+		if derr != nil {
+			return 0, fmt.Errorf("%s: %q is neither seconds nor a duration", source, raw)
+		}
+		if d%time.Second != 0 {
+			return 0, fmt.Errorf("%s: %q is not a whole number of seconds", source, raw)
+		}
+		secs = int(d / time.Second)
+	}
+	if secs <= 0 || secs > math.MaxUint32 {
+		return 0, fmt.Errorf("%s: %q is out of range", source, raw)
+	}
+	return secs, nil
+}
+
+// validateLeaseTimes checks that the lease times that are set are ordered
+// renew <= rebind <= valid, as clients expect.
+func validateLeaseTimes(cfg keamodels.SubnetConfig) error {
+	if cfg.RenewTimer > 0 && cfg.RebindTimer > 0 && cfg.RenewTimer > cfg.RebindTimer {
+		return fmt.Errorf("renew timer %ds exceeds rebind timer %ds", cfg.RenewTimer, cfg.RebindTimer)
+	}
+	if cfg.ValidLife > 0 {
+		if cfg.RebindTimer > cfg.ValidLife {
+			return fmt.Errorf("rebind timer %ds exceeds valid lifetime %ds", cfg.RebindTimer, cfg.ValidLife)
+		}
+		if cfg.RenewTimer > cfg.ValidLife {
+			return fmt.Errorf("renew timer %ds exceeds valid lifetime %ds", cfg.RenewTimer, cfg.ValidLife)
+		}
+	}
+	return nil
+}
+
+// syncSubnetParams brings the lease times, pool require-client-classes, relay
+// addresses and interface of an operator-created subnet in line with want, so
+// changes on the NetworkNamespace or in the defaults reach existing subnets.
+// Lease times and relay settings removed from the settings are left in Kea. Subnets configured by hand are
+// not touched. It returns info with the settings now in Kea.
+func (r *NetworkConfigurationReconciler) syncSubnetParams(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, info *keaservice.SubnetInfo, want keamodels.SubnetConfig, log logr.Logger) *keaservice.SubnetInfo {
+	if info == nil || !info.Managed {
+		return info
+	}
+	changed, err := r.Kea.UpdateSubnetParams(ctx, info, want)
+	if err != nil {
+		log.Error(err, "failed to update subnet settings", "subnetID", info.ID)
+		return info
+	}
+	if len(changed) == 0 {
+		return info
+	}
+	log.Info("updated subnet settings", "subnetID", info.ID, "fields", changed)
+	r.event(nc, corev1.EventTypeNormal, eventReasonSubnetSettingsUpdated, eventActionCreateSubnet,
+		fmt.Sprintf("set %s on Kea subnet %d", strings.Join(changed, ", "), info.ID))
+
+	updated := *info
+	if want.ValidLife > 0 {
+		updated.ValidLife = want.ValidLife
+	}
+	if want.RenewTimer > 0 {
+		updated.RenewTimer = want.RenewTimer
+	}
+	if want.RebindTimer > 0 {
+		updated.RebindTimer = want.RebindTimer
+	}
+	if len(want.RelayAddresses) > 0 {
+		updated.RelayAddresses = want.RelayAddresses
+	}
+	if want.Interface != "" {
+		updated.Interface = want.Interface
+	}
+	updated.Pools = make([]keaservice.SubnetPool, len(info.Pools))
+	for i, p := range info.Pools {
+		updated.Pools[i] = keaservice.SubnetPool{Range: p.Range, RequireClientClasses: want.RequireClientClasses}
+	}
+	return &updated
+}
@@ -0,0 +1,58 @@
+package v1alpha1
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSubnetParams_AnnotationsOverrideDefaults(t *testing.T) {
+	viper.Set(consts.KEA_REQUIRE_CLIENT_CLASSES, "biosclients, ueficlients")
+	viper.Set(consts.KEA_VALID_LIFETIME, "4000")
+	viper.Set(consts.KEA_RENEW_TIMER, "1000")
+	t.Cleanup(func() {
+		for _, k := range []string{consts.KEA_REQUIRE_CLIENT_CLASSES, consts.KEA_VALID_LIFETIME, consts.KEA_RENEW_TIMER} {
+			viper.Set(k, nil)
+		}
+	})
+
+	nn := &vitistackcrdsv1alpha1.NetworkNamespace{ObjectMeta: metav1.ObjectMeta{Name: "ci"}}
+	cfg, err := subnetParams(nn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cfg.RequireClientClasses, []string{"biosclients", "ueficlients"}) || cfg.ValidLife != 4000 || cfg.RenewTimer != 1000 || cfg.RebindTimer != 0 {
+		t.Fatalf("unexpected defaults %+v", cfg)
+	}
+
+	nn.Annotations = map[string]string{
+		consts.RequireClientClassesAnnotation: "",
+		consts.ValidLifetimeAnnotation:        "10m",
+		consts.RenewTimerAnnotation:           "150",
+		consts.RebindTimerAnnotation:          "5m",
+	}
+	if cfg, err = subnetParams(nn); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RequireClientClasses != nil || cfg.ValidLife != 600 || cfg.RenewTimer != 150 || cfg.RebindTimer != 300 {
+		t.Fatalf("unexpected overrides %+v", cfg)
+	}
+
+	for key, bad := range map[string]string{
+		consts.ValidLifetimeAnnotation:        "2m",
+		consts.RenewTimerAnnotation:           "soon",
+		consts.RebindTimerAnnotation:          "1.5s",
+		consts.RequireClientClassesAnnotation: "bios clients",
+	} {
+		annotations := map[string]string{consts.ValidLifetimeAnnotation: "10m", consts.RenewTimerAnnotation: "150", consts.RebindTimerAnnotation: "5m"}
+		annotations[key] = bad
+		nn.Annotations = annotations
+		if _, err := subnetParams(nn); err == nil {
+			t.Errorf("%s=%q: expected an error", key, bad)
+		}
+	}
+}
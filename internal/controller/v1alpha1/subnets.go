@@ -0,0 +1,245 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	subnetutil "github.com/vitistack/kea-operator/internal/util/subnet"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const eventReasonSharedNetworkUpdated = "SharedNetworkUpdated"
+
+// ipv4Subnet is one of the IPv4 subnets of a NetworkNamespace.
+type ipv4Subnet struct {
+	Prefix string
+	Pool   *subnetutil.PoolConfig
+	// Relay holds the relay agent addresses Kea selects the subnet by.
+	Relay []string
+	// ID and Info are filled once the subnet exists in Kea; Info is nil when
+	// its details could not be read.
+	ID      int
+	Info    *keaservice.SubnetInfo
+	Created bool
+}
+
+// ipv4Subnets lists the IPv4 subnets of a NetworkNamespace, the one for
+// status.ipv4Prefix first.
+type ipv4Subnets []ipv4Subnet
+
+// byID returns the subnet with the Kea id, or the primary subnet when none
+// matches, e.g. for a lease in a subnet the operator does not manage.
+func (s ipv4Subnets) byID(id int) ipv4Subnet {
+	for _, sn := range s {
+		if sn.ID == id {
+			return sn
+		}
+	}
+	return s[0]
+}
+
+// prefixOf returns the prefix of the subnet with the Kea id, or "" when it is
+// not one of s.
+func (s ipv4Subnets) prefixOf(id int) string {
+	for _, sn := range s {
+		if sn.ID == id {
+			return sn.Prefix
+		}
+	}
+	return ""
+}
+
+// prefixes returns the prefix of every subnet.
+func (s ipv4Subnets) prefixes() []string {
+	out := make([]string, 0, len(s))
+	for _, sn := range s {
+		out = append(out, sn.Prefix)
+	}
+	return out
+}
+
+// ipv4Prefixes returns status.ipv4Prefix of nn followed by the prefixes of its
+// secondary-ipv4-prefixes annotation. The prefixes must be IPv4 network
+// addresses and must not overlap.
+func ipv4Prefixes(nn *vitistackcrdsv1alpha1.NetworkNamespace) ([]string, error) {
+	prefixes := []string{nn.Status.IPv4Prefix}
+	for p := range strings.SplitSeq(nn.GetAnnotations()[consts.SecondaryIPv4PrefixesAnnotation], ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			prefixes = append(prefixes, p)
+		}
+	}
+	nets := make([]*net.IPNet, 0, len(prefixes))
+	for i, p := range prefixes {
+		source := consts.SecondaryIPv4PrefixesAnnotation
+		if i == 0 {
+			source = "status.ipv4Prefix"
+		}
+		ip, ipnet, err := net.ParseCIDR(p)
+		if err != nil || ip.To4() == nil {
+			return nil, fmt.Errorf("%s: %q is not an IPv4 CIDR prefix", source, p)
+		}
+		if !ip.Equal(ipnet.IP) {
+			return nil, fmt.Errorf("%s: %q is not a network address, use %s", source, p, ipnet)
+		}
+		for j, other := range nets {
+			if other.Contains(ipnet.IP) || ipnet.Contains(other.IP) {
+				return nil, fmt.Errorf("%s: %s overlaps %s", source, p, prefixes[j])
+			}
+		}
+		nets = append(nets, ipnet)
+		prefixes[i] = ipnet.String()
+	}
+	return prefixes, nil
+}
+
+// prefixPolicy adapts the pool policy of a NetworkNamespace to one of its
+// secondary prefixes: explicit pools, exclusions, the reservation range and
+// an explicit gateway address only apply to the prefix they lie in.
+func prefixPolicy(policy subnetutil.PoolPolicy, prefix string) subnetutil.PoolPolicy {
+	_, ipnet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return policy
+	}
+	inPrefix := func(r subnetutil.IPRange) bool { return ipnet.Contains(r.Start) && ipnet.Contains(r.End) }
+	filter := func(ranges []subnetutil.IPRange) []subnetutil.IPRange {
+		var out []subnetutil.IPRange
+		for _, r := range ranges {
+			if inPrefix(r) {
+				out = append(out, r)
+			}
+		}
+		return out
+	}
+	out := policy
+	out.Pools, out.Exclude = filter(policy.Pools), filter(policy.Exclude)
+	if policy.ReservationRange != nil && !inPrefix(*policy.ReservationRange) {
+		out.ReservationRange = nil
+	}
+	if gw := net.ParseIP(policy.Gateway); gw != nil && !ipnet.Contains(gw) {
+		out.Gateway = subnetutil.GatewayFirst
+	}
+	return out
+}
+
+// sharedNetworkName returns the Kea shared network the subnets of nn are
+// placed in, or "" when they are not placed in one. The shared-network
+// annotation names it explicitly; otherwise a NetworkNamespace with secondary
+// prefixes gets "vlan-<id>" when its VLAN is known and "<namespace>-<name>"
+// when not.
+func sharedNetworkName(nn *vitistackcrdsv1alpha1.NetworkNamespace, prefixes []string) (string, error) {
+	if name, ok := nn.GetAnnotations()[consts.SharedNetworkAnnotation]; ok {
+		name = strings.TrimSpace(name)
+		if name == "" || len(name) > 128 {
+			return "", fmt.Errorf("%s: %q is not a valid shared network name", consts.SharedNetworkAnnotation, name)
+		}
+		return name, nil
+	}
+	if len(prefixes) < 2 {
+		return "", nil
+	}
+	if nn.Status.VlanID > 0 {
+		return "vlan-" + strconv.Itoa(nn.Status.VlanID), nil
+	}
+	return nn.GetNamespace() + "-" + nn.GetName(), nil
+}
+
+// ensureIPv4Subnets gets or creates the Kea subnet of each entry of subnets
+// from base, with the gateway and pools of its pool layout and its relay
+// addresses, keeps the options
+// and settings of subnets it did not create in line, and places them in the
+// shared network when network is set. It returns subnets with ID, Info and
+// Created filled.
+func (r *NetworkConfigurationReconciler) ensureIPv4Subnets(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, subnets ipv4Subnets, base keamodels.SubnetConfig, options []keamodels.OptionData, network string, log logr.Logger) (ipv4Subnets, error) {
+	out := make(ipv4Subnets, len(subnets))
+	ids := make([]int, 0, len(subnets))
+	for i, sn := range subnets {
+		cfg := base
+		cfg.Subnet, cfg.Gateway, cfg.Pools = sn.Prefix, sn.Pool.Gateway, keaPools(sn.Pool.Pools)
+		cfg.RelayAddresses = sn.Relay
+		id, created, err := r.Kea.GetOrCreateSubnet(ctx, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("subnet %s: %w", sn.Prefix, err)
+		}
+		if created {
+			log.Info("created new Kea subnet", "subnet", sn.Prefix, "subnetID", id)
+			r.event(nc, corev1.EventTypeNormal, eventReasonSubnetCreated, eventActionCreateSubnet,
+				fmt.Sprintf("created Kea subnet %d for %s", id, sn.Prefix))
+		}
+		// Subnet info lookup is non-fatal: reservations still proceed without
+		// gateway and DNS, just with less status detail.
+		id, info := r.resolveSubnetInfo(ctx, id, sn.Prefix, log)
+		if !created {
+			info = r.syncSubnetOptions(ctx, nc, info, options, log)
+			info = r.syncSubnetParams(ctx, nc, info, cfg, log)
+		}
+		sn.ID, sn.Info, sn.Created = id, info, created
+		out[i] = sn
+		ids = append(ids, id)
+	}
+
+	if network == "" {
+		return out, nil
+	}
+	created, added, err := r.Kea.EnsureSharedNetwork(ctx, network, ids)
+	if err != nil {
+		return nil, fmt.Errorf("shared network %s: %w", network, err)
+	}
+	if created {
+		log.Info("created Kea shared network", "sharedNetwork", network)
+	}
+	if len(added) > 0 {
+		names := make([]string, 0, len(added))
+		for _, id := range added {
+			names = append(names, strconv.Itoa(id))
+		}
+		log.Info("placed subnets in Kea shared network", "sharedNetwork", network, "subnetIDs", added)
+		r.event(nc, corev1.EventTypeNormal, eventReasonSharedNetworkUpdated, eventActionCreateSubnet,
+			fmt.Sprintf("placed Kea subnet(s) %s in shared network %s", strings.Join(names, ", "), network))
+	}
+	return out, nil
+}
+
+// subnetStage describes subnets for the SubnetReady condition and reports
+// whether any of them was created.
+func subnetStage(subnets ipv4Subnets, network string) (string, bool) {
+	parts := make([]string, 0, len(subnets))
+	created := false
+	for _, sn := range subnets {
+		verb := "subnet"
+		if sn.Created {
+			verb, created = "created subnet", true
+		}
+		parts = append(parts, fmt.Sprintf("%s %d for %s", verb, sn.ID, sn.Prefix))
+	}
+	msg := strings.Join(parts, ", ")
+	if network != "" {
+		msg += " in shared network " + network
+	}
+	return msg, created
+}
+
+// reservationTargets returns one reservation target per subnet on the given
+// VLAN, with the allocation ranges of its pool layout in operator allocation
+// mode.
+func reservationTargets(subnets ipv4Subnets, allocMode string, vlan int) []reservationTarget {
+	targets := make([]reservationTarget, 0, len(subnets))
+	for _, sn := range subnets {
+		t := reservationTarget{SubnetID: sn.ID, Prefix: sn.Prefix, Gateway: sn.Pool.Gateway, VLAN: vlan}
+		if sn.Info != nil && sn.Info.Gateway != "" {
+			t.Gateway = sn.Info.Gateway
+		}
+		if allocMode == allocationModeOperator {
+			t.AllocRanges = allocationRanges(sn.Pool)
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
@@ -0,0 +1,87 @@
+package v1alpha1
+
+import (
+	"net"
+	"testing"
+
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	subnetutil "github.com/vitistack/kea-operator/internal/util/subnet"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testNetworkNamespace(prefix string, vlan int, annotations map[string]string) *vitistackcrdsv1alpha1.NetworkNamespace {
+	nn := &vitistackcrdsv1alpha1.NetworkNamespace{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "nn", Annotations: annotations}}
+	nn.Status.IPv4Prefix = prefix
+	nn.Status.VlanID = vlan
+	return nn
+}
+
+func TestIPv4Prefixes(t *testing.T) {
+	nn := testNetworkNamespace(testOldPrefix, 0, map[string]string{
+		consts.SecondaryIPv4PrefixesAnnotation: " 10.1.0.0/24, ,10.2.0.0/25",
+	})
+	got, err := ipv4Prefixes(nn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != testOldPrefix || got[1] != testNewPrefix || got[2] != "10.2.0.0/25" {
+		t.Fatalf("unexpected prefixes %v", got)
+	}
+
+	for _, bad := range []string{"10.1.0.1/24", "10.0.0.128/25", "2001:db8::/64", "nonsense"} {
+		nn.Annotations[consts.SecondaryIPv4PrefixesAnnotation] = bad
+		if _, err := ipv4Prefixes(nn); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestSharedNetworkName(t *testing.T) {
+	two := []string{testOldPrefix, testNewPrefix}
+	for _, tc := range []struct {
+		name        string
+		vlan        int
+		annotations map[string]string
+		prefixes    []string
+		want        string
+	}{
+		{name: "single prefix", prefixes: []string{testOldPrefix}},
+		{name: "vlan", vlan: 120, prefixes: two, want: "vlan-120"},
+		{name: "no vlan", prefixes: two, want: testNamespace + "-nn"},
+		{name: "annotation", vlan: 120, annotations: map[string]string{consts.SharedNetworkAnnotation: " lab "}, prefixes: []string{testOldPrefix}, want: "lab"},
+	} {
+		got, err := sharedNetworkName(testNetworkNamespace(testOldPrefix, tc.vlan, tc.annotations), tc.prefixes)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: expected %q, got %q, %v", tc.name, tc.want, got, err)
+		}
+	}
+	if _, err := sharedNetworkName(testNetworkNamespace(testOldPrefix, 0, map[string]string{consts.SharedNetworkAnnotation: ""}), two); err == nil {
+		t.Fatal("expected an empty shared network name to be rejected")
+	}
+}
+
+func TestPrefixPolicy(t *testing.T) {
+	r := func(start, end string) subnetutil.IPRange {
+		return subnetutil.IPRange{Start: net.ParseIP(start), End: net.ParseIP(end)}
+	}
+	reservations := r("10.0.0.200", "10.0.0.250")
+	policy := subnetutil.PoolPolicy{
+		Gateway:          "10.0.0.254",
+		ReserveHead:      3,
+		Pools:            []subnetutil.IPRange{r("10.0.0.10", "10.0.0.100"), r("10.1.0.10", "10.1.0.100")},
+		Exclude:          []subnetutil.IPRange{r("10.0.0.50", "10.0.0.60")},
+		ReservationRange: &reservations,
+	}
+
+	got := prefixPolicy(policy, testNewPrefix)
+	if got.Gateway != subnetutil.GatewayFirst || got.ReserveHead != 3 {
+		t.Fatalf("expected the default gateway and the same head reserve, got %+v", got)
+	}
+	if len(got.Pools) != 1 || got.Pools[0].Start.String() != "10.1.0.10" || len(got.Exclude) != 0 || got.ReservationRange != nil {
+		t.Fatalf("expected only the ranges inside %s, got %+v", testNewPrefix, got)
+	}
+	if got := prefixPolicy(policy, testOldPrefix); got.Gateway != policy.Gateway || len(got.Pools) != 1 || got.ReservationRange == nil {
+		t.Fatalf("expected the ranges inside %s to be kept, got %+v", testOldPrefix, got)
+	}
+}
@@ -42,6 +42,11 @@ func InitialChecks() {
 		crdcheck.Ref{Group: "vitistack.io", Version: "v1alpha1", Resource: "networknamespaces"},     // your CRD plural
 		crdcheck.Ref{Group: "vitistack.io", Version: "v1alpha1", Resource: "networkconfigurations"}, // your CRD plural
 	)
+	if viper.GetBool(consts.ENABLE_CLIENT_CLASSES) {
+		crdcheck.MustEnsureInstalled(context.TODO(),
+			crdcheck.Ref{Group: "kea.vitistack.io", Version: "v1alpha1", Resource: "dhcpclientclasses"},
+		)
+	}
 }
 
 func checkKea() bool {
@@ -0,0 +1,139 @@
+package kea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	subnetutil "github.com/vitistack/kea-operator/internal/util/subnet"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// maxAllocationAttempts bounds how many candidates AllocateReservation tries
+// after Kea rejects a pick because someone else reserved it in the meantime.
+const maxAllocationAttempts = 16
+
+// ErrNoFreeAddress is returned by AllocateReservation when every address in
+// the ranges is reserved or leased.
+var ErrNoFreeAddress = errors.New("no free address")
+
+// allocLock returns the per-subnet mutex used to serialize address allocation.
+func (s *Service) allocLock(subnetID int) *sync.Mutex {
+	m, _ := s.allocLocks.LoadOrStore(subnetID, &sync.Mutex{})
+	return m.(*sync.Mutex)
+}
+
+// AllocateReservation picks a free IPv4 address for cfg.MAC in cfg.SubnetID
+// from ranges, in order, and reserves it, without waiting for the host to
+// obtain a lease. If the identifier cfg is keyed on (the MAC by default)
+// already holds an IP-pinned reservation in the subnet, that address is kept,
+// with only cfg.Host synced, so repeated reconciles are stable.
+//
+// Allocation is serialized per subnet within this process, so concurrent
+// reconciles of different NetworkConfigurations never pick the same address.
+// Across processes (another replica, or a hand-made reservation) an address
+// reserved since the listing is caught by EnsureReservation's conflict check
+// and skipped; in the remaining window Kea's own uniqueness check rejects the
+// duplicate reservation-add and the error is returned so the caller retries
+// with a fresh view.
+func (s *Service) AllocateReservation(ctx context.Context, cfg keamodels.ReservationConfig, ranges []subnetutil.IPRange) (string, ReservationAction, error) {
+	idType, id, err := host4Identifier(cfg)
+	if err != nil {
+		return "", ReservationUnchanged, err
+	}
+	cfg.MAC = strings.ToLower(strings.TrimSpace(cfg.MAC))
+
+	lock := s.allocLock(cfg.SubnetID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if existing := hostInSubnet(s.findReservations(ctx, idType, id, cfg.SubnetID), cfg.SubnetID); existing != nil && existing.IPAddress != "" {
+		// Keep the address; only the host settings may need syncing.
+		cfg.IPAddress = existing.IPAddress
+		action, err := s.EnsureReservation(ctx, cfg)
+		if err != nil {
+			return "", ReservationUnchanged, err
+		}
+		return existing.IPAddress, action, nil
+	}
+
+	used, err := s.usedAddresses(ctx, cfg.SubnetID)
+	if err != nil {
+		return "", ReservationUnchanged, fmt.Errorf("cannot determine free addresses in subnet %d: %w", cfg.SubnetID, err)
+	}
+
+	attempts := 0
+	for _, r := range ranges {
+		// uint64 so the loop terminates when End is 255.255.255.255.
+		for n, last := uint64(subnetutil.IPv4ToUint32(r.Start)), uint64(subnetutil.IPv4ToUint32(r.End)); n <= last; n++ {
+			candidate := subnetutil.Uint32ToIPv4(uint32(n)).String()
+			if _, taken := used[candidate]; taken {
+				continue
+			}
+			cfg.IPAddress = candidate
+			action, err := s.EnsureReservation(ctx, cfg)
+			if err == nil {
+				return candidate, action, nil
+			}
+			conflict, isConflict := AsReservationConflict(err)
+			if !isConflict || conflict.Kind != ConflictIPAddressReserved || attempts >= maxAllocationAttempts {
+				return "", ReservationUnchanged, err
+			}
+			attempts++
+			used[candidate] = struct{}{}
+		}
+	}
+	return "", ReservationUnchanged, fmt.Errorf("%w in %v for subnet %d", ErrNoFreeAddress, ranges, cfg.SubnetID)
+}
+
+// usedAddresses returns the IPv4 addresses in subnetID that are reserved or
+// currently leased. Listing failures are returned rather than treated as an
+// empty subnet so the allocator never hands out addresses blindly.
+func (s *Service) usedAddresses(ctx context.Context, subnetID int) (map[string]struct{}, error) {
+	used := make(map[string]struct{})
+
+	resResp, err := s.send(ctx, keamodels.Request{
+		Command: "reservation-get-all",
+		Args:    map[string]any{keaFieldSubnetID: subnetID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resResp.Result != 0 && resResp.Result != 3 {
+		return nil, fmt.Errorf("kea reservation-get-all failed: %s", resResp.Text)
+	}
+	collectIPs(resResp.Arguments["hosts"], used)
+
+	leaseResp, err := s.send(ctx, keamodels.Request{
+		Command: "lease4-get-all",
+		Args:    map[string]any{"subnets": []int{subnetID}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if leaseResp.Result != 0 && leaseResp.Result != 3 {
+		return nil, fmt.Errorf("kea lease4-get-all failed: %s", leaseResp.Text)
+	}
+	collectIPs(leaseResp.Arguments["leases"], used)
+
+	return used, nil
+}
+
+// collectIPs adds the ip-address of every record in a Kea hosts/leases list to used.
+func collectIPs(records any, used map[string]struct{}) {
+	list, ok := records.([]any)
+	if !ok {
+		return
+	}
+	for _, rec := range list {
+		m, ok := rec.(map[string]any)
+		if !ok {
+			continue
+		}
+		if ip, ok := m[keaFieldIPAddress].(string); ok && ip != "" {
+			used[ip] = struct{}{}
+		}
+	}
+}
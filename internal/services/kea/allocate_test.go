@@ -0,0 +1,100 @@
+package kea
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	subnetutil "github.com/vitistack/kea-operator/internal/util/subnet"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+var testAllocRanges = []subnetutil.IPRange{
+	{Start: net.ParseIP("10.0.0.10").To4(), End: net.ParseIP("10.0.0.11").To4()},
+	{Start: net.ParseIP("10.0.0.20").To4(), End: net.ParseIP("10.0.0.21").To4()},
+}
+
+// TestAllocateReservation_SkipsUsedAddresses verifies that reserved and leased
+// addresses are never handed out and that later ranges are used once earlier
+// ones are full.
+func TestAllocateReservation_SkipsUsedAddresses(t *testing.T) {
+	client := newHostsKea(map[string]any{
+		keaFieldSubnetID:  1,
+		keaFieldHWAddress: "aa:bb:cc:dd:ee:99",
+		keaFieldIPAddress: "10.0.0.10",
+	})
+	client.leases = []any{map[string]any{keaFieldIPAddress: "10.0.0.11", keaFieldHWAddress: "aa:bb:cc:dd:ee:98"}}
+	svc := New(client)
+
+	ip, action, err := svc.AllocateReservation(context.Background(), keamodels.ReservationConfig{MAC: testMAC, SubnetID: 1}, testAllocRanges)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ip != "10.0.0.20" || action != ReservationCreated {
+		t.Fatalf("expected 10.0.0.20 created, got %s (%v)", ip, action)
+	}
+	if got := client.host(testMAC)[keaFieldIPAddress]; got != ip {
+		t.Fatalf("expected reservation for %s, got %v", ip, got)
+	}
+}
+
+// TestAllocateReservation_KeepsExistingReservation verifies that a MAC already
+// pinned in the subnet keeps its address.
+func TestAllocateReservation_KeepsExistingReservation(t *testing.T) {
+	client := newHostsKea(map[string]any{
+		keaFieldSubnetID:  1,
+		keaFieldHWAddress: testMAC,
+		keaFieldIPAddress: testLeaseIP,
+	})
+	svc := New(client)
+
+	ip, action, err := svc.AllocateReservation(context.Background(), keamodels.ReservationConfig{MAC: testMAC, SubnetID: 1}, testAllocRanges)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ip != testLeaseIP || action != ReservationUnchanged {
+		t.Fatalf("expected existing %s unchanged, got %s (%v)", testLeaseIP, ip, action)
+	}
+	if client.sent(cmdReservationAdd) {
+		t.Fatalf("expected no reservation-add, got %v", client.commands)
+	}
+}
+
+// TestAllocateReservation_Concurrent verifies that concurrent allocations in
+// the same subnet get distinct addresses and that an exhausted range errors.
+func TestAllocateReservation_Concurrent(t *testing.T) {
+	client := newHostsKea()
+	svc := New(client)
+
+	const n = 5 // one more than the ranges hold
+	ips := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg := keamodels.ReservationConfig{MAC: fmt.Sprintf("aa:bb:cc:dd:ee:%02x", 0x10+i), SubnetID: 1}
+			ips[i], _, errs[i] = svc.AllocateReservation(context.Background(), cfg, testAllocRanges)
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	failed := 0
+	for i := range n {
+		if errs[i] != nil {
+			failed++
+			continue
+		}
+		if seen[ips[i]] {
+			t.Fatalf("address %s allocated twice", ips[i])
+		}
+		seen[ips[i]] = true
+	}
+	if failed != 1 {
+		t.Fatalf("expected exactly one allocation to fail on an exhausted range, got %d", failed)
+	}
+}
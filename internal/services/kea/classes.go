@@ -0,0 +1,189 @@
+package kea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// Kea client class fields. Kea 3.0 renamed only-if-required to
+// only-in-additional-list; the old name is still accepted and both are read.
+const (
+	classFieldName                 = "name"
+	classFieldTest                 = "test"
+	classFieldOnlyIfRequired       = "only-if-required"
+	classFieldOnlyInAdditionalList = "only-in-additional-list"
+)
+
+// ErrClientClassNotManaged is returned by EnsureClientClass when a class of
+// that name exists in Kea but was configured by hand or is owned by another
+// resource.
+var ErrClientClassNotManaged = errors.New("client class is not managed by this resource")
+
+// ClientClassAction reports what EnsureClientClass changed in Kea.
+type ClientClassAction int
+
+const (
+	// ClientClassUnchanged means the class already matched.
+	ClientClassUnchanged ClientClassAction = iota
+	// ClientClassCreated means the class was added with class-add.
+	ClientClassCreated
+	// ClientClassUpdated means the class was rewritten with class-update.
+	ClientClassUpdated
+)
+
+// clientClass is the subset of a Kea class definition the service acts on.
+type clientClass struct {
+	Managed bool
+	Owner   string
+	raw     map[string]any
+}
+
+// getClientClass returns the class named name via class-get, or nil when Kea
+// has no such class.
+func (s *Service) getClientClass(ctx context.Context, name string) (*clientClass, error) {
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: "class-get",
+		Args:    map[string]any{classFieldName: name},
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.Result {
+	case 0:
+	case 3: // not found
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("kea class-get failed: %s", resp.Text)
+	}
+	list, _ := resp.Arguments["client-classes"].([]any)
+	if len(list) == 0 {
+		return nil, nil
+	}
+	m, ok := list[0].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected class-get response shape")
+	}
+	c := &clientClass{raw: m}
+	if uc, ok := m[keaFieldUserContext].(map[string]any); ok {
+		c.Managed = uc[userContextManagedBy] == managedByValue
+		c.Owner, _ = uc[userContextOwner].(string)
+	}
+	return c, nil
+}
+
+// clientClassArgs converts cfg into a Kea class definition.
+func clientClassArgs(cfg keamodels.ClientClassConfig) map[string]any {
+	class := map[string]any{classFieldName: cfg.Name}
+	if cfg.Test != "" {
+		class[classFieldTest] = cfg.Test
+	}
+	if cfg.OnlyIfRequired {
+		class[classFieldOnlyIfRequired] = true
+	}
+	maps.Copy(class, hostFieldArgs(keamodels.HostParams{
+		NextServer:     cfg.NextServer,
+		ServerHostname: cfg.ServerHostname,
+		BootFileName:   cfg.BootFileName,
+		Options:        cfg.Options,
+	}))
+	uc := map[string]any{userContextManagedBy: managedByValue}
+	if cfg.Owner != "" {
+		uc[userContextOwner] = cfg.Owner
+	}
+	class[keaFieldUserContext] = uc
+	return class
+}
+
+// clientClassInSync reports whether the class in Kea matches cfg.
+func clientClassInSync(existing *clientClass, cfg keamodels.ClientClassConfig) bool {
+	str := func(field string) string {
+		v, _ := existing.raw[field].(string)
+		if emptyHostField(v) {
+			return ""
+		}
+		return v
+	}
+	onlyIfRequired, _ := existing.raw[classFieldOnlyIfRequired].(bool)
+	if v, ok := existing.raw[classFieldOnlyInAdditionalList].(bool); ok {
+		onlyIfRequired = v
+	}
+	options := hostOptions(existing.raw[hostFieldOptionData])
+	return existing.Managed && existing.Owner == cfg.Owner &&
+		strings.TrimSpace(str(classFieldTest)) == strings.TrimSpace(cfg.Test) &&
+		onlyIfRequired == cfg.OnlyIfRequired &&
+		str(hostFieldNextServer) == cfg.NextServer &&
+		str(hostFieldServerHostname) == cfg.ServerHostname &&
+		str(hostFieldBootFileName) == cfg.BootFileName &&
+		len(options) == len(cfg.Options) && len(MissingOptions(cfg.Options, options)) == 0
+}
+
+// EnsureClientClass creates or updates the kea-dhcp4 client class cfg.Name
+// through the class_cmds hook. A class that exists but is not managed by the
+// operator, or is owned by someone other than cfg.Owner, is left alone and
+// ErrClientClassNotManaged returned, unless adopt is set.
+func (s *Service) EnsureClientClass(ctx context.Context, cfg keamodels.ClientClassConfig, adopt bool) (ClientClassAction, error) {
+	if cfg.Name == "" {
+		return ClientClassUnchanged, fmt.Errorf("missing class name")
+	}
+	existing, err := s.getClientClass(ctx, cfg.Name)
+	if err != nil {
+		return ClientClassUnchanged, err
+	}
+
+	command, action := "class-add", ClientClassCreated
+	if existing != nil {
+		if !adopt && (!existing.Managed || existing.Owner != cfg.Owner) {
+			owner := existing.Owner
+			if !existing.Managed || owner == "" {
+				owner = "the Kea configuration"
+			}
+			return ClientClassUnchanged, fmt.Errorf("%w: class %q is defined by %s", ErrClientClassNotManaged, cfg.Name, owner)
+		}
+		if clientClassInSync(existing, cfg) {
+			return ClientClassUnchanged, nil
+		}
+		command, action = "class-update", ClientClassUpdated
+	}
+
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: command,
+		Args:    map[string]any{"client-classes": []map[string]any{clientClassArgs(cfg)}},
+	})
+	if err != nil {
+		return ClientClassUnchanged, err
+	}
+	if resp.Result != 0 {
+		return ClientClassUnchanged, fmt.Errorf("kea %s failed: %s", command, resp.Text)
+	}
+	return action, nil
+}
+
+// DeleteClientClass removes the client class name if the operator manages it
+// for owner. Classes configured by hand or owned by someone else are left in
+// Kea. It reports whether a class was removed.
+func (s *Service) DeleteClientClass(ctx context.Context, name, owner string) (bool, error) {
+	existing, err := s.getClientClass(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	if existing == nil || !existing.Managed || existing.Owner != owner {
+		return false, nil
+	}
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: "class-del",
+		Args:    map[string]any{classFieldName: name},
+	})
+	if err != nil {
+		return false, err
+	}
+	// Result 3 means the class is already gone.
+	if resp.Result != 0 && resp.Result != 3 {
+		return false, fmt.Errorf("kea class-del failed: %s", resp.Text)
+	}
+	return resp.Result == 0, nil
+}
@@ -0,0 +1,116 @@
+package kea
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// classesKea is a minimal stateful class_cmds fake keyed by class name.
+type classesKea struct {
+	mu       sync.Mutex
+	classes  map[string]map[string]any
+	commands []string
+}
+
+func newClassesKea(classes ...map[string]any) *classesKea {
+	f := &classesKea{classes: map[string]map[string]any{}}
+	for _, c := range classes {
+		f.classes[c[classFieldName].(string)] = c
+	}
+	return f
+}
+
+func (f *classesKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, cmd.Command)
+	switch cmd.Command {
+	case "class-get":
+		name, _ := cmd.Args[classFieldName].(string)
+		c, ok := f.classes[name]
+		if !ok {
+			return keamodels.Response{Result: 3, Text: "Class '" + name + "' not found."}, nil
+		}
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"client-classes": []any{c}}}, nil
+	case "class-add", "class-update":
+		c := cmd.Args["client-classes"].([]map[string]any)[0]
+		name := c[classFieldName].(string)
+		if _, exists := f.classes[name]; exists == (cmd.Command == "class-add") {
+			return keamodels.Response{Result: 1, Text: "unexpected " + cmd.Command}, nil
+		}
+		f.classes[name] = c
+		return keamodels.Response{Result: 0}, nil
+	case "class-del":
+		name, _ := cmd.Args[classFieldName].(string)
+		if _, ok := f.classes[name]; !ok {
+			return keamodels.Response{Result: 3}, nil
+		}
+		delete(f.classes, name)
+		return keamodels.Response{Result: 0}, nil
+	}
+	return keamodels.Response{Result: 2, Text: "unsupported command " + cmd.Command}, nil
+}
+
+// TestEnsureClientClass_Lifecycle verifies that a class is added, left alone
+// while in sync, updated on change and deleted for its owner only.
+func TestEnsureClientClass_Lifecycle(t *testing.T) {
+	client := newClassesKea()
+	svc := New(client)
+	ctx := context.Background()
+	cfg := keamodels.ClientClassConfig{
+		Name:         "ueficlients",
+		Test:         "option[93].hex == 0x0007",
+		NextServer:   "10.0.0.9",
+		BootFileName: "ipxe.efi",
+		Options:      []keamodels.OptionData{{Name: "tftp-server-name", Code: 66, Data: "10.0.0.9"}},
+		Owner:        "DHCPClientClass/uefi",
+	}
+
+	if action, err := svc.EnsureClientClass(ctx, cfg, false); err != nil || action != ClientClassCreated {
+		t.Fatalf("expected ClientClassCreated, got %v, %v", action, err)
+	}
+	if action, err := svc.EnsureClientClass(ctx, cfg, false); err != nil || action != ClientClassUnchanged {
+		t.Fatalf("expected ClientClassUnchanged, got %v, %v", action, err)
+	}
+	cfg.BootFileName = "snp.efi"
+	if action, err := svc.EnsureClientClass(ctx, cfg, false); err != nil || action != ClientClassUpdated {
+		t.Fatalf("expected ClientClassUpdated, got %v, %v", action, err)
+	}
+	if got := client.classes["ueficlients"][hostFieldBootFileName]; got != "snp.efi" {
+		t.Fatalf("expected boot-file-name snp.efi, got %v", got)
+	}
+
+	if removed, err := svc.DeleteClientClass(ctx, "ueficlients", "DHCPClientClass/other"); err != nil || removed {
+		t.Fatalf("expected a foreign owner not to remove the class, got %v, %v", removed, err)
+	}
+	if removed, err := svc.DeleteClientClass(ctx, "ueficlients", cfg.Owner); err != nil || !removed {
+		t.Fatalf("expected the class to be removed, got %v, %v", removed, err)
+	}
+}
+
+// TestEnsureClientClass_Unmanaged verifies that a class from the static Kea
+// configuration is only taken over with adopt.
+func TestEnsureClientClass_Unmanaged(t *testing.T) {
+	client := newClassesKea(map[string]any{classFieldName: "biosclients", hostFieldNextServer: "10.0.0.1"})
+	svc := New(client)
+	ctx := context.Background()
+	cfg := keamodels.ClientClassConfig{Name: "biosclients", NextServer: "10.0.0.9", Owner: "DHCPClientClass/bios"}
+
+	if _, err := svc.EnsureClientClass(ctx, cfg, false); !errors.Is(err, ErrClientClassNotManaged) {
+		t.Fatalf("expected ErrClientClassNotManaged, got %v", err)
+	}
+	if removed, err := svc.DeleteClientClass(ctx, "biosclients", cfg.Owner); err != nil || removed {
+		t.Fatalf("expected an unmanaged class to be kept, got %v, %v", removed, err)
+	}
+	if action, err := svc.EnsureClientClass(ctx, cfg, true); err != nil || action != ClientClassUpdated {
+		t.Fatalf("expected ClientClassUpdated on adopt, got %v, %v", action, err)
+	}
+	uc, _ := client.classes["biosclients"][keaFieldUserContext].(map[string]any)
+	if uc[userContextManagedBy] != managedByValue || uc[userContextOwner] != cfg.Owner {
+		t.Fatalf("unexpected user-context %v", uc)
+	}
+}
@@ -0,0 +1,155 @@
+package kea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// ConflictKind identifies why a reservation clashes with one held by someone else.
+// The values double as machine-readable condition and Event reasons.
+type ConflictKind string
+
+const (
+	// ConflictIPAddressReserved means the IP is already reserved for another host in the subnet.
+	ConflictIPAddressReserved ConflictKind = "IPAddressReserved"
+	// ConflictMACReservedInOtherSubnet means the MAC, or the identifier the reservation is
+	// keyed on, already holds a reservation in a different subnet.
+	ConflictMACReservedInOtherSubnet ConflictKind = "MACReservedInOtherSubnet"
+	// ConflictIPAddressLeased means the IP is currently leased to another MAC.
+	ConflictIPAddressLeased ConflictKind = "IPAddressLeased"
+)
+
+// ReservationConflictError is returned by EnsureReservation and
+// EnsureIPNotLeasedToOther when the requested reservation clashes with an
+// existing reservation or lease. It names the other reservation and,
+// when its user-context records one, the resource that owns it.
+type ReservationConflictError struct {
+	Kind      ConflictKind
+	MAC       string
+	IPAddress string
+	SubnetID  int
+	// IdentifierType and Identifier are what the reservation is keyed on,
+	// when not the MAC.
+	IdentifierType string
+	Identifier     string
+
+	// OtherHWAddress and OtherSubnetID identify the conflicting reservation or
+	// lease; OtherIdentifierType and OtherIdentifier are set for a reservation
+	// keyed on something other than a hw-address.
+	OtherHWAddress      string
+	OtherSubnetID       int
+	OtherIdentifierType string
+	OtherIdentifier     string
+	// Owner is the owner recorded in the conflicting reservation's user-context, if any.
+	Owner string
+}
+
+func (e *ReservationConflictError) Error() string {
+	owner := ""
+	if e.Owner != "" {
+		owner = fmt.Sprintf(" (owner %s)", e.Owner)
+	}
+	self := describeIdentifier(IdentifierHWAddress, e.MAC)
+	if e.Identifier != "" {
+		self = describeIdentifier(e.IdentifierType, e.Identifier)
+	}
+	other := describeIdentifier(IdentifierHWAddress, e.OtherHWAddress)
+	if e.OtherIdentifier != "" {
+		other = describeIdentifier(e.OtherIdentifierType, e.OtherIdentifier)
+	}
+	switch e.Kind {
+	case ConflictIPAddressReserved:
+		return fmt.Sprintf("ip-address %s in subnet %d is already reserved for %s%s",
+			e.IPAddress, e.SubnetID, other, owner)
+	case ConflictIPAddressLeased:
+		return fmt.Sprintf("ip-address %s is currently leased to %s", e.IPAddress, other)
+	case ConflictMACReservedInOtherSubnet:
+		return fmt.Sprintf("%s is already reserved in subnet %d%s",
+			self, e.OtherSubnetID, owner)
+	}
+	return fmt.Sprintf("reservation conflict for %s", self)
+}
+
+// newConflict returns a conflict of kind for the reservation key in subnetID
+// with ip, clashing with other.
+func newConflict(kind ConflictKind, key reservationKey, subnetID int, ip string, other *hostReservation) *ReservationConflictError {
+	e := &ReservationConflictError{
+		Kind:           kind,
+		MAC:            key.mac,
+		IPAddress:      ip,
+		SubnetID:       subnetID,
+		OtherHWAddress: other.HWAddress,
+		OtherSubnetID:  other.SubnetID,
+		Owner:          other.Owner,
+	}
+	if key.idType != IdentifierHWAddress {
+		e.IdentifierType, e.Identifier = key.idType, key.id
+	}
+	if other.IdentifierType != IdentifierHWAddress {
+		e.OtherIdentifierType, e.OtherIdentifier = other.IdentifierType, other.Identifier
+	}
+	return e
+}
+
+// AsReservationConflict reports whether err is (or wraps) a ReservationConflictError.
+func AsReservationConflict(err error) (*ReservationConflictError, bool) {
+	var conflict *ReservationConflictError
+	if errors.As(err, &conflict) {
+		return conflict, true
+	}
+	return nil, false
+}
+
+// reservationKey is the MAC of an interface and the identifier its
+// reservation is keyed on.
+type reservationKey struct {
+	mac, idType, id string
+}
+
+// checkReservationConflicts verifies that key may be reserved in subnetID with
+// the given ip. keyHosts are the reservations already keyed on its identifier.
+// A reservation in another subnet that records the same owner is not a
+// conflict: it is this owner's own stale record (e.g. from before a prefix
+// change). Neither is an owner-less one in a subnet listed in recorded, the
+// subnets the owner's reservation inventory has for it, which is how
+// reservations made before owners were recorded are recognised.
+func (s *Service) checkReservationConflicts(ctx context.Context, key reservationKey, subnetID int, ip, owner string, recorded []int, keyHosts []hostReservation) error {
+	for i := range keyHosts {
+		h := &keyHosts[i]
+		if h.SubnetID == subnetID || (owner != "" && h.Owner == owner) || (h.Owner == "" && slices.Contains(recorded, h.SubnetID)) {
+			continue
+		}
+		return newConflict(ConflictMACReservedInOtherSubnet, key, subnetID, ip, h)
+	}
+
+	if ip == "" {
+		return nil
+	}
+	other := s.getReservationByIP(ctx, subnetID, ip)
+	if other == nil || other.Identifier == "" || (other.IdentifierType == key.idType && other.Identifier == key.id) {
+		return nil
+	}
+	return newConflict(ConflictIPAddressReserved, key, subnetID, ip, other)
+}
+
+// getReservationByIP returns the reservation holding ip in subnetID via
+// reservation-get, or nil when there is none or Kea can't be queried.
+func (s *Service) getReservationByIP(ctx context.Context, subnetID int, ip string) *hostReservation {
+	req := keamodels.Request{
+		Command: "reservation-get",
+		Args: map[string]any{
+			keaFieldSubnetID:  subnetID,
+			keaFieldIPAddress: ip,
+		},
+	}
+	resp, err := s.send(ctx, req)
+	if err != nil || resp.Result != 0 || len(resp.Arguments) == 0 {
+		return nil
+	}
+	h := toHostReservation(resp.Arguments, subnetID)
+	return &h
+}
@@ -0,0 +1,89 @@
+package kea
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+const (
+	testOwner      = "default/nc-a"
+	testOtherOwner = "default/nc-b"
+	testOtherMAC   = "aa:bb:cc:dd:ee:02"
+)
+
+func ownedBy(owner string) map[string]any {
+	return map[string]any{userContextOwner: owner, userContextManagedBy: managedByValue}
+}
+
+// TestEnsureReservation_IPReservedForOtherMAC verifies that a lease IP already
+// reserved for another MAC yields a typed conflict naming the other owner, and
+// that no reservation-add is attempted.
+func TestEnsureReservation_IPReservedForOtherMAC(t *testing.T) {
+	client := newHostsKea(map[string]any{
+		keaFieldSubnetID:    1,
+		keaFieldHWAddress:   testOtherMAC,
+		keaFieldIPAddress:   testLeaseIP,
+		keaFieldUserContext: ownedBy(testOtherOwner),
+	})
+	svc := New(client)
+
+	_, err := svc.EnsureReservation(context.Background(), keamodels.ReservationConfig{
+		MAC: testMAC, SubnetID: 1, IPAddress: testLeaseIP, Owner: testOwner,
+	})
+	conflict, ok := AsReservationConflict(err)
+	if !ok {
+		t.Fatalf("expected ReservationConflictError, got %v", err)
+	}
+	if conflict.Kind != ConflictIPAddressReserved || conflict.OtherHWAddress != testOtherMAC || conflict.Owner != testOtherOwner {
+		t.Fatalf("unexpected conflict: %+v", conflict)
+	}
+	if client.sent(cmdReservationAdd) {
+		t.Fatalf("expected no reservation-add on conflict")
+	}
+}
+
+// TestEnsureReservation_MACReservedInOtherSubnet verifies the cross-subnet MAC
+// check, and that a stale reservation recording the same owner, or an
+// owner-less one the owner's inventory records, is not a conflict.
+func TestEnsureReservation_MACReservedInOtherSubnet(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		otherOwner   string
+		recorded     []int
+		wantConflict bool
+	}{
+		{name: "other owner", otherOwner: testOtherOwner, wantConflict: true},
+		{name: "same owner", otherOwner: testOwner, wantConflict: false},
+		{name: "no owner", wantConflict: true},
+		{name: "no owner, recorded", recorded: []int{2}, wantConflict: false},
+		{name: "other owner, recorded", otherOwner: testOtherOwner, recorded: []int{2}, wantConflict: true},
+	} {
+		t.Run(tc.name, func(tt *testing.T) {
+			host := map[string]any{keaFieldSubnetID: 2, keaFieldHWAddress: testMAC}
+			if tc.otherOwner != "" {
+				host[keaFieldUserContext] = ownedBy(tc.otherOwner)
+			}
+			client := newHostsKea(host)
+			svc := New(client)
+
+			action, err := svc.EnsureReservation(context.Background(), keamodels.ReservationConfig{
+				MAC: testMAC, SubnetID: 1, IPAddress: testLeaseIP, Owner: testOwner, RecordedSubnetIDs: tc.recorded,
+			})
+			conflict, isConflict := AsReservationConflict(err)
+			if isConflict != tc.wantConflict {
+				tt.Fatalf("conflict=%v, want %v (err=%v)", isConflict, tc.wantConflict, err)
+			}
+			if isConflict {
+				if conflict.Kind != ConflictMACReservedInOtherSubnet || conflict.OtherSubnetID != 2 {
+					tt.Fatalf("unexpected conflict: %+v", conflict)
+				}
+				return
+			}
+			if err != nil || action != ReservationCreated {
+				tt.Fatalf("expected ReservationCreated, got %v (err=%v)", action, err)
+			}
+		})
+	}
+}
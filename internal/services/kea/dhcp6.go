@@ -0,0 +1,387 @@
+package kea
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// dhcp6Service routes a command to kea-dhcp6 through the Control Agent.
+const dhcp6Service = "dhcp6"
+
+const (
+	keaFieldDUID        = "duid"
+	keaFieldIPAddresses = "ip-addresses"
+)
+
+// host6Identifier returns the identifier-type and identifier a DHCPv6
+// reservation is keyed on: the DUID when set, otherwise the MAC.
+func host6Identifier(mac, duid string) (string, string, error) {
+	if duid = strings.ToLower(strings.TrimSpace(duid)); duid != "" {
+		return keaFieldDUID, duid, nil
+	}
+	if mac = strings.ToLower(strings.TrimSpace(mac)); mac != "" {
+		return keaFieldHWAddress, mac, nil
+	}
+	return "", "", fmt.Errorf("missing mac or duid")
+}
+
+// ListSubnets6 returns the id and prefix of every IPv6 subnet via subnet6-list.
+func (s *Service) ListSubnets6(ctx context.Context) ([]SubnetInfo, error) {
+	resp, err := s.send(ctx, keamodels.Request{Command: "subnet6-list", Service: dhcp6Service, Args: map[string]any{}})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.Result {
+	case 0:
+	case 3: // empty: no subnets configured
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("kea subnet6-list failed: %s", resp.Text)
+	}
+	list, _ := resp.Arguments["subnets"].([]any)
+	out := make([]SubnetInfo, 0, len(list))
+	for _, snet := range list {
+		m, ok := snet.(map[string]any)
+		if !ok {
+			continue
+		}
+		info := SubnetInfo{}
+		info.ID, _ = asInt(m["id"])
+		info.Subnet, _ = m["subnet"].(string)
+		if info.Subnet != "" {
+			out = append(out, info)
+		}
+	}
+	return out, nil
+}
+
+// GetSubnet6ID returns the id of the IPv6 subnet matching prefix.
+func (s *Service) GetSubnet6ID(ctx context.Context, ipv6Prefix string) (int, error) {
+	subnets, err := s.ListSubnets6(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, sn := range subnets {
+		if sn.Subnet == ipv6Prefix {
+			return sn.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("no matching Kea subnet for prefix %s", ipv6Prefix)
+}
+
+// CreateSubnet6 creates a new IPv6 subnet in kea-dhcp6 via subnet6-add and
+// returns its subnet ID. DHCPv6 subnet IDs are numbered independently of
+// DHCPv4 ones.
+func (s *Service) CreateSubnet6(ctx context.Context, cfg keamodels.Subnet6Config) (int, error) {
+	if cfg.Subnet == "" {
+		return 0, fmt.Errorf("subnet CIDR is required")
+	}
+
+	subnetID := cfg.ID
+	if subnetID <= 0 {
+		subnetID = 1
+		if subnets, err := s.ListSubnets6(ctx); err == nil {
+			for _, sn := range subnets {
+				subnetID = max(subnetID, sn.ID+1)
+			}
+		}
+	}
+
+	subnet6 := map[string]any{
+		"subnet":         cfg.Subnet,
+		"id":             subnetID,
+		"valid-lifetime": 4000,
+	}
+	if cfg.ValidLife > 0 {
+		subnet6["valid-lifetime"] = cfg.ValidLife
+	}
+	if cfg.PreferredLife > 0 {
+		subnet6["preferred-lifetime"] = cfg.PreferredLife
+	}
+	if len(cfg.Pools) > 0 {
+		pools := make([]map[string]any, 0, len(cfg.Pools))
+		for _, r := range cfg.Pools {
+			pools = append(pools, map[string]any{"pool": r})
+		}
+		subnet6["pools"] = pools
+	}
+	if len(cfg.PDPools) > 0 {
+		pdPools := make([]map[string]any, 0, len(cfg.PDPools))
+		for _, p := range cfg.PDPools {
+			pdPools = append(pdPools, map[string]any{
+				"prefix":        p.Prefix,
+				"prefix-len":    p.PrefixLen,
+				"delegated-len": p.DelegatedLen,
+			})
+		}
+		subnet6["pd-pools"] = pdPools
+	}
+	if len(cfg.DNS) > 0 {
+		subnet6["option-data"] = []map[string]any{{
+			"name": "dns-servers",
+			"code": 23,
+			"data": strings.Join(cfg.DNS, ", "),
+		}}
+	}
+	subnet6[keaFieldUserContext] = map[string]any{userContextManagedBy: managedByValue}
+
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: "subnet6-add",
+		Service: dhcp6Service,
+		Args:    map[string]any{"subnet6": []map[string]any{subnet6}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to send subnet6-add request: %w", err)
+	}
+	if resp.Result != 0 {
+		return 0, fmt.Errorf("kea subnet6-add failed: %s", resp.Text)
+	}
+	return subnetID, nil
+}
+
+// GetOrCreateSubnet6 returns the subnet ID for an IPv6 prefix, creating the
+// subnet if it doesn't exist. It is the DHCPv6 counterpart of GetOrCreateSubnet
+// and shares its per-prefix serialization.
+func (s *Service) GetOrCreateSubnet6(ctx context.Context, cfg keamodels.Subnet6Config) (int, bool, error) {
+	lock := s.subnetLock("6/" + cfg.Subnet)
+	lock.Lock()
+	defer lock.Unlock()
+
+	subnetID, err := s.GetSubnet6ID(ctx, cfg.Subnet)
+	if err == nil {
+		return subnetID, false, nil
+	}
+	if !strings.Contains(err.Error(), "no matching Kea subnet") {
+		return 0, false, err
+	}
+
+	newID, createErr := s.CreateSubnet6(ctx, cfg)
+	if createErr == nil {
+		return newID, true, nil
+	}
+	// A concurrent writer may have created it between our list and create.
+	if existingID, getErr := s.GetSubnet6ID(ctx, cfg.Subnet); getErr == nil {
+		return existingID, false, nil
+	}
+	return 0, false, fmt.Errorf("failed to create subnet: %w", createErr)
+}
+
+// GetLease6 returns the most recent IA_NA lease in subnetID held by the client
+// with the given DUID, or by mac when duid is empty, or nil when it holds
+// none. A DUID is looked up with lease6-get-by-duid; a MAC requires scanning
+// the subnet with lease6-get-all, since Kea has no lookup by hardware address
+// for DHCPv6 and only knows it when the client or a relay reported it.
+func (s *Service) GetLease6(ctx context.Context, subnetID int, mac, duid string) (*LeaseInfo, error) {
+	idType, id, err := host6Identifier(mac, duid)
+	if err != nil {
+		return nil, err
+	}
+	req := keamodels.Request{Command: "lease6-get-all", Service: dhcp6Service, Args: map[string]any{"subnets": []int{subnetID}}}
+	if idType == keaFieldDUID {
+		req = keamodels.Request{Command: "lease6-get-by-duid", Service: dhcp6Service, Args: map[string]any{keaFieldDUID: id}}
+	}
+	resp, err := s.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.Result {
+	case 0:
+	case 3: // empty: no leases
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("kea %s failed: %s", req.Command, resp.Text)
+	}
+
+	var best map[string]any
+	var bestCLTT int
+	list, _ := resp.Arguments["leases"].([]any)
+	for _, elem := range list {
+		m, ok := elem.(map[string]any)
+		if !ok {
+			continue
+		}
+		if t, _ := m["type"].(string); t != "" && t != "IA_NA" {
+			continue
+		}
+		if sid, _ := asInt(m[keaFieldSubnetID]); sid != subnetID {
+			continue
+		}
+		got, _ := m[idType].(string)
+		if !strings.EqualFold(strings.TrimSpace(got), id) {
+			continue
+		}
+		if ip, _ := m[keaFieldIPAddress].(string); ip == "" {
+			continue
+		}
+		cltt, _ := asInt(m["cltt"])
+		if best == nil || cltt > bestCLTT {
+			best, bestCLTT = m, cltt
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	lease := &LeaseInfo{Peer: s.Peer()}
+	lease.IPAddress, _ = best[keaFieldIPAddress].(string)
+	if hw, ok := best[keaFieldHWAddress].(string); ok {
+		lease.HWAddress = strings.ToLower(hw)
+	}
+	lease.SubnetID, _ = asInt(best[keaFieldSubnetID])
+	lease.Hostname, _ = best["hostname"].(string)
+	lease.ClientID, _ = best[keaFieldDUID].(string)
+	lease.State, _ = asInt(best["state"])
+	cltt, okCLTT := asInt(best["cltt"])
+	lft, okLft := asInt(best["valid-lft"])
+	if okCLTT && okLft {
+		lease.Expires = time.Unix(int64(cltt)+int64(lft), 0).UTC()
+	}
+	return lease, nil
+}
+
+// EnsureReservation6 ensures a kea-dhcp6 reservation exists for cfg in
+// cfg.SubnetID, keyed on cfg.DUID or else cfg.MAC, with the optional IPv6
+// address cfg.IPAddress. As with EnsureReservation an empty address never
+// downgrades an existing address reservation, and a reservation with a
+// different address is rewritten. Conflicts with reservations held by others
+// are left for Kea to reject.
+func (s *Service) EnsureReservation6(ctx context.Context, cfg keamodels.ReservationConfig) (ReservationAction, error) {
+	idType, id, err := host6Identifier(cfg.MAC, cfg.DUID)
+	if err != nil {
+		return ReservationUnchanged, err
+	}
+	ip := strings.TrimSpace(cfg.IPAddress)
+	reservation := map[string]any{
+		keaFieldSubnetID: cfg.SubnetID,
+		idType:           id,
+	}
+	if ip != "" {
+		reservation[keaFieldIPAddresses] = []string{ip}
+	}
+	if cfg.Owner != "" {
+		reservation[keaFieldUserContext] = map[string]any{
+			userContextOwner:     cfg.Owner,
+			userContextManagedBy: managedByValue,
+		}
+	}
+
+	existing, err := s.getReservation6(ctx, cfg.SubnetID, idType, id)
+	if err != nil {
+		return ReservationUnchanged, err
+	}
+	if existing != nil {
+		addrs, _ := existing[keaFieldIPAddresses].([]any)
+		if ip == "" || slices.ContainsFunc(addrs, func(a any) bool { return a == ip }) {
+			return ReservationUnchanged, nil
+		}
+		for _, field := range preservedHostFields {
+			if v, ok := existing[field]; ok {
+				if _, set := reservation[field]; !set {
+					reservation[field] = v
+				}
+			}
+		}
+		resp, err := s.send(ctx, keamodels.Request{
+			Command: "reservation-update",
+			Service: dhcp6Service,
+			Args:    map[string]any{"reservation": reservation, "operation-target": "all"},
+		})
+		if err != nil {
+			return ReservationUnchanged, err
+		}
+		switch resp.Result {
+		case 0:
+			return ReservationUpdated, nil
+		case 2: // command not supported: fall back to del + add
+		default:
+			return ReservationUnchanged, fmt.Errorf("kea reservation-update failed: %s", resp.Text)
+		}
+		if err := s.deleteReservation6(ctx, cfg.SubnetID, idType, id); err != nil {
+			return ReservationUnchanged, fmt.Errorf("kea reservation update fallback: %w", err)
+		}
+		if err := s.addReservation6(ctx, reservation); err != nil {
+			return ReservationUnchanged, updateFallbackError(err, s.addReservation6(ctx, existing))
+		}
+		return ReservationUpdated, nil
+	}
+
+	if err := s.addReservation6(ctx, reservation); err != nil {
+		return ReservationUnchanged, err
+	}
+	return ReservationCreated, nil
+}
+
+// DeleteReservation6 removes the kea-dhcp6 reservation keyed on duid, or on
+// mac when duid is empty, in subnetID. A missing reservation is not an error.
+func (s *Service) DeleteReservation6(ctx context.Context, mac, duid string, subnetID int) error {
+	idType, id, err := host6Identifier(mac, duid)
+	if err != nil {
+		return err
+	}
+	return s.deleteReservation6(ctx, subnetID, idType, id)
+}
+
+// getReservation6 returns the raw kea-dhcp6 host record for the identifier in
+// subnetID via reservation-get, or nil when there is none.
+func (s *Service) getReservation6(ctx context.Context, subnetID int, idType, id string) (map[string]any, error) {
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: "reservation-get",
+		Service: dhcp6Service,
+		Args: map[string]any{
+			keaFieldSubnetID:       subnetID,
+			keaFieldIdentifierType: idType,
+			keaFieldIdentifier:     id,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.Result {
+	case 0:
+		return resp.Arguments, nil
+	case 3: // empty: no such host
+		return nil, nil
+	}
+	return nil, fmt.Errorf("kea reservation-get failed: %s", resp.Text)
+}
+
+func (s *Service) addReservation6(ctx context.Context, reservation map[string]any) error {
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: "reservation-add",
+		Service: dhcp6Service,
+		Args:    map[string]any{"reservation": reservation, "operation-target": "all"},
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Result != 0 {
+		return fmt.Errorf("kea reservation-add failed: %s", resp.Text)
+	}
+	return nil
+}
+
+func (s *Service) deleteReservation6(ctx context.Context, subnetID int, idType, id string) error {
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: "reservation-del",
+		Service: dhcp6Service,
+		Args: map[string]any{
+			keaFieldSubnetID:       subnetID,
+			keaFieldIdentifierType: idType,
+			keaFieldIdentifier:     id,
+			"operation-target":     "all",
+		},
+	})
+	if err != nil {
+		return err
+	}
+	// Result 3 means there was nothing to delete.
+	if resp.Result != 0 && resp.Result != 3 {
+		return fmt.Errorf("kea reservation-del failed: %s", resp.Text)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package kea
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+func TestRequestMarshalService(t *testing.T) {
+	b, err := json.Marshal(keamodels.Request{Command: "subnet6-list", Service: dhcp6Service})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != `{"command":"subnet6-list","service":["dhcp6"]}` {
+		t.Fatalf("unexpected encoding %s", got)
+	}
+	b, _ = json.Marshal(keamodels.Request{Command: "subnet4-list"})
+	if strings.Contains(string(b), "service") {
+		t.Fatalf("expected no service without one set, got %s", b)
+	}
+}
+
+// hosts6Kea holds one kea-dhcp6 host and records the commands it receives.
+type hosts6Kea struct {
+	host     map[string]any
+	subnets  []any
+	commands []keamodels.Request
+}
+
+func (f *hosts6Kea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	f.commands = append(f.commands, cmd)
+	switch cmd.Command {
+	case "subnet6-list":
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"subnets": f.subnets}}, nil
+	case cmdReservationGet:
+		if f.host == nil || f.host[cmd.Args[keaFieldIdentifierType].(string)] != cmd.Args[keaFieldIdentifier] {
+			return keamodels.Response{Result: 3}, nil
+		}
+		return keamodels.Response{Result: 0, Arguments: f.host}, nil
+	}
+	return keamodels.Response{Result: 0}, nil
+}
+
+func TestEnsureReservation6(t *testing.T) {
+	const duid = "00:03:00:01:aa:bb:cc:dd:ee:01"
+	kea := &hosts6Kea{host: map[string]any{
+		keaFieldSubnetID: 1, keaFieldDUID: duid, keaFieldIPAddresses: []any{"2001:db8::10"}, "hostname": "h1",
+	}}
+	s := New(kea)
+	ctx := context.Background()
+
+	action, err := s.EnsureReservation6(ctx, keamodels.ReservationConfig{MAC: testMAC, DUID: duid, SubnetID: 1, IPAddress: "2001:db8::10"})
+	if err != nil || action != ReservationUnchanged {
+		t.Fatalf("expected unchanged, got %v, %v", action, err)
+	}
+
+	action, err = s.EnsureReservation6(ctx, keamodels.ReservationConfig{MAC: testMAC, DUID: duid, SubnetID: 1, IPAddress: "2001:db8::11"})
+	if err != nil || action != ReservationUpdated {
+		t.Fatalf("expected updated, got %v, %v", action, err)
+	}
+	upd := kea.commands[len(kea.commands)-1]
+	res, _ := upd.Args["reservation"].(map[string]any)
+	if upd.Command != cmdReservationUpdate || upd.Service != dhcp6Service || res[keaFieldDUID] != duid || res["hostname"] != "h1" {
+		t.Fatalf("unexpected update %+v", upd)
+	}
+
+	action, err = s.EnsureReservation6(ctx, keamodels.ReservationConfig{MAC: testMAC, SubnetID: 1})
+	if err != nil || action != ReservationCreated {
+		t.Fatalf("expected a MAC-keyed reservation to be created, got %v, %v", action, err)
+	}
+	add := kea.commands[len(kea.commands)-1]
+	if res, _ := add.Args["reservation"].(map[string]any); add.Command != cmdReservationAdd || res[keaFieldHWAddress] != testMAC {
+		t.Fatalf("unexpected add %+v", add)
+	}
+}
+
+func TestGetOrCreateSubnet6(t *testing.T) {
+	kea := &hosts6Kea{subnets: []any{map[string]any{"id": 4, "subnet": "2001:db8:9::/64"}}}
+	s := New(kea)
+	id, created, err := s.GetOrCreateSubnet6(context.Background(), keamodels.Subnet6Config{
+		Subnet:  "2001:db8:1::/64",
+		Pools:   []string{"2001:db8:1::100 - 2001:db8:1::ffff"},
+		PDPools: []keamodels.PDPool{{Prefix: "2001:db8:8000::", PrefixLen: 40, DelegatedLen: 56}},
+	})
+	if err != nil || !created || id != 5 {
+		t.Fatalf("expected subnet 5 to be created, got %d, %v, %v", id, created, err)
+	}
+	add := kea.commands[len(kea.commands)-1]
+	subnets, _ := add.Args["subnet6"].([]map[string]any)
+	if add.Command != "subnet6-add" || len(subnets) != 1 || subnets[0]["pd-pools"] == nil {
+		t.Fatalf("unexpected subnet6-add %+v", add)
+	}
+}
+
+// With Client6 set, kea-dhcp6 commands go to it without the service field and
+// kea-dhcp4 commands stay on Client.
+func TestSend_Client6(t *testing.T) {
+	kea4, kea6 := &hosts6Kea{}, &hosts6Kea{}
+	s := New(kea4)
+	s.Client6 = kea6
+	ctx := context.Background()
+
+	if _, err := s.ListSubnets6(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.send(ctx, keamodels.Request{Command: "subnet4-list"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(kea6.commands) != 1 || kea6.commands[0].Command != "subnet6-list" || kea6.commands[0].Service != "" {
+		t.Fatalf("expected subnet6-list on Client6 without a service, got %+v", kea6.commands)
+	}
+	if len(kea4.commands) != 1 || kea4.commands[0].Command != "subnet4-list" {
+		t.Fatalf("expected only subnet4-list on Client, got %+v", kea4.commands)
+	}
+}
@@ -0,0 +1,70 @@
+package kea
+
+import (
+	"context"
+	"sync"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// writeCommands are the Kea commands that change server state. In a dry run
+// they are recorded instead of sent.
+var writeCommands = map[string]bool{
+	"subnet4-add":         true,
+	"subnet6-add":         true,
+	"subnet4-delta-add":   true,
+	"reservation-add":     true,
+	"reservation-update":  true,
+	"reservation-del":     true,
+	"lease4-add":          true,
+	"lease4-del":          true,
+	"network4-add":        true,
+	"network4-subnet-add": true,
+	"class-add":           true,
+	"class-update":        true,
+	"class-del":           true,
+}
+
+// DryRun records the write commands the Service would have sent to Kea for a
+// context created by WithDryRun. Read commands are still sent so the
+// recorded commands reflect the real Kea state.
+type DryRun struct {
+	mu       sync.Mutex
+	commands []keamodels.Request
+}
+
+// Commands returns the recorded commands in the order they were issued.
+func (d *DryRun) Commands() []keamodels.Request {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]keamodels.Request(nil), d.commands...)
+}
+
+func (d *DryRun) record(req keamodels.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.commands = append(d.commands, req)
+}
+
+type dryRunKey struct{}
+
+// WithDryRun returns a context under which Service calls record write
+// commands in d and report success without sending them.
+func WithDryRun(ctx context.Context, d *DryRun) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, d)
+}
+
+// send sends req to Kea, unless ctx is a dry run and req is a write command.
+// kea-dhcp6 commands go to Client6 when it is set, without the service field
+// a server's own control socket does not take.
+func (s *Service) send(ctx context.Context, req keamodels.Request) (keamodels.Response, error) {
+	if d, ok := ctx.Value(dryRunKey{}).(*DryRun); ok && d != nil && writeCommands[req.Command] {
+		d.record(req)
+		return keamodels.Response{Result: 0, Text: "dry run"}, nil
+	}
+	if req.Service == dhcp6Service && s.Client6 != nil {
+		req.Service = ""
+		return s.Client6.Send(ctx, req)
+	}
+	return s.Client.Send(ctx, req)
+}
@@ -0,0 +1,30 @@
+package kea
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+func TestDryRun_RecordsWritesOnly(t *testing.T) {
+	client := newHostsKea(map[string]any{keaFieldSubnetID: 1, keaFieldHWAddress: testMAC})
+	svc := New(client)
+	dr := &DryRun{}
+	ctx := WithDryRun(context.Background(), dr)
+
+	if err := svc.DeleteReservation(ctx, keamodels.ReservationConfig{MAC: testMAC, SubnetID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if client.host(testMAC) == nil {
+		t.Fatal("expected the reservation to survive a dry run")
+	}
+	if cmds := dr.Commands(); len(cmds) != 1 || cmds[0].Command != "reservation-del" {
+		t.Fatalf("expected a recorded reservation-del, got %v", cmds)
+	}
+
+	_, _, _ = svc.GetReservedIPv4(ctx, keamodels.ReservationConfig{MAC: testMAC})
+	if len(dr.Commands()) != 1 {
+		t.Fatalf("expected reads not to be recorded, got %v", dr.Commands())
+	}
+}
@@ -0,0 +1,182 @@
+package kea
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// Kea host reservation fields filled from keamodels.HostParams.
+const (
+	hostFieldHostname       = "hostname"
+	hostFieldNextServer     = "next-server"
+	hostFieldServerHostname = "server-hostname"
+	hostFieldBootFileName   = "boot-file-name"
+	hostFieldClientClasses  = "client-classes"
+	hostFieldOptionData     = "option-data"
+)
+
+// userContextHostFields lists, in a reservation's user-context, the host
+// fields the operator set. Fields dropped from the HostParams are removed
+// from the reservation again; fields set by hand are left alone.
+const userContextHostFields = "host-fields"
+
+// hostFieldArgs converts h into Kea host reservation fields. Empty fields are
+// left out.
+func hostFieldArgs(h keamodels.HostParams) map[string]any {
+	out := make(map[string]any)
+	for field, v := range map[string]string{
+		hostFieldHostname:       h.Hostname,
+		hostFieldNextServer:     h.NextServer,
+		hostFieldServerHostname: h.ServerHostname,
+		hostFieldBootFileName:   h.BootFileName,
+	} {
+		if v != "" {
+			out[field] = v
+		}
+	}
+	if len(h.ClientClasses) > 0 {
+		out[hostFieldClientClasses] = slices.Clone(h.ClientClasses)
+	}
+	if len(h.Options) > 0 {
+		data := make([]map[string]any, 0, len(h.Options))
+		for _, o := range h.Options {
+			data = append(data, optionDataArgs(o))
+		}
+		out[hostFieldOptionData] = data
+	}
+	return out
+}
+
+// managedHostFields returns the host fields the operator set on the
+// reservation, as recorded in its user-context.
+func (h *hostReservation) managedHostFields() []string {
+	uc, _ := h.raw[keaFieldUserContext].(map[string]any)
+	return stringList(uc[userContextHostFields])
+}
+
+// hostFieldsInSync reports whether existing carries the fields of want and
+// none of the fields the operator set earlier but want no longer has, and
+// records owner when one is given. A reservation made before owners were
+// recorded is therefore rewritten once to carry it.
+func hostFieldsInSync(existing *hostReservation, want keamodels.HostParams, owner string) bool {
+	if owner != "" && existing.Owner != owner {
+		return false
+	}
+	fields := hostFieldArgs(want)
+	for field, v := range fields {
+		have := existing.raw[field]
+		switch field {
+		case hostFieldHostname:
+			if s, _ := have.(string); !strings.EqualFold(s, want.Hostname) {
+				return false
+			}
+		case hostFieldClientClasses:
+			if !slices.Equal(stringList(have), want.ClientClasses) {
+				return false
+			}
+		case hostFieldOptionData:
+			options := hostOptions(have)
+			if len(options) != len(want.Options) || len(MissingOptions(want.Options, options)) > 0 {
+				return false
+			}
+		default:
+			if have != v {
+				return false
+			}
+		}
+	}
+	for _, field := range existing.managedHostFields() {
+		if _, wanted := fields[field]; !wanted && !emptyHostField(existing.raw[field]) {
+			return false
+		}
+	}
+	return true
+}
+
+// hostOptions parses the option-data list of a host record.
+func hostOptions(raw any) []keamodels.OptionData {
+	var out []keamodels.OptionData
+	switch list := raw.(type) {
+	case []any:
+		for _, entry := range list {
+			if o, ok := parseOptionData(entry); ok {
+				out = append(out, o)
+			}
+		}
+	case []map[string]any:
+		for _, entry := range list {
+			if o, ok := parseOptionData(entry); ok {
+				out = append(out, o)
+			}
+		}
+	}
+	return out
+}
+
+// emptyHostField reports whether a host field read from Kea is unset. Kea
+// returns next-server as 0.0.0.0 and the other fields as "" or [] when they
+// are not configured.
+func emptyHostField(v any) bool {
+	switch f := v.(type) {
+	case nil:
+		return true
+	case string:
+		return f == "" || f == "0.0.0.0"
+	case []any:
+		return len(f) == 0
+	case []string:
+		return len(f) == 0
+	case []map[string]any:
+		return len(f) == 0
+	}
+	return false
+}
+
+// hostUserContext builds the user-context of a reservation: the owner, if
+// any, and the host fields set from fields.
+func hostUserContext(owner string, fields map[string]any) map[string]any {
+	if owner == "" && len(fields) == 0 {
+		return nil
+	}
+	uc := map[string]any{userContextManagedBy: managedByValue}
+	if owner != "" {
+		uc[userContextOwner] = owner
+	}
+	if len(fields) > 0 {
+		uc[userContextHostFields] = slices.Sorted(maps.Keys(fields))
+	}
+	return uc
+}
+
+// mergeUserContext returns the existing user-context of a reservation with
+// the keys of uc written over it. The host-fields list is always taken from
+// uc, so it is dropped when the operator no longer sets any host field.
+func mergeUserContext(existing any, uc map[string]any) map[string]any {
+	out := map[string]any{}
+	if m, ok := existing.(map[string]any); ok {
+		maps.Copy(out, m)
+	}
+	delete(out, userContextHostFields)
+	maps.Copy(out, uc)
+	return out
+}
+
+// stringList converts a JSON-decoded list of strings.
+func stringList(v any) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []any:
+		out := make([]string, 0, len(list))
+		for _, e := range list {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
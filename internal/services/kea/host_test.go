@@ -0,0 +1,120 @@
+package kea
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// TestEnsureReservation_SyncsHostParams verifies that changed host settings
+// are written to an existing reservation without touching its address or the
+// fields set by hand, and that a second call is a no-op.
+func TestEnsureReservation_SyncsHostParams(t *testing.T) {
+	client := newHostsKea(map[string]any{
+		keaFieldSubnetID:        1,
+		keaFieldHWAddress:       testMAC,
+		keaFieldIPAddress:       testLeaseIP,
+		hostFieldServerHostname: "tftp.example.org",
+		hostFieldNextServer:     "0.0.0.0",
+	})
+	svc := New(client)
+	cfg := keamodels.ReservationConfig{
+		MAC: testMAC, SubnetID: 1, Owner: "ns/nc",
+		Host: keamodels.HostParams{
+			Hostname:      "node-1",
+			BootFileName:  "ipxe.efi",
+			ClientClasses: []string{"uefi"},
+			Options:       []keamodels.OptionData{{Name: "tftp-server-name", Code: 66, Data: "10.0.0.9"}},
+		},
+	}
+
+	action, err := svc.EnsureReservation(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if action != ReservationHostUpdated {
+		t.Fatalf("expected ReservationHostUpdated, got %v", action)
+	}
+	h := client.host(testMAC)
+	if h[keaFieldIPAddress] != testLeaseIP || h[hostFieldHostname] != "node-1" || h[hostFieldBootFileName] != "ipxe.efi" {
+		t.Fatalf("unexpected reservation %v", h)
+	}
+	if h[hostFieldServerHostname] != "tftp.example.org" {
+		t.Fatalf("expected hand-set server-hostname to be preserved, got %v", h[hostFieldServerHostname])
+	}
+	uc, _ := h[keaFieldUserContext].(map[string]any)
+	if got := stringList(uc[userContextHostFields]); len(got) != 4 || uc[userContextOwner] != "ns/nc" {
+		t.Fatalf("unexpected user-context %v", uc)
+	}
+
+	client.commands = nil
+	if action, err := svc.EnsureReservation(context.Background(), cfg); err != nil || action != ReservationUnchanged {
+		t.Fatalf("expected ReservationUnchanged on the second call, got %v, %v", action, err)
+	}
+	if client.sent(cmdReservationUpdate) {
+		t.Fatalf("expected no write commands, got %v", client.commands)
+	}
+}
+
+// TestEnsureReservation_DropsRemovedHostParams verifies that host fields the
+// operator set earlier are removed once they are no longer wanted.
+func TestEnsureReservation_DropsRemovedHostParams(t *testing.T) {
+	client := newHostsKea(map[string]any{
+		keaFieldSubnetID:      1,
+		keaFieldHWAddress:     testMAC,
+		keaFieldIPAddress:     testLeaseIP,
+		hostFieldHostname:     "node-1",
+		hostFieldBootFileName: "ipxe.efi",
+		keaFieldUserContext: map[string]any{
+			userContextOwner:      "ns/nc",
+			userContextManagedBy:  managedByValue,
+			userContextHostFields: []any{hostFieldBootFileName, hostFieldHostname},
+		},
+	})
+	svc := New(client)
+
+	action, err := svc.EnsureReservation(context.Background(), keamodels.ReservationConfig{
+		MAC: testMAC, SubnetID: 1, Owner: "ns/nc", Host: keamodels.HostParams{Hostname: "node-1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if action != ReservationHostUpdated {
+		t.Fatalf("expected ReservationHostUpdated, got %v", action)
+	}
+	h := client.host(testMAC)
+	if _, ok := h[hostFieldBootFileName]; ok {
+		t.Fatalf("expected boot-file-name to be removed, got %v", h)
+	}
+	if h[hostFieldHostname] != "node-1" || h[keaFieldIPAddress] != testLeaseIP {
+		t.Fatalf("unexpected reservation %v", h)
+	}
+}
+
+// TestEnsureReservation_MigratesOwnerlessReservation verifies that a
+// reservation made before owners were recorded gets the owner backfilled, so
+// that after a prefix change the reservation in the new subnet is not a
+// conflict with it.
+func TestEnsureReservation_MigratesOwnerlessReservation(t *testing.T) {
+	client := newHostsKea(map[string]any{keaFieldSubnetID: 1, keaFieldHWAddress: testMAC, keaFieldIPAddress: testLeaseIP})
+	svc := New(client)
+	ctx := context.Background()
+	cfg := keamodels.ReservationConfig{MAC: testMAC, SubnetID: 1, IPAddress: testLeaseIP, Owner: testOwner}
+
+	if action, err := svc.EnsureReservation(ctx, cfg); err != nil || action != ReservationHostUpdated {
+		t.Fatalf("expected ReservationHostUpdated, got %v, %v", action, err)
+	}
+	h := client.host(testMAC)
+	if uc, _ := h[keaFieldUserContext].(map[string]any); uc[userContextOwner] != testOwner || h[keaFieldIPAddress] != testLeaseIP {
+		t.Fatalf("expected the owner backfilled and the address kept, got %v", h)
+	}
+	if action, err := svc.EnsureReservation(ctx, cfg); err != nil || action != ReservationUnchanged {
+		t.Fatalf("expected ReservationUnchanged once the owner is recorded, got %v, %v", action, err)
+	}
+
+	cfg.SubnetID, cfg.IPAddress = 2, ""
+	if action, err := svc.EnsureReservation(ctx, cfg); err != nil || action != ReservationCreated {
+		t.Fatalf("expected the reservation in the new subnet created, got %v, %v", action, err)
+	}
+}
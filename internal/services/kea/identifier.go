@@ -0,0 +1,97 @@
+package kea
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// DHCPv4 host identifier types a reservation can be keyed on.
+const (
+	IdentifierHWAddress = keaFieldHWAddress
+	IdentifierClientID  = "client-id"
+	IdentifierDUID      = keaFieldDUID
+	IdentifierCircuitID = "circuit-id"
+	IdentifierFlexID    = "flex-id"
+)
+
+// hostIdentifierTypes lists the identifier types in the order a host record
+// is searched for its identifier.
+var hostIdentifierTypes = []string{IdentifierHWAddress, IdentifierClientID, IdentifierDUID, IdentifierCircuitID, IdentifierFlexID}
+
+// IsIdentifierType reports whether t is a DHCPv4 host identifier type.
+func IsIdentifierType(t string) bool {
+	return slices.Contains(hostIdentifierTypes, t)
+}
+
+// NormalizeIdentifier returns value as lowercase colon-separated hex, the
+// form identifiers are compared in. value is hex, with or without ":", "-"
+// or "." separators, or text in single quotes ('ge-0/0/1'), which is
+// converted to its bytes as Kea does.
+func NormalizeIdentifier(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	var raw []byte
+	if text, ok := strings.CutPrefix(value, "'"); ok {
+		text, ok = strings.CutSuffix(text, "'")
+		if !ok || text == "" {
+			return "", fmt.Errorf("unterminated quoted identifier %s", value)
+		}
+		raw = []byte(text)
+	} else {
+		digits := strings.NewReplacer(":", "", "-", "", ".", "").Replace(value)
+		var err error
+		if raw, err = hex.DecodeString(digits); err != nil || len(raw) == 0 {
+			return "", fmt.Errorf("%q is neither hex nor quoted text", value)
+		}
+	}
+	parts := make([]string, len(raw))
+	for i, b := range raw {
+		parts[i] = hex.EncodeToString([]byte{b})
+	}
+	return strings.Join(parts, ":"), nil
+}
+
+// host4Identifier returns the identifier-type and identifier the DHCPv4
+// reservation of cfg is keyed on: cfg.IdentifierType, hw-address by default,
+// with cfg.Identifier. Without an identifier the MAC is used for hw-address
+// and the client-id "01:<mac>" (hardware type Ethernet) for client-id.
+func host4Identifier(cfg keamodels.ReservationConfig) (string, string, error) {
+	mac := strings.ToLower(strings.TrimSpace(cfg.MAC))
+	idType := cfg.IdentifierType
+	if idType == "" {
+		idType = IdentifierHWAddress
+	}
+	if !IsIdentifierType(idType) {
+		return "", "", fmt.Errorf("unsupported identifier-type %q", idType)
+	}
+	value := strings.TrimSpace(cfg.Identifier)
+	if value == "" {
+		switch {
+		case mac == "":
+			return "", "", fmt.Errorf("missing mac")
+		case idType == IdentifierHWAddress:
+			value = mac
+		case idType == IdentifierClientID:
+			value = "01:" + mac
+		default:
+			return "", "", fmt.Errorf("missing identifier for identifier-type %s", idType)
+		}
+	}
+	id, err := NormalizeIdentifier(value)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", idType, err)
+	}
+	return idType, id, nil
+}
+
+// describeIdentifier names an identifier for messages, e.g.
+// "client-id 01:aa:bb:cc:dd:ee:ff".
+func describeIdentifier(idType, id string) string {
+	if idType == "" {
+		idType = IdentifierHWAddress
+	}
+	return idType + " " + id
+}
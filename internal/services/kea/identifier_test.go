@@ -0,0 +1,82 @@
+package kea
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+func TestNormalizeIdentifier(t *testing.T) {
+	for in, want := range map[string]string{
+		"01:AA:BB:CC:DD:EE:01": "01:aa:bb:cc:dd:ee:01",
+		"01-aa-bb-cc-dd-ee-01": "01:aa:bb:cc:dd:ee:01",
+		"01aa.bbcc.ddee.01":    "01:aa:bb:cc:dd:ee:01",
+		"'ge-0/0/1'":           "67:65:2d:30:2f:30:2f:31",
+	} {
+		if got, err := NormalizeIdentifier(in); err != nil || got != want {
+			t.Errorf("%q: expected %q, got %q, %v", in, want, got, err)
+		}
+	}
+	for _, bad := range []string{"", "xyz", "abc", "'open", "''"} {
+		if _, err := NormalizeIdentifier(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestHost4Identifier(t *testing.T) {
+	for _, tc := range []struct {
+		cfg          keamodels.ReservationConfig
+		wantType, id string
+	}{
+		{cfg: keamodels.ReservationConfig{MAC: "AA:BB:CC:DD:EE:01"}, wantType: IdentifierHWAddress, id: testMAC},
+		{cfg: keamodels.ReservationConfig{MAC: testMAC, IdentifierType: IdentifierClientID}, wantType: IdentifierClientID, id: "01:" + testMAC},
+		{cfg: keamodels.ReservationConfig{MAC: testMAC, IdentifierType: IdentifierCircuitID, Identifier: "'eth0'"}, wantType: IdentifierCircuitID, id: "65:74:68:30"},
+	} {
+		idType, id, err := host4Identifier(tc.cfg)
+		if err != nil || idType != tc.wantType || id != tc.id {
+			t.Errorf("%+v: expected %s %s, got %s %s, %v", tc.cfg, tc.wantType, tc.id, idType, id, err)
+		}
+	}
+	for _, bad := range []keamodels.ReservationConfig{
+		{MAC: testMAC, IdentifierType: IdentifierFlexID},
+		{MAC: testMAC, IdentifierType: "serial"},
+		{IdentifierType: IdentifierClientID},
+	} {
+		if _, _, err := host4Identifier(bad); err == nil {
+			t.Errorf("%+v: expected an error", bad)
+		}
+	}
+}
+
+// TestEnsureReservation_ClientID verifies that a reservation keyed on a
+// client-id is created, found again and removed by that client-id, leaving the
+// hw-address reservation of the same MAC in another subnet alone.
+func TestEnsureReservation_ClientID(t *testing.T) {
+	client := newHostsKea(map[string]any{keaFieldSubnetID: 2, keaFieldHWAddress: testMAC})
+	svc := New(client)
+	ctx := context.Background()
+	cfg := keamodels.ReservationConfig{MAC: testMAC, SubnetID: 1, IPAddress: testLeaseIP, IdentifierType: IdentifierClientID}
+
+	if action, err := svc.EnsureReservation(ctx, cfg); err != nil || action != ReservationCreated {
+		t.Fatalf("expected ReservationCreated, got %v, %v", action, err)
+	}
+	h := client.host("01:" + testMAC)
+	if h == nil || h[IdentifierClientID] != "01:"+testMAC || h[keaFieldHWAddress] != nil {
+		t.Fatalf("expected a reservation keyed on the client-id, got %v", h)
+	}
+	if action, err := svc.EnsureReservation(ctx, cfg); err != nil || action != ReservationUnchanged {
+		t.Fatalf("expected ReservationUnchanged, got %v, %v", action, err)
+	}
+	if ip, sid, err := svc.GetReservedIPv4(ctx, cfg); err != nil || ip != testLeaseIP || sid != 1 {
+		t.Fatalf("expected %s in subnet 1, got %s, %d, %v", testLeaseIP, ip, sid, err)
+	}
+
+	if err := svc.DeleteReservation(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if client.host("01:"+testMAC) != nil || len(client.hosts) != 1 {
+		t.Fatalf("expected only the client-id reservation removed, got %v", client.hosts)
+	}
+}
@@ -0,0 +1,298 @@
+package kea
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// Kea lease4 states.
+const (
+	LeaseStateDefault          = 0
+	LeaseStateDeclined         = 1
+	LeaseStateExpiredReclaimed = 2
+)
+
+// LeaseInfo is the subset of a Kea lease4 record the operator acts on.
+type LeaseInfo struct {
+	IPAddress string
+	HWAddress string
+	SubnetID  int
+
+	// Expires is cltt + valid-lft and Renewed is cltt, the last time the
+	// client renewed the lease; zero when Kea did not report them.
+	Expires  time.Time
+	Renewed  time.Time
+	Hostname string
+	ClientID string
+	State    int
+	// Peer is the Kea server that answered the lookup, when known.
+	Peer string
+}
+
+// StateName returns the Kea name of the lease state.
+func (l *LeaseInfo) StateName() string {
+	switch l.State {
+	case LeaseStateDefault:
+		return "default"
+	case LeaseStateDeclined:
+		return "declined"
+	case LeaseStateExpiredReclaimed:
+		return "expired-reclaimed"
+	}
+	return strconv.Itoa(l.State)
+}
+
+// GetLeaseForMAC returns the most recent lease for mac via
+// lease4-get-by-hw-address, or nil when it holds none.
+func (s *Service) GetLeaseForMAC(ctx context.Context, mac string) (*LeaseInfo, error) {
+	leases, err := s.GetLeasesForMAC(ctx, mac)
+	if err != nil || len(leases) == 0 {
+		return nil, err
+	}
+	// Kea returns every lease of the MAC; pick the newest (largest cltt).
+	best := &leases[0]
+	for i := range leases[1:] {
+		if l := &leases[i+1]; l.Renewed.After(best.Renewed) {
+			best = l
+		}
+	}
+	return best, nil
+}
+
+// GetLeasesForMAC returns every lease mac holds, in any subnet, via
+// lease4-get-by-hw-address.
+func (s *Service) GetLeasesForMAC(ctx context.Context, mac string) ([]LeaseInfo, error) {
+	mac = strings.ToLower(strings.TrimSpace(mac))
+	if mac == "" {
+		return nil, fmt.Errorf("missing mac")
+	}
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: "lease4-get-by-hw-address",
+		Args:    map[string]any{keaFieldHWAddress: mac},
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.Result {
+	case 0:
+	case 3: // empty: no lease for this MAC
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("kea lease4-get-by-hw-address failed: %s", resp.Text)
+	}
+
+	var records []map[string]any
+	switch leases := resp.Arguments["leases"].(type) {
+	case []any:
+		for _, elem := range leases {
+			if m, ok := elem.(map[string]any); ok {
+				records = append(records, m)
+			}
+		}
+	case map[string]any:
+		// Some deployments might return a single lease object; keep legacy support.
+		records = append(records, leases)
+	}
+	var out []LeaseInfo
+	for _, m := range records {
+		lease := s.parseLease(m)
+		// Be defensive in case server returns extra entries
+		if lease.IPAddress == "" || (lease.HWAddress != "" && lease.HWAddress != mac) {
+			continue
+		}
+		out = append(out, *lease)
+	}
+	return out, nil
+}
+
+// GetLeaseForIP returns the active lease for ip via lease4-get, or nil when the
+// address isn't leased.
+func (s *Service) GetLeaseForIP(ctx context.Context, ip string) (*LeaseInfo, error) {
+	req := keamodels.Request{
+		Command: "lease4-get",
+		Args:    map[string]any{keaFieldIPAddress: ip},
+	}
+	resp, err := s.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.Result {
+	case 0:
+	case 3: // empty: no lease for this address
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("kea lease4-get failed: %s", resp.Text)
+	}
+	lease := s.parseLease(resp.Arguments)
+	lease.IPAddress = ip
+	return lease, nil
+}
+
+// parseLease reads a lease4 record as Kea returns it.
+func (s *Service) parseLease(m map[string]any) *LeaseInfo {
+	lease := &LeaseInfo{Peer: s.Peer()}
+	lease.IPAddress, _ = m[keaFieldIPAddress].(string)
+	if hw, ok := m[keaFieldHWAddress].(string); ok {
+		lease.HWAddress = strings.ToLower(strings.TrimSpace(hw))
+	}
+	lease.SubnetID, _ = asInt(m[keaFieldSubnetID])
+	lease.Hostname, _ = m["hostname"].(string)
+	lease.ClientID, _ = m["client-id"].(string)
+	lease.State, _ = asInt(m["state"])
+	cltt, okCLTT := asInt(m["cltt"])
+	lft, okLft := asInt(m["valid-lft"])
+	if okCLTT {
+		lease.Renewed = time.Unix(int64(cltt), 0).UTC()
+	}
+	if okCLTT && okLft {
+		lease.Expires = lease.Renewed.Add(time.Duration(lft) * time.Second)
+	}
+	return lease
+}
+
+// GetLeasePage returns up to limit leases following the address from via
+// lease4-get-page, starting at the lowest address when from is "". next is
+// the from of the following page, or "" once the last page was returned.
+func (s *Service) GetLeasePage(ctx context.Context, from string, limit int) (leases []LeaseInfo, next string, err error) {
+	if from == "" {
+		from = "start"
+	}
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: "lease4-get-page",
+		Args:    map[string]any{"from": from, "limit": limit},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	switch resp.Result {
+	case 0:
+	case 3: // empty: no leases past from
+		return nil, "", nil
+	default:
+		return nil, "", fmt.Errorf("kea lease4-get-page failed: %s", resp.Text)
+	}
+	list, _ := resp.Arguments["leases"].([]any)
+	for _, elem := range list {
+		m, ok := elem.(map[string]any)
+		if !ok {
+			continue
+		}
+		leases = append(leases, *s.parseLease(m))
+	}
+	if len(list) < limit || len(leases) == 0 {
+		return leases, "", nil
+	}
+	return leases, leases[len(leases)-1].IPAddress, nil
+}
+
+// DeleteLease removes the lease for ip via lease4-del. A missing lease is not an error.
+func (s *Service) DeleteLease(ctx context.Context, ip string) error {
+	req := keamodels.Request{
+		Command: "lease4-del",
+		Args:    map[string]any{keaFieldIPAddress: ip},
+	}
+	resp, err := s.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.Result != 0 && resp.Result != 3 {
+		return fmt.Errorf("kea lease4-del failed: %s", resp.Text)
+	}
+	return nil
+}
+
+// SeedLease adds a lease for cfg.MAC on cfg.IPAddress via lease4-add, so the
+// address is in use for Kea and anyone reading its leases before the host
+// first asks for it. It reports whether the lease was added; a lease cfg.MAC
+// already holds on the address is left as is. A lease of another MAC on the
+// address, including one added concurrently, is returned as a
+// *ReservationConflictError of kind ConflictIPAddressLeased.
+func (s *Service) SeedLease(ctx context.Context, cfg keamodels.LeaseConfig) (bool, error) {
+	mac := strings.ToLower(strings.TrimSpace(cfg.MAC))
+	ip := strings.TrimSpace(cfg.IPAddress)
+	if mac == "" || ip == "" {
+		return false, fmt.Errorf("missing mac or ip")
+	}
+	// held reports whether ip is leased, to mac or as a conflict otherwise.
+	held := func() (bool, error) {
+		lease, err := s.GetLeaseForIP(ctx, ip)
+		if err != nil || lease == nil {
+			return false, err
+		}
+		if lease.HWAddress != "" && lease.HWAddress != mac {
+			return true, &ReservationConflictError{
+				Kind:           ConflictIPAddressLeased,
+				MAC:            mac,
+				IPAddress:      ip,
+				SubnetID:       cfg.SubnetID,
+				OtherHWAddress: lease.HWAddress,
+				OtherSubnetID:  lease.SubnetID,
+			}
+		}
+		return true, nil
+	}
+	if ok, err := held(); ok || err != nil {
+		return false, err
+	}
+
+	args := map[string]any{
+		keaFieldIPAddress: ip,
+		keaFieldHWAddress: mac,
+		keaFieldSubnetID:  cfg.SubnetID,
+	}
+	if cfg.Hostname != "" {
+		args["hostname"] = cfg.Hostname
+	}
+	if cfg.ValidLife > 0 {
+		args["valid-lft"] = cfg.ValidLife
+	}
+	if cfg.Owner != "" {
+		args[keaFieldUserContext] = map[string]any{userContextOwner: cfg.Owner}
+	}
+	resp, err := s.send(ctx, keamodels.Request{Command: "lease4-add", Args: args})
+	if err != nil {
+		return false, err
+	}
+	if resp.Result != 0 {
+		// Most likely a lease was added for the address since the lookup.
+		if ok, err := held(); ok || err != nil {
+			return false, err
+		}
+		return false, fmt.Errorf("kea lease4-add failed: %s", resp.Text)
+	}
+	return true, nil
+}
+
+// EnsureIPNotLeasedToOther verifies that ip is not leased to a MAC other than
+// mac. When it is and release is set, the conflicting dynamic lease is deleted
+// and released=true is returned; otherwise a *ReservationConflictError of kind
+// ConflictIPAddressLeased is returned.
+func (s *Service) EnsureIPNotLeasedToOther(ctx context.Context, ip, mac string, release bool) (bool, error) {
+	mac = strings.ToLower(strings.TrimSpace(mac))
+	lease, err := s.GetLeaseForIP(ctx, ip)
+	if err != nil {
+		return false, err
+	}
+	if lease == nil || lease.HWAddress == "" || lease.HWAddress == mac {
+		return false, nil
+	}
+	if !release {
+		return false, &ReservationConflictError{
+			Kind:           ConflictIPAddressLeased,
+			MAC:            mac,
+			IPAddress:      ip,
+			SubnetID:       lease.SubnetID,
+			OtherHWAddress: lease.HWAddress,
+			OtherSubnetID:  lease.SubnetID,
+		}
+	}
+	if err := s.DeleteLease(ctx, ip); err != nil {
+		return false, err
+	}
+	return true, nil
+}
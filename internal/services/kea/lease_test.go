@@ -0,0 +1,164 @@
+package kea
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// leasesKea answers lease4-get/lease4-add/lease4-del from a map of ip ->
+// hw-address. When raceMAC is set, another client takes the address just
+// before a lease4-add arrives.
+type leasesKea struct {
+	leases  map[string]string
+	raceMAC string
+	added   []map[string]any
+}
+
+func (f *leasesKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	ip, _ := cmd.Args[keaFieldIPAddress].(string)
+	switch cmd.Command {
+	case "lease4-get":
+		hw, ok := f.leases[ip]
+		if !ok {
+			return keamodels.Response{Result: 3, Text: "Lease not found."}, nil
+		}
+		return keamodels.Response{Result: 0, Arguments: map[string]any{
+			keaFieldIPAddress: ip, keaFieldHWAddress: hw, keaFieldSubnetID: 1,
+		}}, nil
+	case "lease4-add":
+		if f.raceMAC != "" {
+			f.leases[ip] = f.raceMAC
+		}
+		if _, ok := f.leases[ip]; ok {
+			return keamodels.Response{Result: 1, Text: "Lease for address " + ip + " already exists."}, nil
+		}
+		f.leases[ip], _ = cmd.Args[keaFieldHWAddress].(string)
+		f.added = append(f.added, cmd.Args)
+		return keamodels.Response{Result: 0}, nil
+	case "lease4-del":
+		delete(f.leases, ip)
+		return keamodels.Response{Result: 0}, nil
+	}
+	return keamodels.Response{Result: 0}, nil
+}
+
+func TestEnsureIPNotLeasedToOther(t *testing.T) {
+	ctx := context.Background()
+
+	client := &leasesKea{leases: map[string]string{testLeaseIP: testOtherMAC}}
+	svc := New(client)
+	if _, err := svc.EnsureIPNotLeasedToOther(ctx, testLeaseIP, testMAC, false); err == nil {
+		t.Fatalf("expected conflict without release")
+	} else if c, ok := AsReservationConflict(err); !ok || c.Kind != ConflictIPAddressLeased || c.OtherHWAddress != testOtherMAC {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	released, err := svc.EnsureIPNotLeasedToOther(ctx, testLeaseIP, testMAC, true)
+	if err != nil || !released {
+		t.Fatalf("expected lease to be released, got released=%v err=%v", released, err)
+	}
+	if _, still := client.leases[testLeaseIP]; still {
+		t.Fatalf("expected lease4-del to remove the lease")
+	}
+
+	client.leases[testLeaseIP] = testMAC
+	if released, err := svc.EnsureIPNotLeasedToOther(ctx, testLeaseIP, testMAC, true); err != nil || released {
+		t.Fatalf("own lease must not be released, got released=%v err=%v", released, err)
+	}
+}
+
+func TestSeedLease(t *testing.T) {
+	ctx := context.Background()
+	client := &leasesKea{leases: map[string]string{}}
+	svc := New(client)
+	cfg := keamodels.LeaseConfig{MAC: testMAC, IPAddress: testLeaseIP, SubnetID: 1, Hostname: "node-1", ValidLife: 600}
+
+	if added, err := svc.SeedLease(ctx, cfg); err != nil || !added {
+		t.Fatalf("expected the lease added, got %v, %v", added, err)
+	}
+	if args := client.added[0]; args["hostname"] != "node-1" || args["valid-lft"] != 600 || args[keaFieldSubnetID] != 1 {
+		t.Fatalf("unexpected lease4-add arguments %v", args)
+	}
+	if added, err := svc.SeedLease(ctx, cfg); err != nil || added {
+		t.Fatalf("expected the existing lease kept, got %v, %v", added, err)
+	}
+
+	client.leases[testLeaseIP] = testOtherMAC
+	if _, err := svc.SeedLease(ctx, cfg); err == nil {
+		t.Fatal("expected a conflict with the lease of another MAC")
+	} else if c, ok := AsReservationConflict(err); !ok || c.Kind != ConflictIPAddressLeased || c.OtherHWAddress != testOtherMAC {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client = &leasesKea{leases: map[string]string{}, raceMAC: testOtherMAC}
+	if _, err := New(client).SeedLease(ctx, cfg); err == nil {
+		t.Fatal("expected a conflict with a lease added concurrently")
+	} else if _, ok := AsReservationConflict(err); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGetLeaseForMAC_Details(t *testing.T) {
+	client := fakeKeaClient{resp: keamodels.Response{
+		Result: 0,
+		Arguments: map[string]any{
+			"leases": []any{
+				map[string]any{
+					keaFieldHWAddress: testMAC, keaFieldIPAddress: "10.0.0.5", keaFieldSubnetID: 1.0,
+					"cltt": 1000.0, "valid-lft": 3600.0, "state": 2.0,
+				},
+				map[string]any{
+					keaFieldHWAddress: testMAC, keaFieldIPAddress: testLeaseIP, keaFieldSubnetID: 1.0,
+					"cltt": 2000.0, "valid-lft": 3600.0, "state": 0.0,
+					"hostname": "pxe-host", "client-id": "01:aa:bb",
+				},
+			},
+		},
+	}}
+	lease, err := New(client).GetLeaseForMAC(context.Background(), testMAC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease == nil || lease.IPAddress != testLeaseIP || lease.SubnetID != 1 {
+		t.Fatalf("expected newest lease %s, got %+v", testLeaseIP, lease)
+	}
+	if !lease.Expires.Equal(time.Unix(5600, 0)) || lease.Hostname != "pxe-host" || lease.ClientID != "01:aa:bb" || lease.StateName() != "default" {
+		t.Fatalf("unexpected lease details %+v", lease)
+	}
+
+	lease, err = New(fakeKeaClient{resp: keamodels.Response{Result: 3}}).GetLeaseForMAC(context.Background(), testMAC)
+	if err != nil || lease != nil {
+		t.Fatalf("expected no lease, got %+v, %v", lease, err)
+	}
+}
+
+func TestGetLeasePage(t *testing.T) {
+	full := fakeKeaClient{resp: keamodels.Response{
+		Result: 0,
+		Arguments: map[string]any{
+			"count": 2.0,
+			"leases": []any{
+				map[string]any{keaFieldHWAddress: testMAC, keaFieldIPAddress: "10.0.0.5", keaFieldSubnetID: 1.0, "cltt": 1000.0},
+				map[string]any{keaFieldHWAddress: testMAC, keaFieldIPAddress: testLeaseIP, keaFieldSubnetID: 1.0, "cltt": 2000.0},
+			},
+		},
+	}}
+	leases, next, err := New(full).GetLeasePage(context.Background(), "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leases) != 2 || next != testLeaseIP || !leases[1].Renewed.Equal(time.Unix(2000, 0)) {
+		t.Fatalf("expected a full page continuing after %s, got %+v, next %q", testLeaseIP, leases, next)
+	}
+
+	// A short page is the last one.
+	if leases, next, err = New(full).GetLeasePage(context.Background(), testLeaseIP, 3); err != nil || len(leases) != 2 || next != "" {
+		t.Fatalf("expected the last page, got %+v, next %q, %v", leases, next, err)
+	}
+	if leases, next, err = New(fakeKeaClient{resp: keamodels.Response{Result: 3}}).GetLeasePage(context.Background(), testLeaseIP, 2); err != nil || leases != nil || next != "" {
+		t.Fatalf("expected no leases past the last page, got %+v, next %q, %v", leases, next, err)
+	}
+}
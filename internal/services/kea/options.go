@@ -0,0 +1,124 @@
+package kea
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// Option codes of the dhcp4 options the service fills from SubnetConfig
+// fields and reads back into SubnetInfo.
+const (
+	optionCodeRouters           = 3
+	optionCodeDomainNameServers = 6
+)
+
+// subnetOptionData builds the option-data list of a new subnet: routers and
+// domain-name-servers from Gateway and DNS, unless cfg.Options carries its
+// own entry for them, followed by cfg.Options.
+func subnetOptionData(cfg keamodels.SubnetConfig) []map[string]any {
+	var base []keamodels.OptionData
+	if cfg.Gateway != "" {
+		base = append(base, keamodels.OptionData{Name: "routers", Code: optionCodeRouters, Data: cfg.Gateway})
+	}
+	if len(cfg.DNS) > 0 {
+		base = append(base, keamodels.OptionData{Name: "domain-name-servers", Code: optionCodeDomainNameServers, Data: strings.Join(cfg.DNS, ", ")})
+	}
+	var out []map[string]any
+	for _, o := range base {
+		if !slices.ContainsFunc(cfg.Options, o.Matches) {
+			out = append(out, optionDataArgs(o))
+		}
+	}
+	for _, o := range cfg.Options {
+		out = append(out, optionDataArgs(o))
+	}
+	return out
+}
+
+// optionDataArgs converts o into a Kea option-data entry.
+func optionDataArgs(o keamodels.OptionData) map[string]any {
+	m := map[string]any{"data": o.Data}
+	if o.Name != "" {
+		m["name"] = o.Name
+	}
+	if o.Code > 0 {
+		m["code"] = o.Code
+	}
+	if o.Space != "" {
+		m["space"] = o.Space
+	}
+	if o.CSVFormat != nil {
+		m["csv-format"] = *o.CSVFormat
+	}
+	return m
+}
+
+// parseOptionData converts a Kea option-data entry into an OptionData.
+func parseOptionData(raw any) (keamodels.OptionData, bool) {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return keamodels.OptionData{}, false
+	}
+	o := keamodels.OptionData{}
+	o.Name, _ = m["name"].(string)
+	code := m["code"]
+	if p, ok := code.(*any); ok && p != nil {
+		code = *p
+	}
+	o.Code, _ = asInt(code)
+	o.Space, _ = m["space"].(string)
+	switch d := m["data"].(type) {
+	case string:
+		o.Data = d
+	case *any:
+		if d != nil {
+			o.Data, _ = (*d).(string)
+		}
+	}
+	if csv, ok := m["csv-format"].(bool); ok {
+		o.CSVFormat = &csv
+	}
+	return o, o.Name != "" || o.Code > 0
+}
+
+// MissingOptions returns the entries of want that have no option in have
+// carrying the same data.
+func MissingOptions(want, have []keamodels.OptionData) []keamodels.OptionData {
+	var out []keamodels.OptionData
+	for _, w := range want {
+		if !slices.ContainsFunc(have, func(h keamodels.OptionData) bool { return w.Matches(h) && w.SameData(h) }) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// UpdateSubnetOptions adds options to an existing IPv4 subnet, replacing
+// options of the same code, via subnet4-delta-add. Other options and the
+// rest of the subnet are left as they are.
+func (s *Service) UpdateSubnetOptions(ctx context.Context, subnetID int, subnet string, options []keamodels.OptionData) error {
+	if len(options) == 0 {
+		return nil
+	}
+	data := make([]map[string]any, 0, len(options))
+	for _, o := range options {
+		data = append(data, optionDataArgs(o))
+	}
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: "subnet4-delta-add",
+		Args: map[string]any{
+			"subnet4": []map[string]any{{"id": subnetID, "subnet": subnet, "option-data": data}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Result != 0 {
+		return fmt.Errorf("kea subnet4-delta-add failed: %s", resp.Text)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package kea
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// subnetGetKea answers subnet4-get with a fixed subnet and records the
+// subnet4-add and subnet4-delta-add it receives.
+type subnetGetKea struct {
+	subnet map[string]any
+	added  []map[string]any
+	delta  []map[string]any
+}
+
+func (f *subnetGetKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	switch cmd.Command {
+	case "subnet4-get":
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"subnet4": []any{f.subnet}}}, nil
+	case cmdSubnet4Add:
+		f.added, _ = cmd.Args["subnet4"].([]map[string]any)
+	case "subnet4-delta-add":
+		f.delta, _ = cmd.Args["subnet4"].([]map[string]any)
+	}
+	return keamodels.Response{Result: 0}, nil
+}
+
+func TestCreateSubnet_OptionData(t *testing.T) {
+	kea := &subnetGetKea{}
+	s := New(kea)
+	_, err := s.CreateSubnet(context.Background(), keamodels.SubnetConfig{
+		Subnet:  testCIDR,
+		ID:      1,
+		Gateway: "10.0.0.1",
+		DNS:     []string{"10.0.0.2"},
+		Options: []keamodels.OptionData{
+			{Name: "routers", Code: 3, Data: "10.0.0.254"},
+			{Name: "interface-mtu", Code: 26, Data: "9000"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, _ := kea.added[0]["option-data"].([]map[string]any)
+	if len(opts) != 3 {
+		t.Fatalf("expected DNS, routers and interface-mtu, got %+v", opts)
+	}
+	for _, o := range opts {
+		if o["code"] == 3 && o["data"] != "10.0.0.254" {
+			t.Fatalf("expected the routers option to replace Gateway, got %+v", o)
+		}
+	}
+}
+
+func TestGetSubnetInfo_Options(t *testing.T) {
+	kea := &subnetGetKea{subnet: map[string]any{
+		"id": 1, "subnet": testCIDR,
+		"option-data": []any{
+			map[string]any{"name": "routers", "code": float64(3), "space": "dhcp4", "data": "10.0.0.1", "csv-format": true},
+			map[string]any{"name": "domain-name-servers", "code": float64(6), "space": "dhcp4", "data": "10.0.0.2, 10.0.0.3"},
+			map[string]any{"name": "ntp-servers", "code": float64(42), "space": "dhcp4", "data": "10.0.0.5"},
+		},
+	}}
+	info, err := New(kea).GetSubnetInfo(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Gateway != "10.0.0.1" || len(info.DNS) != 2 || len(info.Options) != 3 || info.Options[2].Name != "ntp-servers" {
+		t.Fatalf("unexpected subnet info %+v", info)
+	}
+
+	want := []keamodels.OptionData{
+		{Name: "domain-name-servers", Code: 6, Data: "10.0.0.2,10.0.0.3"},
+		{Name: "ntp-servers", Code: 42, Data: "10.0.0.6"},
+	}
+	if missing := MissingOptions(want, info.Options); len(missing) != 1 || missing[0].Name != "ntp-servers" {
+		t.Fatalf("expected only the changed ntp-servers to be missing, got %+v", missing)
+	}
+}
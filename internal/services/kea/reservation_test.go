@@ -0,0 +1,243 @@
+package kea
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+const (
+	cmdReservationAdd     = "reservation-add"
+	cmdReservationUpdate  = "reservation-update"
+	cmdReservationDel     = "reservation-del"
+	cmdReservationGetByID = "reservation-get-by-id"
+	cmdReservationGet     = "reservation-get"
+	cmdReservationGetAll  = "reservation-get-all"
+	cmdLease4GetAll       = "lease4-get-all"
+	testMAC               = "aa:bb:cc:dd:ee:01"
+	testLeaseIP           = "10.0.0.50"
+)
+
+// hostsKea is a minimal stateful host-reservation fake. Hosts are keyed by
+// identifier and subnet-id and it answers the host_cmds commands the service
+// issues. When noUpdate is set, reservation-update is rejected as unsupported
+// to exercise the del+add fallback; failUpdate makes it fail otherwise.
+// leases is returned as-is by lease4-get-all.
+type hostsKea struct {
+	mu         sync.Mutex
+	hosts      []map[string]any
+	leases     []any
+	noUpdate   bool
+	failUpdate bool
+	commands   []string
+}
+
+func newHostsKea(hosts ...map[string]any) *hostsKea {
+	return &hostsKea{hosts: hosts}
+}
+
+// hostKey returns the identifier and subnet-id of h, whatever its
+// identifier type.
+func hostKey(h map[string]any) (string, int) {
+	sid, _ := asInt(h[keaFieldSubnetID])
+	for _, t := range hostIdentifierTypes {
+		if id, ok := h[t].(string); ok {
+			return id, sid
+		}
+	}
+	return "", sid
+}
+
+// indexOf returns the index of the host matching id and subnet-id, or -1.
+func (f *hostsKea) indexOf(id string, sid int) int {
+	for i, h := range f.hosts {
+		if hid, hsid := hostKey(h); hid == id && hsid == sid {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *hostsKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, cmd.Command)
+	switch cmd.Command {
+	case cmdReservationGetByID:
+		idType, _ := cmd.Args[keaFieldIdentifierType].(string)
+		id, _ := cmd.Args[keaFieldIdentifier].(string)
+		var found []any
+		for _, h := range f.hosts {
+			if h[idType] == id {
+				found = append(found, h)
+			}
+		}
+		if len(found) == 0 {
+			return keamodels.Response{Result: 3, Text: "0 IPv4 host(s) found."}, nil
+		}
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"hosts": found}}, nil
+	case cmdReservationGet:
+		ip, _ := cmd.Args[keaFieldIPAddress].(string)
+		sid, _ := asInt(cmd.Args[keaFieldSubnetID])
+		for _, h := range f.hosts {
+			if _, hsid := hostKey(h); hsid == sid && h[keaFieldIPAddress] == ip {
+				return keamodels.Response{Result: 0, Arguments: h}, nil
+			}
+		}
+		return keamodels.Response{Result: 3, Text: "Host not found."}, nil
+	case cmdReservationGetAll:
+		sid, _ := asInt(cmd.Args[keaFieldSubnetID])
+		var found []any
+		for _, h := range f.hosts {
+			if _, hsid := hostKey(h); hsid == sid {
+				found = append(found, h)
+			}
+		}
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"hosts": found}}, nil
+	case cmdLease4GetAll:
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"leases": f.leases}}, nil
+	case cmdReservationAdd:
+		res, _ := cmd.Args["reservation"].(map[string]any)
+		if f.indexOf(hostKey(res)) >= 0 {
+			return keamodels.Response{Result: 1, Text: "Host already exists."}, nil
+		}
+		f.hosts = append(f.hosts, res)
+		return keamodels.Response{Result: 0}, nil
+	case cmdReservationUpdate:
+		if f.noUpdate {
+			return keamodels.Response{Result: 2, Text: "'reservation-update' command not supported."}, nil
+		}
+		if f.failUpdate {
+			return keamodels.Response{Result: 1, Text: "Unable to update host."}, nil
+		}
+		res, _ := cmd.Args["reservation"].(map[string]any)
+		i := f.indexOf(hostKey(res))
+		if i < 0 {
+			return keamodels.Response{Result: 1, Text: "Host not found."}, nil
+		}
+		f.hosts[i] = res
+		return keamodels.Response{Result: 0}, nil
+	case cmdReservationDel:
+		id, _ := cmd.Args[keaFieldIdentifier].(string)
+		sid, _ := asInt(cmd.Args[keaFieldSubnetID])
+		if i := f.indexOf(id, sid); i >= 0 {
+			f.hosts = append(f.hosts[:i], f.hosts[i+1:]...)
+		}
+		return keamodels.Response{Result: 0}, nil
+	}
+	return keamodels.Response{Result: 0}, nil
+}
+
+// host returns the reservation for mac in subnet 1, the subnet used throughout these tests.
+func (f *hostsKea) host(mac string) map[string]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i := f.indexOf(mac, 1); i >= 0 {
+		return f.hosts[i]
+	}
+	return nil
+}
+
+func (f *hostsKea) sent(command string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// TestEnsureReservation_UpgradesMACOnlyToLeasedIP verifies that a MAC-only
+// placeholder is rewritten with the leased IP via reservation-update, keeping
+// host attributes the operator doesn't manage.
+func TestEnsureReservation_UpgradesMACOnlyToLeasedIP(t *testing.T) {
+	client := newHostsKea(map[string]any{
+		keaFieldSubnetID:  1,
+		keaFieldHWAddress: testMAC,
+		"hostname":        "node-1",
+	})
+	svc := New(client)
+
+	action, err := svc.EnsureReservation(context.Background(), keamodels.ReservationConfig{MAC: testMAC, SubnetID: 1, IPAddress: testLeaseIP})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if action != ReservationUpdated {
+		t.Fatalf("expected ReservationUpdated, got %v", action)
+	}
+	h := client.host(testMAC)
+	if h[keaFieldIPAddress] != testLeaseIP {
+		t.Fatalf("expected reservation pinned to %s, got %v", testLeaseIP, h[keaFieldIPAddress])
+	}
+	if h["hostname"] != "node-1" {
+		t.Fatalf("expected hostname to be preserved, got %v", h["hostname"])
+	}
+}
+
+// TestEnsureReservation_UpgradeFallsBackToDelAdd verifies the del+add path on
+// Kea versions without reservation-update.
+func TestEnsureReservation_UpgradeFallsBackToDelAdd(t *testing.T) {
+	client := newHostsKea(map[string]any{keaFieldSubnetID: 1, keaFieldHWAddress: testMAC})
+	client.noUpdate = true
+	svc := New(client)
+
+	action, err := svc.EnsureReservation(context.Background(), keamodels.ReservationConfig{MAC: testMAC, SubnetID: 1, IPAddress: testLeaseIP})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if action != ReservationUpdated {
+		t.Fatalf("expected ReservationUpdated, got %v", action)
+	}
+	if !client.sent(cmdReservationDel) {
+		t.Fatalf("expected reservation-del in fallback path")
+	}
+	if got := client.host(testMAC)[keaFieldIPAddress]; got != testLeaseIP {
+		t.Fatalf("expected reservation pinned to %s, got %v", testLeaseIP, got)
+	}
+}
+
+// TestEnsureReservation_UpdateErrorNoFallback verifies that a failed
+// reservation-update on a Kea that supports it is returned rather than
+// retried as del+add.
+func TestEnsureReservation_UpdateErrorNoFallback(t *testing.T) {
+	client := newHostsKea(map[string]any{keaFieldSubnetID: 1, keaFieldHWAddress: testMAC})
+	client.failUpdate = true
+	svc := New(client)
+
+	if _, err := svc.EnsureReservation(context.Background(), keamodels.ReservationConfig{MAC: testMAC, SubnetID: 1, IPAddress: testLeaseIP}); err == nil || !strings.Contains(err.Error(), "Unable to update host") {
+		t.Fatalf("expected the reservation-update error, got %v", err)
+	}
+	if client.sent(cmdReservationDel) || client.sent(cmdReservationAdd) {
+		t.Fatalf("expected no del+add fallback, got %v", client.commands)
+	}
+	if h := client.host(testMAC); h == nil || h[keaFieldIPAddress] != nil {
+		t.Fatalf("expected the reservation left as it was, got %v", h)
+	}
+}
+
+// TestEnsureReservation_NoDowngradeWithoutIP verifies that an empty desired IP
+// leaves an IP-pinned reservation untouched.
+func TestEnsureReservation_NoDowngradeWithoutIP(t *testing.T) {
+	client := newHostsKea(map[string]any{
+		keaFieldSubnetID:  1,
+		keaFieldHWAddress: testMAC,
+		keaFieldIPAddress: testLeaseIP,
+	})
+	svc := New(client)
+
+	action, err := svc.EnsureReservation(context.Background(), keamodels.ReservationConfig{MAC: strings.ToUpper(testMAC), SubnetID: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if action != ReservationUnchanged {
+		t.Fatalf("expected ReservationUnchanged, got %v", action)
+	}
+	if client.sent(cmdReservationUpdate) || client.sent(cmdReservationDel) {
+		t.Fatalf("expected no write commands, got %v", client.commands)
+	}
+}
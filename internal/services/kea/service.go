@@ -2,7 +2,10 @@ package kea
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
 
@@ -20,12 +23,26 @@ const (
 	keaFieldIPAddress      = "ip-address"
 	keaFieldIdentifier     = "identifier"
 	keaFieldIdentifierType = "identifier-type"
+	keaFieldUserContext    = "user-context"
+)
+
+// user-context keys the operator writes on the Kea objects it creates, so
+// ownership can be reported and operator-managed objects told apart from ones
+// configured by hand.
+const (
+	userContextOwner     = "owner"
+	userContextManagedBy = "managed-by"
+	managedByValue       = "kea-operator"
 )
 
 // Service wraps Kea operations used by the controller.
 type Service struct {
 	Client keainterface.KeaClient
 
+	// Client6, when set, receives the kea-dhcp6 commands directly instead of
+	// Client routing them through the Control Agent.
+	Client6 keainterface.KeaClient
+
 	// subnetLocks serializes GetOrCreateSubnet for the same subnet CIDR within
 	// this process. Multiple NetworkConfigurations that share a NetworkNamespace
 	// prefix reconcile concurrently — the workqueue only serializes per object
@@ -33,12 +50,37 @@ type Service struct {
 	// same prefix. Keyed by CIDR; the number of entries is bounded by the number
 	// of distinct subnets.
 	subnetLocks sync.Map // map[string]*sync.Mutex
+
+	// allocLocks serializes AllocateReservation per subnet-id so concurrent
+	// reconciles never pick the same free address. Keyed by subnet-id.
+	allocLocks sync.Map // map[int]*sync.Mutex
 }
 
 func New(client keainterface.KeaClient) *Service {
 	return &Service{Client: client}
 }
 
+// Peer returns the Kea server that answered the most recent request, when the
+// client can tell, or "".
+func (s *Service) Peer() string {
+	if pr, ok := s.Client.(keainterface.PeerReporter); ok {
+		return pr.CurrentPeer()
+	}
+	return ""
+}
+
+// Peer6 returns the kea-dhcp6 server that answered the most recent request,
+// when the client can tell, or "".
+func (s *Service) Peer6() string {
+	if s.Client6 == nil {
+		return s.Peer()
+	}
+	if pr, ok := s.Client6.(keainterface.PeerReporter); ok {
+		return pr.CurrentPeer()
+	}
+	return ""
+}
+
 // subnetLock returns the per-CIDR mutex used to serialize subnet get-or-create.
 func (s *Service) subnetLock(cidr string) *sync.Mutex {
 	m, _ := s.subnetLocks.LoadOrStore(cidr, &sync.Mutex{})
@@ -79,40 +121,36 @@ func (s *Service) CreateSubnet(ctx context.Context, cfg keamodels.SubnetConfig)
 		subnet4["rebind-timer"] = cfg.RebindTimer
 	}
 
-	// Build pools if start and end are specified
-	if cfg.PoolStart != "" && cfg.PoolEnd != "" {
-		pool := map[string]any{
-			"pool": fmt.Sprintf("%s - %s", cfg.PoolStart, cfg.PoolEnd),
-		}
-		if len(cfg.RequireClientClasses) > 0 {
-			pool["require-client-classes"] = cfg.RequireClientClasses
+	// Build pools from the explicit list, or from start and end if specified
+	poolRanges := cfg.Pools
+	if len(poolRanges) == 0 && cfg.PoolStart != "" && cfg.PoolEnd != "" {
+		poolRanges = []string{fmt.Sprintf("%s - %s", cfg.PoolStart, cfg.PoolEnd)}
+	}
+	if len(poolRanges) > 0 {
+		pools := make([]map[string]any, 0, len(poolRanges))
+		for _, r := range poolRanges {
+			pool := map[string]any{"pool": r}
+			if len(cfg.RequireClientClasses) > 0 {
+				pool["require-client-classes"] = cfg.RequireClientClasses
+			}
+			pools = append(pools, pool)
 		}
-		subnet4["pools"] = []map[string]any{pool}
+		subnet4["pools"] = pools
 	}
 
-	// Build option-data for gateway and DNS
-	var optionData []map[string]any
-
-	if cfg.Gateway != "" {
-		optionData = append(optionData, map[string]any{
-			"name": "routers",
-			"code": 3,
-			"data": cfg.Gateway,
-		})
+	if optionData := subnetOptionData(cfg); len(optionData) > 0 {
+		subnet4["option-data"] = optionData
 	}
 
-	if len(cfg.DNS) > 0 {
-		optionData = append(optionData, map[string]any{
-			"name": "domain-name-servers",
-			"code": 6,
-			"data": strings.Join(cfg.DNS, ", "),
-		})
+	if len(cfg.RelayAddresses) > 0 {
+		subnet4[subnetFieldRelay] = map[string]any{relayFieldIPAddresses: cfg.RelayAddresses}
 	}
-
-	if len(optionData) > 0 {
-		subnet4["option-data"] = optionData
+	if cfg.Interface != "" {
+		subnet4[subnetFieldInterface] = cfg.Interface
 	}
 
+	subnet4[keaFieldUserContext] = map[string]any{userContextManagedBy: managedByValue}
+
 	req := keamodels.Request{
 		Command: "subnet4-add",
 		Args: map[string]any{
@@ -120,7 +158,7 @@ func (s *Service) CreateSubnet(ctx context.Context, cfg keamodels.SubnetConfig)
 		},
 	}
 
-	resp, err := s.Client.Send(ctx, req)
+	resp, err := s.send(ctx, req)
 	if err != nil {
 		return 0, fmt.Errorf("failed to send subnet4-add request: %w", err)
 	}
@@ -136,7 +174,7 @@ func (s *Service) CreateSubnet(ctx context.Context, cfg keamodels.SubnetConfig)
 // getNextSubnetID finds the next available subnet ID by listing existing subnets
 func (s *Service) getNextSubnetID(ctx context.Context) int {
 	req := keamodels.Request{Command: "subnet4-list", Args: map[string]any{}}
-	resp, err := s.Client.Send(ctx, req)
+	resp, err := s.send(ctx, req)
 	if err != nil {
 		return 1 // If we can't list, start with ID 1
 	}
@@ -212,7 +250,7 @@ func (s *Service) GetOrCreateSubnet(ctx context.Context, cfg keamodels.SubnetCon
 // GetSubnetID lists Kea subnets and returns the id of the subnet matching the given IPv4 CIDR prefix.
 func (s *Service) GetSubnetID(ctx context.Context, ipv4Prefix string) (int, error) {
 	req := keamodels.Request{Command: "subnet4-list", Args: map[string]any{}}
-	resp, err := s.Client.Send(ctx, req)
+	resp, err := s.send(ctx, req)
 	if err != nil {
 		return 0, err
 	}
@@ -274,6 +312,60 @@ type SubnetInfo struct {
 	Subnet  string
 	Gateway string
 	DNS     []string
+	// Managed is set for subnets created by the operator (user-context
+	// managed-by). Only filled by GetSubnetInfo.
+	Managed bool
+	// Options is the subnet's option-data. Only filled by GetSubnetInfo.
+	Options []keamodels.OptionData
+	// ValidLife, RenewTimer and RebindTimer are the subnet's lease times in
+	// seconds, 0 when inherited from the global configuration. Only filled by
+	// GetSubnetInfo.
+	ValidLife   int
+	RenewTimer  int
+	RebindTimer int
+	// Pools are the subnet's address pools. Only filled by GetSubnetInfo.
+	Pools []SubnetPool
+	// RelayAddresses and Interface select the subnet for relayed and directly
+	// attached clients. Only filled by GetSubnetInfo.
+	RelayAddresses []string
+	Interface      string
+}
+
+// SubnetPool is an address pool of a subnet and the client classes it
+// requires.
+type SubnetPool struct {
+	Range                string
+	RequireClientClasses []string
+}
+
+// ListSubnets returns the id and prefix of every IPv4 subnet via subnet4-list.
+func (s *Service) ListSubnets(ctx context.Context) ([]SubnetInfo, error) {
+	resp, err := s.send(ctx, keamodels.Request{Command: "subnet4-list", Args: map[string]any{}})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.Result {
+	case 0:
+	case 3: // empty: no subnets configured
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("kea subnet4-list failed: %s", resp.Text)
+	}
+	list, _ := resp.Arguments["subnets"].([]any)
+	out := make([]SubnetInfo, 0, len(list))
+	for _, snet := range list {
+		m, ok := snet.(map[string]any)
+		if !ok {
+			continue
+		}
+		info := SubnetInfo{}
+		info.ID, _ = asInt(m["id"])
+		info.Subnet, _ = m["subnet"].(string)
+		if info.Subnet != "" {
+			out = append(out, info)
+		}
+	}
+	return out, nil
 }
 
 // GetSubnetInfo retrieves detailed subnet information including gateway and DNS servers
@@ -282,7 +374,7 @@ func (s *Service) GetSubnetInfo(ctx context.Context, subnetID int) (*SubnetInfo,
 		Command: "subnet4-get",
 		Args:    map[string]any{"id": subnetID},
 	}
-	resp, err := s.Client.Send(ctx, req)
+	resp, err := s.send(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -302,6 +394,9 @@ func (s *Service) GetSubnetInfo(ctx context.Context, subnetID int) (*SubnetInfo,
 	}
 
 	info := &SubnetInfo{ID: subnetID}
+	if uc, ok := subnetData[keaFieldUserContext].(map[string]any); ok {
+		info.Managed = uc[userContextManagedBy] == managedByValue
+	}
 
 	// Extract subnet CIDR
 	if subnet, ok := subnetData["subnet"].(string); ok {
@@ -312,51 +407,23 @@ func (s *Service) GetSubnetInfo(ctx context.Context, subnetID int) (*SubnetInfo,
 		}
 	}
 
-	// Extract option-data for gateway (router) and DNS
+	// Extract option-data; routers and domain-name-servers also fill Gateway and DNS
 	if optionData, ok := subnetData["option-data"].([]any); ok {
-		for _, opt := range optionData {
-			optMap, ok := opt.(map[string]any)
+		for _, raw := range optionData {
+			opt, ok := parseOptionData(raw)
 			if !ok {
 				continue
 			}
-
-			var code float64
-			var data string
-
-			// Extract code
-			switch c := optMap["code"].(type) {
-			case float64:
-				code = c
-			case int:
-				code = float64(c)
-			case *any:
-				if c != nil {
-					if f, ok := (*c).(float64); ok {
-						code = f
-					} else if i, ok := (*c).(int); ok {
-						code = float64(i)
-					}
-				}
-			}
-
-			// Extract data
-			switch d := optMap["data"].(type) {
-			case string:
-				data = d
-			case *any:
-				if d != nil {
-					if str, ok := (*d).(string); ok {
-						data = str
-					}
-				}
+			info.Options = append(info.Options, opt)
+			if opt.Data == "" {
+				continue
 			}
-
-			// Code 3 = router (gateway), Code 6 = DNS servers
-			if code == 3 && data != "" {
-				info.Gateway = data
-			} else if code == 6 && data != "" {
+			switch opt.Code {
+			case optionCodeRouters:
+				info.Gateway = opt.Data
+			case optionCodeDomainNameServers:
 				// DNS can be comma-separated
-				for dns := range strings.SplitSeq(data, ",") {
+				for dns := range strings.SplitSeq(opt.Data, ",") {
 					dns = strings.TrimSpace(dns)
 					if dns != "" {
 						info.DNS = append(info.DNS, dns)
@@ -365,52 +432,145 @@ func (s *Service) GetSubnetInfo(ctx context.Context, subnetID int) (*SubnetInfo,
 			}
 		}
 	}
+	parseSubnetParams(info, subnetData)
 
 	return info, nil
 }
 
-// DeleteReservationForMAC removes a reservation for the given MAC and subnet.
-func (s *Service) DeleteReservationForMAC(ctx context.Context, mac string, subnetID int) error {
-	mac = strings.ToLower(strings.TrimSpace(mac))
-	if mac == "" {
-		return fmt.Errorf("missing mac")
+// DeleteReservation removes the reservation keyed on the identifier of cfg
+// (see EnsureReservation) in cfg.SubnetID.
+func (s *Service) DeleteReservation(ctx context.Context, cfg keamodels.ReservationConfig) error {
+	idType, id, err := host4Identifier(cfg)
+	if err != nil {
+		return err
 	}
 	delReq := keamodels.Request{
 		Command: "reservation-del",
 		Args: map[string]any{
-			keaFieldSubnetID:       subnetID,
-			keaFieldIdentifierType: keaFieldHWAddress,
-			keaFieldIdentifier:     mac,
+			keaFieldSubnetID:       cfg.SubnetID,
+			keaFieldIdentifierType: idType,
+			keaFieldIdentifier:     id,
 			"operation-target":     "all",
 		},
 	}
-	resp, err := s.Client.Send(ctx, delReq)
+	resp, err := s.send(ctx, delReq)
 	if err != nil {
 		return err
 	}
-	if resp.Result != 0 {
+	// Result 3 means there was nothing to delete.
+	if resp.Result != 0 && resp.Result != 3 {
 		return fmt.Errorf("kea reservation-del failed: %s", resp.Text)
 	}
 	return nil
 }
 
-// EnsureReservationForMACIP ensures a reservation exists for mac in the given subnet, with optional ip.
-// Returns (created bool, err error) where created=true if a new reservation was added, false if it already existed.
-func (s *Service) EnsureReservationForMACIP(ctx context.Context, mac string, subnetID int, ipv4 string) (bool, error) {
-	mac = strings.ToLower(strings.TrimSpace(mac))
-	if mac == "" {
-		return false, fmt.Errorf("missing mac")
-	}
-	if s.macReservationExists(ctx, mac, subnetID) {
-		return false, nil // already exists, nothing created
+// ReservationAction reports what EnsureReservation changed in Kea.
+type ReservationAction int
+
+const (
+	// ReservationUnchanged means a matching reservation already existed.
+	ReservationUnchanged ReservationAction = iota
+	// ReservationCreated means a new reservation was added.
+	ReservationCreated
+	// ReservationUpdated means an existing reservation was rewritten, e.g. a
+	// MAC-only placeholder was pinned to the IP the host has since leased.
+	ReservationUpdated
+	// ReservationHostUpdated means only the host settings (hostname, boot
+	// parameters, client classes, option-data) or the owner of an existing
+	// reservation were rewritten.
+	ReservationHostUpdated
+)
+
+// hostReservation is the subset of a Kea host record the service acts on.
+// raw keeps the full record so an update can carry over fields we don't manage.
+type hostReservation struct {
+	SubnetID  int
+	HWAddress string
+	IPAddress string
+	// IdentifierType and Identifier are what the host is keyed on, the
+	// identifier in NormalizeIdentifier form.
+	IdentifierType string
+	Identifier     string
+	// Owner is the owning resource recorded in the host's user-context, if any.
+	Owner string
+	raw   map[string]any
+}
+
+// preservedHostFields are host attributes copied from the existing record when
+// a reservation is rewritten, so an IP upgrade doesn't drop them.
+var preservedHostFields = []string{
+	hostFieldHostname,
+	hostFieldClientClasses,
+	hostFieldOptionData,
+	hostFieldNextServer,
+	hostFieldServerHostname,
+	hostFieldBootFileName,
+	keaFieldUserContext,
+}
+
+// EnsureReservation ensures a reservation exists for cfg.MAC in cfg.SubnetID, with optional IP.
+// The reservation is keyed on cfg.IdentifierType and cfg.Identifier, the MAC (hw-address) by
+// default; see host4Identifier. When a reservation already exists with a different (or no)
+// ip-address and an IP is requested, the reservation is rewritten so MAC-only placeholders
+// become fixed-address reservations. An empty IP never downgrades an existing IP-pinned
+// reservation. Before writing, the IP and identifier are checked against reservations held by
+// others; a clash is returned as *ReservationConflictError. The fields of cfg.Host are kept in
+// sync on the reservation; host fields set by hand are only replaced by fields cfg.Host sets.
+func (s *Service) EnsureReservation(ctx context.Context, cfg keamodels.ReservationConfig) (ReservationAction, error) {
+	idType, id, err := host4Identifier(cfg)
+	if err != nil {
+		return ReservationUnchanged, err
 	}
+	mac := strings.ToLower(strings.TrimSpace(cfg.MAC))
+	ip := strings.TrimSpace(cfg.IPAddress)
 	reservation := map[string]any{
-		keaFieldSubnetID:  subnetID,
-		keaFieldHWAddress: mac,
+		keaFieldSubnetID: cfg.SubnetID,
+		idType:           id,
 	}
-	if ip := strings.TrimSpace(ipv4); ip != "" {
+	if ip != "" {
 		reservation[keaFieldIPAddress] = ip
 	}
+	hostFields := hostFieldArgs(cfg.Host)
+	maps.Copy(reservation, hostFields)
+	if uc := hostUserContext(cfg.Owner, hostFields); uc != nil {
+		reservation[keaFieldUserContext] = uc
+	}
+
+	hosts := s.findReservations(ctx, idType, id, cfg.SubnetID)
+	existing := hostInSubnet(hosts, cfg.SubnetID)
+	if existing != nil && (ip == "" || existing.IPAddress == ip) {
+		if hostFieldsInSync(existing, cfg.Host, cfg.Owner) {
+			return ReservationUnchanged, nil // already exists, nothing to change
+		}
+		// Only the host settings or owner changed; the address stays as it is.
+		if existing.IPAddress != "" {
+			reservation[keaFieldIPAddress] = existing.IPAddress
+		}
+		if err := s.updateReservation(ctx, existing, reservation); err != nil {
+			return ReservationUnchanged, err
+		}
+		return ReservationHostUpdated, nil
+	}
+
+	if err := s.checkReservationConflicts(ctx, reservationKey{mac, idType, id}, cfg.SubnetID, ip, cfg.Owner, cfg.RecordedSubnetIDs, hosts); err != nil {
+		return ReservationUnchanged, err
+	}
+
+	if existing != nil {
+		if err := s.updateReservation(ctx, existing, reservation); err != nil {
+			return ReservationUnchanged, err
+		}
+		return ReservationUpdated, nil
+	}
+
+	if err := s.addReservation(ctx, reservation); err != nil {
+		return ReservationUnchanged, err
+	}
+	return ReservationCreated, nil // new reservation created
+}
+
+// addReservation issues reservation-add for the given host record.
+func (s *Service) addReservation(ctx context.Context, reservation map[string]any) error {
 	addReq := keamodels.Request{
 		Command: "reservation-add",
 		Args: map[string]any{
@@ -418,168 +578,246 @@ func (s *Service) EnsureReservationForMACIP(ctx context.Context, mac string, sub
 			"operation-target": "all",
 		},
 	}
-	addResp, addErr := s.Client.Send(ctx, addReq)
+	addResp, addErr := s.send(ctx, addReq)
 	if addErr != nil {
-		return false, addErr
+		return addErr
 	}
 	if addResp.Result != 0 {
-		return false, fmt.Errorf("kea reservation-add failed: %s", addResp.Text)
+		return fmt.Errorf("kea reservation-add failed: %s", addResp.Text)
+	}
+	return nil
+}
+
+// updateReservation rewrites an existing host record. It prefers the atomic
+// reservation-update command and falls back to reservation-del followed by
+// reservation-add only when Kea answers that it does not support it (result
+// 2); any other failure is returned. If the fallback add fails, the previous
+// record is restored so the host isn't left without any reservation. Host
+// fields the operator set on the existing record are not carried over, so
+// fields it no longer sets are removed.
+func (s *Service) updateReservation(ctx context.Context, existing *hostReservation, reservation map[string]any) error {
+	managed := existing.managedHostFields()
+	for _, field := range preservedHostFields {
+		v, ok := existing.raw[field]
+		if !ok {
+			continue
+		}
+		if field == keaFieldUserContext {
+			uc, _ := reservation[field].(map[string]any)
+			reservation[field] = mergeUserContext(v, uc)
+			continue
+		}
+		if _, set := reservation[field]; !set && !slices.Contains(managed, field) {
+			reservation[field] = v
+		}
+	}
+
+	updReq := keamodels.Request{
+		Command: "reservation-update",
+		Args: map[string]any{
+			"reservation":      reservation,
+			"operation-target": "all",
+		},
 	}
-	return true, nil // new reservation created
+	resp, err := s.send(ctx, updReq)
+	if err != nil {
+		return err
+	}
+	switch resp.Result {
+	case 0:
+		return nil
+	case 2: // command not supported: fall back to del + add
+	default:
+		return fmt.Errorf("kea reservation-update failed: %s", resp.Text)
+	}
+
+	if err := s.DeleteReservation(ctx, existing.config()); err != nil {
+		return fmt.Errorf("kea reservation update fallback: %w", err)
+	}
+	if addErr := s.addReservation(ctx, reservation); addErr != nil {
+		return updateFallbackError(addErr, s.addReservation(ctx, existing.raw))
+	}
+	return nil
 }
 
-// macReservationExists checks whether a reservation already exists for the given MAC + subnet.
-func (s *Service) macReservationExists(ctx context.Context, mac string, subnetID int) bool {
-	mac = strings.ToLower(strings.TrimSpace(mac))
-	if mac == "" {
-		return false
+// updateFallbackError reports a del + add fallback whose add failed, along
+// with the failure to restore the previous record, if any.
+func updateFallbackError(addErr, restoreErr error) error {
+	if restoreErr != nil {
+		restoreErr = fmt.Errorf("restoring the previous reservation: %w", restoreErr)
+	}
+	return fmt.Errorf("kea reservation update fallback: %w", errors.Join(addErr, restoreErr))
+}
+
+// findReservations returns the reservations keyed on the identifier id of
+// type idType. The primary lookup (reservation-get-by-id) spans all subnets;
+// the reservation-get-all fallback is scoped to subnetID. Returns nil when none
+// exist or Kea can't be queried.
+func (s *Service) findReservations(ctx context.Context, idType, id string, subnetID int) []hostReservation {
+	if id == "" {
+		return nil
 	}
 
 	// 1. Primary: reservation-get-by-id (identifier-type + identifier) => hosts list
 	primary := keamodels.Request{
 		Command: "reservation-get-by-id",
 		Args: map[string]any{
-			keaFieldIdentifierType: keaFieldHWAddress,
-			keaFieldIdentifier:     mac,
+			keaFieldIdentifierType: idType,
+			keaFieldIdentifier:     id,
 		},
 	}
-	if resp, err := s.Client.Send(ctx, primary); err == nil {
+	if resp, err := s.send(ctx, primary); err == nil {
 		if resp.Result == 0 { // success path returns hosts array
-			if hosts, ok := resp.Arguments["hosts"].([]any); ok {
-				for _, h := range hosts {
-					hm, ok := h.(map[string]any)
-					if !ok {
-						continue
-					}
-					if hw, ok2 := hm[keaFieldHWAddress].(string); ok2 && strings.EqualFold(hw, mac) {
-						if sid, ok3 := hm[keaFieldSubnetID]; ok3 {
-							switch v := sid.(type) {
-							case float64:
-								if int(v) != subnetID {
-									continue
-								}
-							case int:
-								if v != subnetID {
-									continue
-								}
-							}
-						}
-						return true
-					}
-				}
-			}
-			return false
+			return matchHostReservations(resp.Arguments["hosts"], idType, id, 0)
 		}
 		txt := strings.ToLower(resp.Text)
 		if strings.Contains(txt, "not found") || strings.Contains(txt, "no host") || strings.Contains(txt, "0 ipv4 host") {
-			return false
+			return nil
 		}
 	}
 
 	// 2. Fallback: reservation-get-all (scan hosts list for match)
 	fallback := keamodels.Request{Command: "reservation-get-all", Args: map[string]any{keaFieldSubnetID: subnetID}}
-	resp2, err2 := s.Client.Send(ctx, fallback)
+	resp2, err2 := s.send(ctx, fallback)
 	if err2 != nil || resp2.Result != 0 {
-		return false
+		return nil
 	}
-	if hosts, ok := resp2.Arguments["hosts"].([]any); ok {
-		for _, h := range hosts {
-			hm, ok := h.(map[string]any)
-			if !ok {
-				continue
-			}
-			if hw, ok2 := hm[keaFieldHWAddress].(string); ok2 && strings.EqualFold(hw, mac) {
-				return true
-			}
+	// reservation-get-all is already scoped to the subnet; records may omit subnet-id.
+	return matchHostReservations(resp2.Arguments["hosts"], idType, id, subnetID)
+}
+
+// ReservationInfo is a DHCPv4 reservation found in Kea.
+type ReservationInfo struct {
+	SubnetID  int
+	IPAddress string
+	// Owner is the owner recorded in its user-context, if any.
+	Owner string
+}
+
+// FindReservations returns the DHCPv4 reservations of the host cfg
+// identifies, in every subnet, via reservation-get-by-id. cfg.SubnetID is not
+// used. Unlike the lookups EnsureReservation makes, a failed query is an error.
+func (s *Service) FindReservations(ctx context.Context, cfg keamodels.ReservationConfig) ([]ReservationInfo, error) {
+	idType, id, err := host4Identifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: "reservation-get-by-id",
+		Args:    map[string]any{keaFieldIdentifierType: idType, keaFieldIdentifier: id},
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.Result {
+	case 0:
+	case 3: // empty: no reservations for this host
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("kea reservation-get-by-id failed: %s", resp.Text)
+	}
+	hosts := matchHostReservations(resp.Arguments["hosts"], idType, id, 0)
+	out := make([]ReservationInfo, 0, len(hosts))
+	for _, h := range hosts {
+		out = append(out, ReservationInfo{SubnetID: h.SubnetID, IPAddress: h.IPAddress, Owner: h.Owner})
+	}
+	return out, nil
+}
+
+// hostInSubnet returns the reservation in hosts that belongs to subnetID, or nil.
+func hostInSubnet(hosts []hostReservation, subnetID int) *hostReservation {
+	for i := range hosts {
+		if hosts[i].SubnetID == subnetID {
+			return &hosts[i]
 		}
 	}
-	return false
+	return nil
 }
 
-// GetLeaseIPv4ForMAC tries to resolve an IPv4 lease for the given MAC.
-// Returns ip, subnet-id (if available), error
-func (s *Service) GetLeaseIPv4ForMAC(ctx context.Context, mac string) (string, int, error) {
-	mac = strings.ToLower(strings.TrimSpace(mac))
-	if mac == "" {
-		return "", 0, fmt.Errorf("missing mac")
+// matchHostReservations returns the records in a Kea hosts list keyed on the
+// identifier id of type idType. defaultSubnetID is used for records that
+// don't carry a subnet-id.
+func matchHostReservations(hosts any, idType, id string, defaultSubnetID int) []hostReservation {
+	list, ok := hosts.([]any)
+	if !ok {
+		return nil
 	}
-	primary := keamodels.Request{
-		Command: "lease4-get-by-hw-address",
-		Args:    map[string]any{keaFieldHWAddress: mac},
-	}
-	if resp, err := s.Client.Send(ctx, primary); err == nil {
-		if resp.Result == 0 {
-			// Kea can return leases as an array; pick the newest (largest cltt) that matches the MAC.
-			if arr, ok := resp.Arguments["leases"].([]any); ok {
-				bestIP := ""
-				bestSID := 0
-				var bestCLTT float64
-				for _, elem := range arr {
-					m, ok := elem.(map[string]any)
-					if !ok {
-						continue
-					}
-					hw, _ := m[keaFieldHWAddress].(string)
-					if !strings.EqualFold(strings.TrimSpace(hw), mac) {
-						// Be defensive in case server returns extra entries
-						continue
-					}
-					ip, _ := m[keaFieldIPAddress].(string)
-					if ip == "" {
-						continue
-					}
-					// Prefer the highest cltt (most recent)
-					cltt := 0.0
-					switch v := m["cltt"].(type) {
-					case float64:
-						cltt = v
-					case int:
-						cltt = float64(v)
-					}
-					sid := 0
-					switch v := m[keaFieldSubnetID].(type) {
-					case float64:
-						sid = int(v)
-					case int:
-						sid = v
-					}
-					if bestIP == "" || cltt > bestCLTT {
-						bestIP = ip
-						bestSID = sid
-						bestCLTT = cltt
-					}
-				}
-				if bestIP != "" {
-					return bestIP, bestSID, nil
-				}
-			} else if l, ok := resp.Arguments["leases"].(map[string]any); ok {
-				// Some deployments might return a single lease object; keep legacy support.
-				ip := ""
-				if v, ok2 := l[keaFieldIPAddress].(string); ok2 {
-					ip = v
-				}
-				sid := 0
-				if v, ok2 := l[keaFieldSubnetID].(float64); ok2 {
-					sid = int(v)
-				} else if v2, ok3 := l[keaFieldSubnetID].(int); ok3 {
-					sid = v2
-				}
-				if ip != "" {
-					return ip, sid, nil
-				}
+	var out []hostReservation
+	for _, h := range list {
+		hm, ok := h.(map[string]any)
+		if !ok {
+			continue
+		}
+		h := toHostReservation(hm, defaultSubnetID)
+		if h.IdentifierType != idType || h.Identifier != id {
+			continue
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// toHostReservation converts a Kea host record into a hostReservation.
+func toHostReservation(hm map[string]any, defaultSubnetID int) hostReservation {
+	h := hostReservation{SubnetID: defaultSubnetID, raw: hm}
+	if v, ok := asInt(hm[keaFieldSubnetID]); ok {
+		h.SubnetID = v
+	}
+	hw, _ := hm[keaFieldHWAddress].(string)
+	h.HWAddress = strings.ToLower(hw)
+	for _, t := range hostIdentifierTypes {
+		if v, ok := hm[t].(string); ok && v != "" {
+			h.IdentifierType, h.Identifier = t, strings.ToLower(v)
+			if n, err := NormalizeIdentifier(v); err == nil {
+				h.Identifier = n
 			}
+			break
 		}
 	}
+	h.IPAddress, _ = hm[keaFieldIPAddress].(string)
+	if uc, ok := hm[keaFieldUserContext].(map[string]any); ok {
+		h.Owner, _ = uc[userContextOwner].(string)
+	}
+	return h
+}
+
+// config returns the ReservationConfig that addresses h.
+func (h *hostReservation) config() keamodels.ReservationConfig {
+	return keamodels.ReservationConfig{MAC: h.HWAddress, SubnetID: h.SubnetID, IdentifierType: h.IdentifierType, Identifier: h.Identifier}
+}
 
+// asInt converts a JSON-decoded numeric value to int. Kea responses decode
+// numbers as float64; test fakes and locally built maps use int.
+func asInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int(n), true
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	}
+	return 0, false
+}
+
+// GetReservedIPv4 returns the address of the host reservation keyed on the
+// identifier of cfg (see EnsureReservation) via reservation-get-by-id, for
+// hosts that hold no lease.
+// Returns ip, subnet-id (if available), error
+func (s *Service) GetReservedIPv4(ctx context.Context, cfg keamodels.ReservationConfig) (string, int, error) {
+	idType, id, err := host4Identifier(cfg)
+	if err != nil {
+		return "", 0, err
+	}
 	// Fallback: reservation-get-by-id for any stored address
 	fb := keamodels.Request{
 		Command: "reservation-get-by-id",
 		Args: map[string]any{
-			keaFieldIdentifierType: keaFieldHWAddress,
-			keaFieldIdentifier:     mac,
+			keaFieldIdentifierType: idType,
+			keaFieldIdentifier:     id,
 		},
 	}
-	if resp, err := s.Client.Send(ctx, fb); err == nil && resp.Result == 0 {
+	if resp, err := s.send(ctx, fb); err == nil && resp.Result == 0 {
 		if hosts, ok := resp.Arguments["hosts"].([]any); ok {
 			for _, h := range hosts {
 				hm, ok := h.(map[string]any)
@@ -600,5 +838,5 @@ func (s *Service) GetLeaseIPv4ForMAC(ctx context.Context, mac string) (string, i
 	}
 	// Not finding a lease is not necessarily an error - the machine might not have booted yet
 	// or the lease may have expired. Return empty values to let caller decide how to handle.
-	return "", 0, fmt.Errorf("no lease found for MAC %s", mac)
+	return "", 0, fmt.Errorf("no lease found for %s", describeIdentifier(idType, id))
 }
@@ -17,7 +17,7 @@ func (f fakeKeaClient) Send(ctx context.Context, cmd keamodels.Request) (keamode
 	return f.resp, f.err
 }
 
-func TestGetLeaseIPv4ForMAC_ArrayResponse_PicksLatest(t *testing.T) {
+func TestGetLeaseForMAC_ArrayResponse_PicksLatest(t *testing.T) {
 	mac := "00:02:12:34:56:78"
 	client := fakeKeaClient{resp: keamodels.Response{
 		Result: 0,
@@ -39,16 +39,17 @@ func TestGetLeaseIPv4ForMAC_ArrayResponse_PicksLatest(t *testing.T) {
 		},
 	}}
 	service := &Service{Client: keainterface.KeaClient(client)}
-	ip, sid, err := service.GetLeaseIPv4ForMAC(context.Background(), mac)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	lease, err := service.GetLeaseForMAC(context.Background(), mac)
+	if err != nil || lease == nil {
+		t.Fatalf("unexpected lease/error: %v/%v", lease, err)
 	}
+	ip, sid := lease.IPAddress, lease.SubnetID
 	if ip != "10.123.0.123" || sid != 1 {
 		t.Fatalf("unexpected ip/sid: %s/%d", ip, sid)
 	}
 }
 
-func TestGetLeaseIPv4ForMAC_SingleObject(t *testing.T) {
+func TestGetLeaseForMAC_SingleObject(t *testing.T) {
 	mac := "aa:bb:cc:dd:ee:ff"
 	client := fakeKeaClient{resp: keamodels.Response{
 		Result: 0,
@@ -61,10 +62,11 @@ func TestGetLeaseIPv4ForMAC_SingleObject(t *testing.T) {
 		},
 	}}
 	service := &Service{Client: keainterface.KeaClient(client)}
-	ip, sid, err := service.GetLeaseIPv4ForMAC(context.Background(), mac)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	lease, err := service.GetLeaseForMAC(context.Background(), mac)
+	if err != nil || lease == nil {
+		t.Fatalf("unexpected lease/error: %v/%v", lease, err)
 	}
+	ip, sid := lease.IPAddress, lease.SubnetID
 	if ip != "10.123.0.200" || sid != 2 {
 		t.Fatalf("unexpected ip/sid: %s/%d", ip, sid)
 	}
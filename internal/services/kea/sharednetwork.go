@@ -0,0 +1,98 @@
+package kea
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// getSharedNetworkSubnets returns the ids of the subnets in the IPv4 shared
+// network name via network4-get, and whether the network exists.
+func (s *Service) getSharedNetworkSubnets(ctx context.Context, name string) ([]int, bool, error) {
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: "network4-get",
+		Args:    map[string]any{"name": name},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	switch resp.Result {
+	case 0:
+	case 3: // not found
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("kea network4-get failed: %s", resp.Text)
+	}
+	networks, _ := resp.Arguments["shared-networks"].([]any)
+	if len(networks) == 0 {
+		return nil, false, nil
+	}
+	network, ok := networks[0].(map[string]any)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected network4-get response shape")
+	}
+	subnets, _ := network["subnet4"].([]any)
+	ids := make([]int, 0, len(subnets))
+	for _, raw := range subnets {
+		if m, ok := raw.(map[string]any); ok {
+			if id, ok := asInt(m["id"]); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, true, nil
+}
+
+// EnsureSharedNetwork places the IPv4 subnets subnetIDs in the shared network
+// name, creating the network with network4-add when it does not exist and
+// moving each subnet not yet in it there with network4-subnet-add. Kea
+// rejects a subnet that already belongs to another shared network; that
+// error is returned. It reports whether the network was created and which
+// subnets were added.
+func (s *Service) EnsureSharedNetwork(ctx context.Context, name string, subnetIDs []int) (bool, []int, error) {
+	if name == "" {
+		return false, nil, fmt.Errorf("missing shared network name")
+	}
+	members, exists, err := s.getSharedNetworkSubnets(ctx, name)
+	if err != nil {
+		return false, nil, err
+	}
+	created := false
+	if !exists {
+		resp, err := s.send(ctx, keamodels.Request{
+			Command: "network4-add",
+			Args: map[string]any{"shared-networks": []map[string]any{{
+				"name":              name,
+				keaFieldUserContext: map[string]any{userContextManagedBy: managedByValue},
+			}}},
+		})
+		if err != nil {
+			return false, nil, err
+		}
+		if resp.Result != 0 {
+			return false, nil, fmt.Errorf("kea network4-add failed: %s", resp.Text)
+		}
+		created = true
+	}
+
+	var added []int
+	for _, id := range subnetIDs {
+		if slices.Contains(members, id) {
+			continue
+		}
+		resp, err := s.send(ctx, keamodels.Request{
+			Command: "network4-subnet-add",
+			Args:    map[string]any{"name": name, "id": id},
+		})
+		if err != nil {
+			return created, added, err
+		}
+		if resp.Result != 0 {
+			return created, added, fmt.Errorf("kea network4-subnet-add for subnet %d failed: %s", id, resp.Text)
+		}
+		added = append(added, id)
+	}
+	return created, added, nil
+}
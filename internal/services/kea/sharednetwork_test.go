@@ -0,0 +1,72 @@
+package kea
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// networksKea is a minimal shared network fake keyed by network name.
+type networksKea struct {
+	mu       sync.Mutex
+	networks map[string][]int
+	commands []string
+}
+
+func (f *networksKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, cmd.Command)
+	switch cmd.Command {
+	case "network4-get":
+		name, _ := cmd.Args["name"].(string)
+		ids, ok := f.networks[name]
+		if !ok {
+			return keamodels.Response{Result: 3, Text: "no shared network with name '" + name + "' found"}, nil
+		}
+		subnets := make([]any, 0, len(ids))
+		for _, id := range ids {
+			subnets = append(subnets, map[string]any{"id": float64(id)})
+		}
+		return keamodels.Response{Result: 0, Arguments: map[string]any{
+			"shared-networks": []any{map[string]any{"name": name, "subnet4": subnets}},
+		}}, nil
+	case "network4-add":
+		name := cmd.Args["shared-networks"].([]map[string]any)[0]["name"].(string)
+		f.networks[name] = nil
+		return keamodels.Response{Result: 0}, nil
+	case "network4-subnet-add":
+		name, _ := cmd.Args["name"].(string)
+		id, _ := cmd.Args["id"].(int)
+		for other, ids := range f.networks {
+			for _, member := range ids {
+				if member == id {
+					return keamodels.Response{Result: 1, Text: "subnet already in " + other}, nil
+				}
+			}
+		}
+		f.networks[name] = append(f.networks[name], id)
+		return keamodels.Response{Result: 0}, nil
+	}
+	return keamodels.Response{Result: 2, Text: "unsupported command " + cmd.Command}, nil
+}
+
+func TestEnsureSharedNetwork(t *testing.T) {
+	client := &networksKea{networks: map[string][]int{"other": {9}}}
+	svc := New(client)
+	ctx := context.Background()
+
+	created, added, err := svc.EnsureSharedNetwork(ctx, "vlan-120", []int{1, 2})
+	if err != nil || !created || len(added) != 2 {
+		t.Fatalf("expected the network created with both subnets, got %v, %v, %v", created, added, err)
+	}
+	created, added, err = svc.EnsureSharedNetwork(ctx, "vlan-120", []int{1, 2, 3})
+	if err != nil || created || len(added) != 1 || added[0] != 3 {
+		t.Fatalf("expected only subnet 3 added, got %v, %v, %v", created, added, err)
+	}
+	if _, _, err := svc.EnsureSharedNetwork(ctx, "vlan-120", []int{9}); err == nil {
+		t.Fatal("expected an error for a subnet in another shared network")
+	}
+}
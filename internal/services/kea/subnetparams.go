@@ -0,0 +1,140 @@
+package kea
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// Kea subnet4 fields for lease times, pool classes and subnet selection. Kea
+// 3.0 renamed require-client-classes to evaluate-additional-classes; the old
+// name is still accepted and both are read.
+const (
+	subnetFieldValidLifetime     = "valid-lifetime"
+	subnetFieldRenewTimer        = "renew-timer"
+	subnetFieldRebindTimer       = "rebind-timer"
+	subnetFieldPools             = "pools"
+	poolFieldPool                = "pool"
+	poolFieldRequireClasses      = "require-client-classes"
+	poolFieldEvaluateAddlClasses = "evaluate-additional-classes"
+	subnetFieldRelay             = "relay"
+	relayFieldIPAddresses        = "ip-addresses"
+	subnetFieldInterface         = "interface"
+)
+
+// parseSubnetParams fills the lease times, pools, relay addresses and
+// interface of info from a subnet4 entry returned by subnet4-get.
+func parseSubnetParams(info *SubnetInfo, subnet map[string]any) {
+	info.ValidLife, _ = asInt(subnet[subnetFieldValidLifetime])
+	info.RenewTimer, _ = asInt(subnet[subnetFieldRenewTimer])
+	info.RebindTimer, _ = asInt(subnet[subnetFieldRebindTimer])
+	if relay, ok := subnet[subnetFieldRelay].(map[string]any); ok {
+		info.RelayAddresses = stringList(relay[relayFieldIPAddresses])
+	}
+	info.Interface, _ = subnet[subnetFieldInterface].(string)
+	pools, _ := subnet[subnetFieldPools].([]any)
+	for _, raw := range pools {
+		m, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		r, _ := m[poolFieldPool].(string)
+		if r == "" {
+			continue
+		}
+		classes := stringList(m[poolFieldRequireClasses])
+		if c := stringList(m[poolFieldEvaluateAddlClasses]); len(c) > 0 {
+			classes = c
+		}
+		info.Pools = append(info.Pools, SubnetPool{Range: normalizePoolRange(r), RequireClientClasses: classes})
+	}
+}
+
+// normalizePoolRange writes a "start-end" or "start - end" pool in the
+// "start - end" form the operator sends.
+func normalizePoolRange(r string) string {
+	start, end, ok := strings.Cut(r, "-")
+	if !ok {
+		return strings.TrimSpace(r)
+	}
+	return strings.TrimSpace(start) + " - " + strings.TrimSpace(end)
+}
+
+// subnetParamsDelta returns the subnet4 fields of info that differ from cfg:
+// the lease times, relay addresses and interface cfg sets and, for each pool
+// of info, its require-client-classes. Fields cfg leaves unset are not
+// compared.
+func subnetParamsDelta(info *SubnetInfo, cfg keamodels.SubnetConfig) map[string]any {
+	delta := map[string]any{}
+	for _, f := range []struct {
+		field      string
+		want, have int
+	}{
+		{subnetFieldValidLifetime, cfg.ValidLife, info.ValidLife},
+		{subnetFieldRenewTimer, cfg.RenewTimer, info.RenewTimer},
+		{subnetFieldRebindTimer, cfg.RebindTimer, info.RebindTimer},
+	} {
+		if f.want > 0 && f.want != f.have {
+			delta[f.field] = f.want
+		}
+	}
+	if len(cfg.RelayAddresses) > 0 && !slices.Equal(cfg.RelayAddresses, info.RelayAddresses) {
+		delta[subnetFieldRelay] = map[string]any{relayFieldIPAddresses: cfg.RelayAddresses}
+	}
+	if cfg.Interface != "" && cfg.Interface != info.Interface {
+		delta[subnetFieldInterface] = cfg.Interface
+	}
+	var pools []map[string]any
+	for _, p := range info.Pools {
+		if slices.Equal(p.RequireClientClasses, cfg.RequireClientClasses) {
+			continue
+		}
+		classes := cfg.RequireClientClasses
+		if classes == nil {
+			classes = []string{}
+		}
+		pools = append(pools, map[string]any{poolFieldPool: p.Range, poolFieldRequireClasses: classes})
+	}
+	if len(pools) > 0 {
+		delta[subnetFieldPools] = pools
+	}
+	return delta
+}
+
+// UpdateSubnetParams brings the lease times, pool require-client-classes,
+// relay addresses and interface of the existing IPv4 subnet info in line with
+// cfg via subnet4-delta-add, which overwrites the given fields and replaces
+// pools with the same range. Other fields, options and pools are left as they
+// are. It returns the names of the
+// fields changed, or nil when the subnet already matched.
+func (s *Service) UpdateSubnetParams(ctx context.Context, info *SubnetInfo, cfg keamodels.SubnetConfig) ([]string, error) {
+	delta := subnetParamsDelta(info, cfg)
+	if len(delta) == 0 {
+		return nil, nil
+	}
+	changed := make([]string, 0, len(delta))
+	for field := range delta {
+		if field == subnetFieldPools {
+			field = poolFieldRequireClasses
+		}
+		changed = append(changed, field)
+	}
+	slices.Sort(changed)
+
+	delta["id"] = info.ID
+	delta["subnet"] = info.Subnet
+	resp, err := s.send(ctx, keamodels.Request{
+		Command: "subnet4-delta-add",
+		Args:    map[string]any{"subnet4": []map[string]any{delta}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Result != 0 {
+		return nil, fmt.Errorf("kea subnet4-delta-add failed: %s", resp.Text)
+	}
+	return changed, nil
+}
@@ -0,0 +1,88 @@
+package kea
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+)
+
+// TestUpdateSubnetParams verifies that changed lease times and pool classes
+// read from subnet4-get are sent with subnet4-delta-add, and that a matching
+// subnet is left alone.
+func TestUpdateSubnetParams(t *testing.T) {
+	kea := &subnetGetKea{subnet: map[string]any{
+		"id": 1, "subnet": testCIDR,
+		"valid-lifetime": float64(4000),
+		"renew-timer":    float64(1000),
+		"pools": []any{
+			map[string]any{"pool": "10.0.0.10-10.0.0.200", "require-client-classes": []any{"biosclients"}},
+		},
+	}}
+	s := New(kea)
+	ctx := context.Background()
+	info, err := s.GetSubnetInfo(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ValidLife != 4000 || info.RenewTimer != 1000 || len(info.Pools) != 1 || info.Pools[0].Range != "10.0.0.10 - 10.0.0.200" {
+		t.Fatalf("unexpected subnet info %+v", info)
+	}
+
+	want := keamodels.SubnetConfig{ValidLife: 600, RenewTimer: 1000, RequireClientClasses: []string{"ipxeclients"}}
+	changed, err := s.UpdateSubnetParams(ctx, info, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changed, []string{"require-client-classes", "valid-lifetime"}) {
+		t.Fatalf("unexpected changed fields %v", changed)
+	}
+	d := kea.delta[0]
+	if d["id"] != 1 || d["valid-lifetime"] != 600 || d["renew-timer"] != nil {
+		t.Fatalf("unexpected delta %v", d)
+	}
+	pools, _ := d["pools"].([]map[string]any)
+	if len(pools) != 1 || pools[0]["pool"] != "10.0.0.10 - 10.0.0.200" {
+		t.Fatalf("unexpected pools %v", pools)
+	}
+
+	kea.delta = nil
+	info.ValidLife, info.Pools[0].RequireClientClasses = 600, []string{"ipxeclients"}
+	if changed, err := s.UpdateSubnetParams(ctx, info, want); err != nil || changed != nil || kea.delta != nil {
+		t.Fatalf("expected no update for a matching subnet, got %v, %v", changed, err)
+	}
+}
+
+// TestUpdateSubnetParams_Relay verifies that relay addresses and the interface
+// are read back and only sent when they differ.
+func TestUpdateSubnetParams_Relay(t *testing.T) {
+	kea := &subnetGetKea{subnet: map[string]any{
+		"id": 1, "subnet": testCIDR,
+		"relay": map[string]any{"ip-addresses": []any{"10.0.0.1"}},
+	}}
+	s := New(kea)
+	ctx := context.Background()
+	info, err := s.GetSubnetInfo(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(info.RelayAddresses, []string{"10.0.0.1"}) || info.Interface != "" {
+		t.Fatalf("unexpected subnet info %+v", info)
+	}
+
+	if changed, err := s.UpdateSubnetParams(ctx, info, keamodels.SubnetConfig{RelayAddresses: []string{"10.0.0.1"}}); err != nil || changed != nil {
+		t.Fatalf("expected no update for matching relay addresses, got %v, %v", changed, err)
+	}
+	changed, err := s.UpdateSubnetParams(ctx, info, keamodels.SubnetConfig{RelayAddresses: []string{"10.0.0.254"}, Interface: "eth1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(changed, []string{"interface", "relay"}) {
+		t.Fatalf("unexpected changed fields %v", changed)
+	}
+	relay, _ := kea.delta[0]["relay"].(map[string]any)
+	if addrs, _ := relay["ip-addresses"].([]string); !slices.Equal(addrs, []string{"10.0.0.254"}) || kea.delta[0]["interface"] != "eth1" {
+		t.Fatalf("unexpected delta %v", kea.delta[0])
+	}
+}
@@ -17,7 +17,23 @@ func Init() {
 	viper.SetDefault(consts.DEVELOPMENT, false)
 	viper.SetDefault(consts.KEA_DISABLE_KEEPALIVES, true)
 	viper.SetDefault(consts.KEA_REQUIRE_CLIENT_CLASSES, "biosclients,ueficlients,ipxeclients")
+	viper.SetDefault(consts.KEA_VALID_LIFETIME, "4000")
 	viper.SetDefault(consts.KEA_STRICT_DEFAULTS, false)
+	viper.SetDefault(consts.KEA_IP_ALLOCATION_MODE, "lease")
+	viper.SetDefault(consts.KEA_POOL_GATEWAY, "first")
+	viper.SetDefault(consts.KEA_POOL_RESERVE_HEAD, 3)
+	viper.SetDefault(consts.KEA_POOL_RESERVE_TAIL, 0)
+	viper.SetDefault(consts.KEA_MIGRATION_GRACE_PERIOD, "30m")
+	viper.SetDefault(consts.KEA_MIGRATION_RELEASE_LEASES, false)
+	viper.SetDefault(consts.KEA_DELETION_TIMEOUT, "1h")
+	viper.SetDefault(consts.KEA_RELEASE_LEASES_ON_DELETE, false)
+	viper.SetDefault(consts.KEA_PRESEED_LEASES, false)
+	viper.SetDefault(consts.KEA_LEASE_WATCH_INTERVAL, "30s")
+	viper.SetDefault(consts.KEA_LEASE_WATCH_PAGE_SIZE, 1000)
+	viper.SetDefault(consts.KEA_DRY_RUN, false)
+	viper.SetDefault(consts.ENABLE_WEBHOOKS, false)
+	viper.SetDefault(consts.ENABLE_CLIENT_CLASSES, false)
+	viper.SetDefault(consts.ENABLE_DHCP6, false)
 
 	dotenv.LoadDotEnv()
 
@@ -39,6 +55,7 @@ func printEnvironmentSettings() {
 		consts.KEA_BASE_URL,
 		consts.KEA_URL,
 		consts.KEA_SECONDARY_URL,
+		consts.KEA_DHCP6_URL,
 		consts.KEA_PORT,
 		consts.KEA_TLS_CA_FILE,
 		consts.KEA_TLS_CERT_FILE,
@@ -51,7 +68,30 @@ func printEnvironmentSettings() {
 		consts.KEA_TLS_SECRET_NAMESPACE,
 		consts.KEA_DISABLE_KEEPALIVES,
 		consts.KEA_REQUIRE_CLIENT_CLASSES,
+		consts.KEA_VALID_LIFETIME,
+		consts.KEA_RENEW_TIMER,
+		consts.KEA_REBIND_TIMER,
+		consts.KEA_RELAY_ADDRESSES,
+		consts.KEA_SUBNET_INTERFACE,
 		consts.KEA_STRICT_DEFAULTS,
+		consts.KEA_IP_ALLOCATION_MODE,
+		consts.KEA_POOL_GATEWAY,
+		consts.KEA_POOL_RESERVE_HEAD,
+		consts.KEA_POOL_RESERVE_TAIL,
+		consts.KEA_DHCP_OPTIONS,
+		consts.KEA_HOST_PARAMS,
+		consts.KEA_MIGRATION_GRACE_PERIOD,
+		consts.KEA_MIGRATION_RELEASE_LEASES,
+		consts.KEA_DELETION_TIMEOUT,
+		consts.KEA_RELEASE_LEASES_ON_DELETE,
+		consts.KEA_PRESEED_LEASES,
+		consts.KEA_PRESEED_LEASE_LIFETIME,
+		consts.KEA_LEASE_WATCH_INTERVAL,
+		consts.KEA_LEASE_WATCH_PAGE_SIZE,
+		consts.KEA_DRY_RUN,
+		consts.ENABLE_WEBHOOKS,
+		consts.ENABLE_CLIENT_CLASSES,
+		consts.ENABLE_DHCP6,
 	}
 
 	for _, s := range settings {
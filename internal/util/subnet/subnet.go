@@ -3,73 +3,266 @@ package subnet
 import (
 	"fmt"
 	"net"
+	"sort"
+	"strings"
 )
 
+// Gateway positions accepted by PoolPolicy.Gateway besides an explicit address.
+const (
+	GatewayFirst = "first"
+	GatewayLast  = "last"
+)
+
+// IPRange is an inclusive address range. It is IPv4 except in the results of
+// CalculatePool6.
+type IPRange struct {
+	Start net.IP
+	End   net.IP
+}
+
+// String formats the range as "start-end".
+func (r IPRange) String() string {
+	return r.Start.String() + "-" + r.End.String()
+}
+
+// PoolPolicy describes how a prefix is laid out into gateway, dynamic pools
+// and reservation space.
+type PoolPolicy struct {
+	Gateway     string // GatewayFirst (default), GatewayLast or an explicit IPv4 address
+	ReserveHead int    // usable addresses at the start of the prefix kept out of the pools
+	ReserveTail int    // usable addresses at the end of the prefix kept out of the pools
+	Pools       []IPRange
+	Exclude     []IPRange // removed from the pools, splitting them where needed
+	// ReservationRange, when set, is carved out of the pools and used for
+	// operator-side reservations instead.
+	ReservationRange *IPRange
+}
+
+// DefaultPoolPolicy returns the historical layout: gateway at network+1 and a
+// single pool from network+4 to broadcast-1.
+func DefaultPoolPolicy() PoolPolicy {
+	return PoolPolicy{Gateway: GatewayFirst, ReserveHead: 3}
+}
+
 // PoolConfig contains calculated pool configuration based on a CIDR
 type PoolConfig struct {
-	Gateway   string // First usable IP (e.g., 10.123.1.1)
-	PoolStart string // Start of pool range (e.g., 10.123.1.4)
-	PoolEnd   string // End of pool range (e.g., 10.123.1.254)
+	Gateway          string    // Gateway address (e.g., 10.123.1.1)
+	Pools            []IPRange // Dynamic pools, in address order (e.g., 10.123.1.4-10.123.1.254)
+	ReservationRange *IPRange  // Out-of-pool reservation range, if the policy sets one
 }
 
-// CalculatePoolFromCIDR calculates gateway and pool range from a CIDR.
-// Gateway is .1, pool starts at .4 and ends at .254 (for /24 networks).
-// For other network sizes, pool ends at the last usable address before broadcast.
+// CalculatePoolFromCIDR calculates gateway and pool range from a CIDR using
+// DefaultPoolPolicy.
 func CalculatePoolFromCIDR(cidr string) (*PoolConfig, error) {
-	_, ipnet, err := net.ParseCIDR(cidr)
+	return CalculatePool(cidr, DefaultPoolPolicy())
+}
+
+// CalculatePool lays out an IPv4 prefix according to policy.
+//
+// Pools are policy.Pools if given, otherwise every usable address minus
+// ReserveHead addresses at the start and ReserveTail at the end. When the
+// prefix is too small for those reservations (e.g. a /30 with the default
+// policy) they are dropped and every usable address except the gateway goes to
+// the pool. The gateway, the Exclude ranges and the ReservationRange are then
+// removed from the pools. /31 and /32 prefixes have no room for a gateway and
+// a pool and are rejected.
+func CalculatePool(cidr string, policy PoolPolicy) (*PoolConfig, error) {
+	_, ipnet, err := net.ParseCIDR(strings.TrimSpace(cidr))
 	if err != nil {
 		return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
 	}
-
-	// Get network address as 4-byte slice
-	ip := ipnet.IP.To4()
-	if ip == nil {
+	if ipnet.IP.To4() == nil {
 		return nil, fmt.Errorf("only IPv4 CIDRs are supported: %s", cidr)
 	}
+	ones, _ := ipnet.Mask.Size()
+	if ones > 30 {
+		return nil, fmt.Errorf("network %s is too small: a /%d has no room for a gateway and a pool, use /30 or larger", cidr, ones)
+	}
+
+	network := int64(IPv4ToUint32(ipnet.IP))
+	broadcast := network | int64(^IPv4ToUint32(net.IP(ipnet.Mask)))
+	firstUsable, lastUsable := network+1, broadcast-1
+	inUsable := func(r span) bool { return r.start >= firstUsable && r.end <= lastUsable }
+
+	var gateway int64
+	switch g := strings.ToLower(strings.TrimSpace(policy.Gateway)); g {
+	case "", GatewayFirst:
+		gateway = firstUsable
+	case GatewayLast:
+		gateway = lastUsable
+	default:
+		ip := net.ParseIP(g).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid gateway %q: expected %q, %q or an IPv4 address", policy.Gateway, GatewayFirst, GatewayLast)
+		}
+		gateway = int64(IPv4ToUint32(ip))
+		if !inUsable(span{gateway, gateway}) {
+			return nil, fmt.Errorf("gateway %s is not a usable address in %s", ip, cidr)
+		}
+	}
+
+	var pools []span
+	if len(policy.Pools) > 0 {
+		for _, r := range policy.Pools {
+			sp := toSpan(r)
+			if !inUsable(sp) {
+				return nil, fmt.Errorf("pool %s is not within the usable addresses of %s", r, cidr)
+			}
+			pools = append(pools, sp)
+		}
+	} else {
+		if policy.ReserveHead < 0 || policy.ReserveTail < 0 {
+			return nil, fmt.Errorf("reserved head and tail counts must not be negative")
+		}
+		sp := span{firstUsable + int64(policy.ReserveHead), lastUsable - int64(policy.ReserveTail)}
+		if sp.start > sp.end {
+			sp = span{firstUsable, lastUsable}
+		}
+		pools = []span{sp}
+	}
+
+	pools = subtract(pools, span{gateway, gateway})
+	for _, r := range policy.Exclude {
+		sp := toSpan(r)
+		if sp.start < network || sp.end > broadcast {
+			return nil, fmt.Errorf("excluded range %s is not within %s", r, cidr)
+		}
+		pools = subtract(pools, sp)
+	}
+
+	cfg := &PoolConfig{Gateway: Uint32ToIPv4(uint32(gateway)).String()}
+	if rr := policy.ReservationRange; rr != nil {
+		sp := toSpan(*rr)
+		if !inUsable(sp) {
+			return nil, fmt.Errorf("reservation range %s is not within the usable addresses of %s", rr, cidr)
+		}
+		if sp.start <= gateway && gateway <= sp.end {
+			return nil, fmt.Errorf("reservation range %s contains the gateway %s", rr, cfg.Gateway)
+		}
+		pools = subtract(pools, sp)
+		cfg.ReservationRange = &IPRange{Start: rr.Start.To4(), End: rr.End.To4()}
+	}
+
+	if len(pools) == 0 {
+		return nil, fmt.Errorf("pool policy leaves no dynamic pool addresses in %s", cidr)
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].start < pools[j].start })
+	for _, sp := range pools {
+		cfg.Pools = append(cfg.Pools, IPRange{Start: Uint32ToIPv4(uint32(sp.start)), End: Uint32ToIPv4(uint32(sp.end))})
+	}
+	return cfg, nil
+}
+
+// Pool6Head is the number of addresses at the start of an IPv6 prefix that
+// CalculatePool6 keeps out of the pool for routers and static hosts.
+const Pool6Head = 0x100
+
+// CalculatePool6 lays out an IPv6 prefix as a single dynamic pool covering
+// the whole prefix except its first Pool6Head addresses. Prefixes longer than
+// /112 are rejected.
+func CalculatePool6(cidr string) ([]IPRange, error) {
+	_, ipnet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	if ipnet.IP.To4() != nil {
+		return nil, fmt.Errorf("not an IPv6 CIDR: %s", cidr)
+	}
+	ones, _ := ipnet.Mask.Size()
+	if ones > 112 {
+		return nil, fmt.Errorf("network %s is too small for a DHCPv6 pool, use /112 or larger", cidr)
+	}
 
-	// Calculate broadcast address
-	mask := ipnet.Mask
-	broadcast := make(net.IP, 4)
-	for i := range 4 {
-		broadcast[i] = ip[i] | ^mask[i]
+	start := make(net.IP, net.IPv6len)
+	end := make(net.IP, net.IPv6len)
+	for i := range start {
+		start[i] = ipnet.IP[i]
+		end[i] = ipnet.IP[i] | ^ipnet.Mask[i]
 	}
+	start[net.IPv6len-2] |= Pool6Head >> 8
+	return []IPRange{{Start: start, End: end}}, nil
+}
 
-	// Gateway is network + 1 (e.g., 10.123.1.1)
-	gateway := make(net.IP, 4)
-	copy(gateway, ip)
-	gateway[3]++
+// span is a numeric inclusive range; int64 keeps end+1 and start-1 from
+// wrapping at the edges of the address space.
+type span struct{ start, end int64 }
 
-	// Pool start is network + 4 (e.g., 10.123.1.4)
-	poolStart := make(net.IP, 4)
-	copy(poolStart, ip)
-	poolStart[3] += 4
+func toSpan(r IPRange) span {
+	return span{int64(IPv4ToUint32(r.Start)), int64(IPv4ToUint32(r.End))}
+}
 
-	// Pool end is broadcast - 1 (e.g., 10.123.1.254 for /24)
-	poolEnd := make(net.IP, 4)
-	copy(poolEnd, broadcast)
-	poolEnd[3]--
+// subtract removes cut from every span in spans, splitting spans it falls inside.
+func subtract(spans []span, cut span) []span {
+	var out []span
+	for _, sp := range spans {
+		if cut.end < sp.start || cut.start > sp.end {
+			out = append(out, sp)
+			continue
+		}
+		if sp.start < cut.start {
+			out = append(out, span{sp.start, cut.start - 1})
+		}
+		if cut.end < sp.end {
+			out = append(out, span{cut.end + 1, sp.end})
+		}
+	}
+	return out
+}
 
-	// Validate that pool range is valid (start < end)
-	if !isIPLess(poolStart, poolEnd) {
-		return nil, fmt.Errorf("network %s is too small for a valid pool", cidr)
+// IPv4ToUint32 converts an IPv4 address to its numeric form. Non-IPv4 input yields 0.
+func IPv4ToUint32(ip net.IP) uint32 {
+	v4 := ip.To4()
+	if v4 == nil {
+		return 0
 	}
+	return uint32(v4[0])<<24 | uint32(v4[1])<<16 | uint32(v4[2])<<8 | uint32(v4[3])
+}
 
-	return &PoolConfig{
-		Gateway:   gateway.String(),
-		PoolStart: poolStart.String(),
-		PoolEnd:   poolEnd.String(),
-	}, nil
+// Uint32ToIPv4 converts a numeric IPv4 address back to net.IP.
+func Uint32ToIPv4(n uint32) net.IP {
+	return net.IPv4(byte(n>>24), byte(n>>16), byte(n>>8), byte(n)).To4()
 }
 
-// isIPLess returns true if a < b for IPv4 addresses
-func isIPLess(a, b net.IP) bool {
-	for i := range 4 {
-		if a[i] < b[i] {
-			return true
+// ParseIPv4Range parses an inclusive "start-end" IPv4 range (spaces around
+// the dash are allowed, matching Kea's pool syntax) and checks start <= end.
+func ParseIPv4Range(r string) (net.IP, net.IP, error) {
+	startStr, endStr, ok := strings.Cut(r, "-")
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid IPv4 range %q, expected <start>-<end>", r)
+	}
+	start := net.ParseIP(strings.TrimSpace(startStr)).To4()
+	end := net.ParseIP(strings.TrimSpace(endStr)).To4()
+	if start == nil || end == nil {
+		return nil, nil, fmt.Errorf("invalid IPv4 range %q: both ends must be IPv4 addresses", r)
+	}
+	if IPv4ToUint32(start) > IPv4ToUint32(end) {
+		return nil, nil, fmt.Errorf("invalid IPv4 range %q: start is after end", r)
+	}
+	return start, end, nil
+}
+
+// ParseIPv4Ranges parses a comma-separated list of IPv4 ranges. A bare address
+// is a single-address range.
+func ParseIPv4Ranges(list string) ([]IPRange, error) {
+	var out []IPRange
+	for entry := range strings.SplitSeq(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "-") {
+			ip := net.ParseIP(entry).To4()
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IPv4 address %q", entry)
+			}
+			out = append(out, IPRange{Start: ip, End: ip})
+			continue
 		}
-		if a[i] > b[i] {
-			return false
+		start, end, err := ParseIPv4Range(entry)
+		if err != nil {
+			return nil, err
 		}
+		out = append(out, IPRange{Start: start, End: end})
 	}
-	return false
+	return out, nil
 }
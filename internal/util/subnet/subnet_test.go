@@ -0,0 +1,134 @@
+package subnet
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func mustRange(t *testing.T, r string) IPRange {
+	t.Helper()
+	start, end, err := ParseIPv4Range(r)
+	if err != nil {
+		t.Fatalf("bad range %q: %v", r, err)
+	}
+	return IPRange{Start: start, End: end}
+}
+
+func poolStrings(pools []IPRange) string {
+	parts := make([]string, 0, len(pools))
+	for _, p := range pools {
+		parts = append(parts, p.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestCalculatePool(t *testing.T) {
+	tests := []struct {
+		name        string
+		cidr        string
+		policy      PoolPolicy
+		wantGateway string
+		wantPools   string
+		wantErr     string
+	}{
+		{name: "default /24", cidr: "10.123.1.0/24", policy: DefaultPoolPolicy(),
+			wantGateway: "10.123.1.1", wantPools: "10.123.1.4-10.123.1.254"},
+		{name: "default /16 crosses octets", cidr: "10.123.0.0/16", policy: DefaultPoolPolicy(),
+			wantGateway: "10.123.0.1", wantPools: "10.123.0.4-10.123.255.254"},
+		{name: "default /29", cidr: "10.0.0.8/29", policy: DefaultPoolPolicy(),
+			wantGateway: "10.0.0.9", wantPools: "10.0.0.12-10.0.0.14"},
+		{name: "default /30 drops head reservation", cidr: "10.0.0.4/30", policy: DefaultPoolPolicy(),
+			wantGateway: "10.0.0.5", wantPools: "10.0.0.6-10.0.0.6"},
+		{name: "gateway last with tail", cidr: "10.0.0.0/24", policy: PoolPolicy{Gateway: GatewayLast, ReserveHead: 9, ReserveTail: 5},
+			wantGateway: "10.0.0.254", wantPools: "10.0.0.10-10.0.0.249"},
+		{name: "explicit gateway splits pool", cidr: "10.0.0.0/24", policy: PoolPolicy{Gateway: "10.0.0.100"},
+			wantGateway: "10.0.0.100", wantPools: "10.0.0.1-10.0.0.99,10.0.0.101-10.0.0.254"},
+		{name: "excludes and reservation range", cidr: "10.0.0.0/24", policy: PoolPolicy{
+			ReserveHead: 3,
+			Exclude:     []IPRange{mustRange(t, "10.0.0.50-10.0.0.59")},
+			ReservationRange: func() *IPRange {
+				r := mustRange(t, "10.0.0.200-10.0.0.254")
+				return &r
+			}(),
+		}, wantGateway: "10.0.0.1", wantPools: "10.0.0.4-10.0.0.49,10.0.0.60-10.0.0.199"},
+		{name: "explicit pools", cidr: "10.0.0.0/24", policy: PoolPolicy{
+			Pools: []IPRange{mustRange(t, "10.0.0.100-10.0.0.149"), mustRange(t, "10.0.0.10-10.0.0.19")},
+		}, wantGateway: "10.0.0.1", wantPools: "10.0.0.10-10.0.0.19,10.0.0.100-10.0.0.149"},
+		{name: "/31 rejected", cidr: "10.0.0.0/31", policy: DefaultPoolPolicy(), wantErr: "use /30 or larger"},
+		{name: "/32 rejected", cidr: "10.0.0.1/32", policy: DefaultPoolPolicy(), wantErr: "use /30 or larger"},
+		{name: "gateway outside prefix", cidr: "10.0.0.0/24", policy: PoolPolicy{Gateway: "10.0.1.1"}, wantErr: "not a usable address"},
+		{name: "pool outside prefix", cidr: "10.0.0.0/24", policy: PoolPolicy{
+			Pools: []IPRange{mustRange(t, "10.0.0.200-10.0.1.10")},
+		}, wantErr: "not within"},
+		{name: "everything excluded", cidr: "10.0.0.0/30", policy: PoolPolicy{
+			Exclude: []IPRange{mustRange(t, "10.0.0.2-10.0.0.2")},
+		}, wantErr: "no dynamic pool addresses"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := CalculatePool(tt.cidr, tt.policy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Gateway != tt.wantGateway {
+				t.Errorf("gateway: got %s, want %s", cfg.Gateway, tt.wantGateway)
+			}
+			if got := poolStrings(cfg.Pools); got != tt.wantPools {
+				t.Errorf("pools: got %s, want %s", got, tt.wantPools)
+			}
+		})
+	}
+}
+
+func TestParseIPv4Ranges(t *testing.T) {
+	got, err := ParseIPv4Ranges("10.0.0.1 - 10.0.0.5, 10.0.0.9")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := poolStrings(got); s != "10.0.0.1-10.0.0.5,10.0.0.9-10.0.0.9" {
+		t.Fatalf("got %s", s)
+	}
+	if !got[1].Start.Equal(net.ParseIP("10.0.0.9")) {
+		t.Fatalf("expected single address range, got %v", got[1])
+	}
+	if _, err := ParseIPv4Ranges("10.0.0.5-10.0.0.1"); err == nil {
+		t.Fatalf("expected error for reversed range")
+	}
+}
+
+func TestCalculatePool6(t *testing.T) {
+	tests := []struct {
+		cidr      string
+		wantPools string
+		wantErr   string
+	}{
+		{cidr: "2001:db8:1::/64", wantPools: "2001:db8:1::100-2001:db8:1:0:ffff:ffff:ffff:ffff"},
+		{cidr: "2001:db8:1::/112", wantPools: "2001:db8:1::100-2001:db8:1::ffff"},
+		{cidr: "2001:db8:1::/120", wantErr: "too small"},
+		{cidr: "10.0.0.0/24", wantErr: "not an IPv6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			pools, err := CalculatePool6(tt.cidr)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := poolStrings(pools); got != tt.wantPools {
+				t.Fatalf("pools = %s, want %s", got, tt.wantPools)
+			}
+		})
+	}
+}
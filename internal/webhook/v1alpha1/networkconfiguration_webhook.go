@@ -0,0 +1,142 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	controllerv1alpha1 "github.com/vitistack/kea-operator/internal/controller/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-vitistack-io-v1alpha1-networkconfiguration,mutating=false,failurePolicy=fail,sideEffects=None,groups=vitistack.io,resources=networkconfigurations,verbs=create;update,versions=v1alpha1,name=vnetworkconfiguration-v1alpha1.kea.vitistack.io,admissionReviewVersions=v1
+
+// NetworkConfigurationValidator rejects NetworkConfigurations the operator
+// cannot serve: malformed or duplicate MACs, MACs already claimed by another
+// NetworkConfiguration, and a spec.networkNamespaceName that does not exist.
+// NetworkConfigurations for another provider are not checked.
+type NetworkConfigurationValidator struct {
+	Client client.Reader
+}
+
+// SetupNetworkConfigurationWebhookWithManager registers the validating
+// webhook. It relies on the MAC index registered by the controller's
+// SetupWithManager, so it must be called after it.
+func SetupNetworkConfigurationWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &vitistackcrdsv1alpha1.NetworkConfiguration{}).
+		WithValidator(&NetworkConfigurationValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// ValidateCreate implements admission.Validator.
+func (v *NetworkConfigurationValidator) ValidateCreate(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration) (admission.Warnings, error) {
+	return nil, v.validate(ctx, nil, nc)
+}
+
+// ValidateUpdate implements admission.Validator. Updates that leave the spec
+// alone, such as finalizer removal, and objects being deleted are always
+// admitted so a stale reference never blocks cleanup.
+func (v *NetworkConfigurationValidator) ValidateUpdate(ctx context.Context, oldNC, nc *vitistackcrdsv1alpha1.NetworkConfiguration) (admission.Warnings, error) {
+	if !nc.GetDeletionTimestamp().IsZero() || equality.Semantic.DeepEqual(oldNC.Spec, nc.Spec) {
+		return nil, nil
+	}
+	return nil, v.validate(ctx, oldNC, nc)
+}
+
+// ValidateDelete implements admission.Validator.
+func (v *NetworkConfigurationValidator) ValidateDelete(context.Context, *vitistackcrdsv1alpha1.NetworkConfiguration) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks nc; oldNC is nil on create. Only MACs and a NetworkNamespace
+// name that are new in this request are checked against other objects.
+func (v *NetworkConfigurationValidator) validate(ctx context.Context, oldNC, nc *vitistackcrdsv1alpha1.NetworkConfiguration) error {
+	if vitistackcrdsv1alpha1.IsProviderSet(nc.Spec.Provider) &&
+		!vitistackcrdsv1alpha1.MatchesProvider(nc.Spec.Provider, vitistackcrdsv1alpha1.ProviderNameKea) {
+		return nil
+	}
+
+	var errs field.ErrorList
+	existing := map[string]bool{}
+	if oldNC != nil {
+		for _, mac := range controllerv1alpha1.IndexMACAddresses(oldNC) {
+			existing[mac] = true
+		}
+	}
+
+	ifacesPath := field.NewPath("spec", "networkInterfaces")
+	seen := map[string]int{}
+	for i, iface := range nc.Spec.NetworkInterfaces {
+		path := ifacesPath.Index(i).Child("macAddress")
+		if strings.TrimSpace(iface.MacAddress) == "" {
+			continue
+		}
+		mac := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(iface.MacAddress), "-", ":"))
+		if hw, err := net.ParseMAC(mac); err != nil || len(hw) != 6 {
+			errs = append(errs, field.Invalid(path, iface.MacAddress, "must be a 48-bit MAC address such as aa:bb:cc:dd:ee:ff"))
+			continue
+		}
+		if j, dup := seen[mac]; dup {
+			errs = append(errs, field.Duplicate(path, fmt.Sprintf("%s (also on %s)", iface.MacAddress, ifacesPath.Index(j))))
+			continue
+		}
+		seen[mac] = i
+		if existing[mac] {
+			continue
+		}
+		owner, err := v.macOwner(ctx, nc, mac)
+		if err != nil {
+			return apierrors.NewInternalError(err)
+		}
+		if owner != "" {
+			errs = append(errs, field.Forbidden(path, fmt.Sprintf("%s is already claimed by NetworkConfiguration %s", mac, owner)))
+		}
+	}
+
+	if name := nc.Spec.NetworkNamespaceName; name != "" && (oldNC == nil || oldNC.Spec.NetworkNamespaceName != name) {
+		nn := &vitistackcrdsv1alpha1.NetworkNamespace{}
+		if err := v.Client.Get(ctx, client.ObjectKey{Namespace: nc.Namespace, Name: name}, nn); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return apierrors.NewInternalError(err)
+			}
+			errs = append(errs, field.NotFound(field.NewPath("spec", "networkNamespaceName"), name))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(vitistackcrdsv1alpha1.GroupVersion.WithKind("NetworkConfiguration").GroupKind(), nc.Name, errs)
+}
+
+// macOwner returns "namespace/name" of another live NetworkConfiguration
+// listing mac, or "".
+func (v *NetworkConfigurationValidator) macOwner(ctx context.Context, nc *vitistackcrdsv1alpha1.NetworkConfiguration, mac string) (string, error) {
+	var ncs vitistackcrdsv1alpha1.NetworkConfigurationList
+	if err := v.Client.List(ctx, &ncs, client.MatchingFields{controllerv1alpha1.MACAddressIndex: mac}); err != nil {
+		return "", err
+	}
+	for i := range ncs.Items {
+		other := &ncs.Items[i]
+		if other.Namespace == nc.Namespace && other.Name == nc.Name {
+			continue
+		}
+		// A NetworkConfiguration being deleted releases its MACs shortly.
+		if !other.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		if vitistackcrdsv1alpha1.IsProviderSet(other.Spec.Provider) &&
+			!vitistackcrdsv1alpha1.MatchesProvider(other.Spec.Provider, vitistackcrdsv1alpha1.ProviderNameKea) {
+			continue
+		}
+		return other.Namespace + "/" + other.Name, nil
+	}
+	return "", nil
+}
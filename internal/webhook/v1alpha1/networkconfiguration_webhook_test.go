@@ -0,0 +1,96 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	controllerv1alpha1 "github.com/vitistack/kea-operator/internal/controller/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testNamespace = "tenant-a"
+
+func ncWithMACs(name, networkNamespaceName string, macs ...string) *vitistackcrdsv1alpha1.NetworkConfiguration {
+	nc := &vitistackcrdsv1alpha1.NetworkConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name},
+		Spec:       vitistackcrdsv1alpha1.NetworkConfigurationSpec{NetworkNamespaceName: networkNamespaceName},
+	}
+	for _, mac := range macs {
+		nc.Spec.NetworkInterfaces = append(nc.Spec.NetworkInterfaces, vitistackcrdsv1alpha1.NetworkConfigurationInterface{MacAddress: mac})
+	}
+	return nc
+}
+
+func newNCValidator(t *testing.T, objs ...client.Object) *NetworkConfigurationValidator {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := vitistackcrdsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithIndex(&vitistackcrdsv1alpha1.NetworkConfiguration{}, controllerv1alpha1.MACAddressIndex, controllerv1alpha1.IndexMACAddresses).
+		WithObjects(objs...).
+		Build()
+	return &NetworkConfigurationValidator{Client: c}
+}
+
+func TestNetworkConfigurationValidator_Create(t *testing.T) {
+	nn := &vitistackcrdsv1alpha1.NetworkNamespace{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "nn"}}
+	v := newNCValidator(t, nn, ncWithMACs("other", "nn", "aa:bb:cc:dd:ee:01"))
+
+	tests := []struct {
+		name    string
+		nc      *vitistackcrdsv1alpha1.NetworkConfiguration
+		wantErr string
+	}{
+		{name: "valid", nc: ncWithMACs("nc", "nn", "AA-BB-CC-DD-EE-00")},
+		{name: "malformed MAC", nc: ncWithMACs("nc", "nn", "not-a-mac"), wantErr: "48-bit MAC"},
+		{name: "duplicate MAC", nc: ncWithMACs("nc", "nn", "aa:bb:cc:dd:ee:00", "AA-BB-CC-DD-EE-00"), wantErr: "Duplicate"},
+		{name: "MAC claimed elsewhere", nc: ncWithMACs("nc", "nn", "aa:bb:cc:dd:ee:01"), wantErr: "claimed by NetworkConfiguration tenant-a/other"},
+		{name: "missing NetworkNamespace", nc: ncWithMACs("nc", "missing", "aa:bb:cc:dd:ee:00"), wantErr: "Not found"},
+		{name: "other provider", nc: func() *vitistackcrdsv1alpha1.NetworkConfiguration {
+			nc := ncWithMACs("nc", "missing", "not-a-mac")
+			nc.Spec.Provider = "static"
+			return nc
+		}()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.ValidateCreate(context.Background(), tt.nc)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNetworkConfigurationValidator_Update(t *testing.T) {
+	v := newNCValidator(t)
+	old := ncWithMACs("nc", "gone", "aa:bb:cc:dd:ee:00")
+
+	// Metadata-only updates such as finalizer removal pass even though the
+	// NetworkNamespace no longer exists.
+	updated := old.DeepCopy()
+	updated.Finalizers = nil
+	if _, err := v.ValidateUpdate(context.Background(), old, updated); err != nil {
+		t.Fatalf("expected metadata-only update to pass, got %v", err)
+	}
+
+	// Adding a MAC checks the new MAC but not the unchanged NetworkNamespace.
+	updated = ncWithMACs("nc", "gone", "aa:bb:cc:dd:ee:00", "aa:bb:cc:dd:ee:02")
+	if _, err := v.ValidateUpdate(context.Background(), old, updated); err != nil {
+		t.Fatalf("expected update adding a free MAC to pass, got %v", err)
+	}
+}
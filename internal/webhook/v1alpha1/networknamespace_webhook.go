@@ -0,0 +1,209 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	controllerv1alpha1 "github.com/vitistack/kea-operator/internal/controller/v1alpha1"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-vitistack-io-v1alpha1-networknamespace,mutating=false,failurePolicy=fail,sideEffects=None,groups=vitistack.io,resources=networknamespaces;networknamespaces/status,verbs=create;update,versions=v1alpha1,name=vnetworknamespace-v1alpha1.kea.vitistack.io,admissionReviewVersions=v1
+
+// NetworkNamespaceValidator rejects a DHCP NetworkNamespace whose
+// status.ipv4Prefix or secondary-ipv4-prefixes overlap a Kea subnet the
+// operator does not own. A subnet with exactly one of the prefixes is adopted
+// when the operator created it; any other overlap is rejected. Subnets that
+// already serve the NetworkNamespace never conflict: those of its previous
+// prefixes, and those its NetworkConfigurations hold reservations in. When
+// Kea cannot be reached the change is admitted with a warning, so a Kea
+// outage never blocks NetworkNamespace updates.
+type NetworkNamespaceValidator struct {
+	Kea    *keaservice.Service
+	Client client.Reader
+}
+
+// SetupNetworkNamespaceWebhookWithManager registers the validating webhook.
+func SetupNetworkNamespaceWebhookWithManager(mgr ctrl.Manager, kea *keaservice.Service) error {
+	return ctrl.NewWebhookManagedBy(mgr, &vitistackcrdsv1alpha1.NetworkNamespace{}).
+		WithValidator(&NetworkNamespaceValidator{Kea: kea, Client: mgr.GetClient()}).
+		Complete()
+}
+
+// ValidateCreate implements admission.Validator.
+func (v *NetworkNamespaceValidator) ValidateCreate(ctx context.Context, nn *vitistackcrdsv1alpha1.NetworkNamespace) (admission.Warnings, error) {
+	return v.validate(ctx, nil, nn)
+}
+
+// ValidateUpdate implements admission.Validator. Only changed prefixes are
+// checked, so subnets that predate ownership tracking keep working.
+func (v *NetworkNamespaceValidator) ValidateUpdate(ctx context.Context, oldNN, nn *vitistackcrdsv1alpha1.NetworkNamespace) (admission.Warnings, error) {
+	if oldNN.Status.IPv4Prefix == nn.Status.IPv4Prefix &&
+		oldNN.GetAnnotations()[consts.SecondaryIPv4PrefixesAnnotation] == nn.GetAnnotations()[consts.SecondaryIPv4PrefixesAnnotation] {
+		return nil, nil
+	}
+	return v.validate(ctx, oldNN, nn)
+}
+
+// ValidateDelete implements admission.Validator.
+func (v *NetworkNamespaceValidator) ValidateDelete(context.Context, *vitistackcrdsv1alpha1.NetworkNamespace) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// prefixField is one IPv4 prefix of a NetworkNamespace and where it is set.
+type prefixField struct {
+	path   *field.Path
+	prefix string
+}
+
+// validate checks nn; oldNN is nil on create.
+func (v *NetworkNamespaceValidator) validate(ctx context.Context, oldNN, nn *vitistackcrdsv1alpha1.NetworkNamespace) (admission.Warnings, error) {
+	if strings.TrimSpace(nn.Status.IPv4Prefix) == "" {
+		return nil, nil
+	}
+	if nn.Spec.IPAllocation != nil && nn.Spec.IPAllocation.Type != vitistackcrdsv1alpha1.IPAllocationTypeDHCP {
+		return nil, nil
+	}
+
+	var errs field.ErrorList
+	var wants []*net.IPNet
+	var fields []prefixField
+	for _, f := range prefixFields(nn) {
+		ip, want, err := net.ParseCIDR(f.prefix)
+		switch {
+		case err != nil || ip.To4() == nil:
+			errs = append(errs, field.Invalid(f.path, f.prefix, "must be an IPv4 CIDR prefix"))
+			continue
+		case !ip.Equal(want.IP):
+			errs = append(errs, field.Invalid(f.path, f.prefix, fmt.Sprintf("must be a network address, use %s", want)))
+			continue
+		}
+		if j := slices.IndexFunc(wants, func(other *net.IPNet) bool { return overlaps(other, want) }); j >= 0 {
+			errs = append(errs, field.Invalid(f.path, f.prefix, fmt.Sprintf("overlaps %s", fields[j].prefix)))
+			continue
+		}
+		wants, fields = append(wants, want), append(fields, f)
+	}
+	if len(errs) > 0 {
+		return nil, invalidNetworkNamespace(nn, errs...)
+	}
+
+	own, err := v.ownSubnets(ctx, oldNN, nn)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	for i, want := range wants {
+		conflict, err := v.conflictingSubnet(ctx, want, own)
+		if err != nil {
+			logf.FromContext(ctx).Info("cannot check NetworkNamespace prefix against Kea subnets", "prefix", fields[i].prefix, "error", err.Error())
+			return admission.Warnings{fmt.Sprintf("could not check %s against Kea subnets: %v", fields[i].prefix, err)}, nil
+		}
+		if conflict != "" {
+			errs = append(errs, field.Forbidden(fields[i].path, conflict))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, invalidNetworkNamespace(nn, errs...)
+	}
+	return nil, nil
+}
+
+// prefixFields returns status.ipv4Prefix of nn followed by the prefixes of
+// its secondary-ipv4-prefixes annotation.
+func prefixFields(nn *vitistackcrdsv1alpha1.NetworkNamespace) []prefixField {
+	fields := []prefixField{{path: field.NewPath("status", "ipv4Prefix"), prefix: strings.TrimSpace(nn.Status.IPv4Prefix)}}
+	annotation := field.NewPath("metadata", "annotations").Key(consts.SecondaryIPv4PrefixesAnnotation)
+	for p := range strings.SplitSeq(nn.GetAnnotations()[consts.SecondaryIPv4PrefixesAnnotation], ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			fields = append(fields, prefixField{path: annotation, prefix: p})
+		}
+	}
+	return fields
+}
+
+// ownedSubnets identifies the Kea subnets already serving a NetworkNamespace,
+// whether or not the operator created them.
+type ownedSubnets struct {
+	prefixes map[string]bool
+	ids      map[int]bool
+}
+
+func (o ownedSubnets) owns(id int, prefix *net.IPNet) bool {
+	return o.ids[id] || o.prefixes[prefix.String()]
+}
+
+// ownSubnets collects the subnets serving nn: those of the prefixes oldNN
+// had, and those the reservation inventories of its NetworkConfigurations
+// record.
+func (v *NetworkNamespaceValidator) ownSubnets(ctx context.Context, oldNN, nn *vitistackcrdsv1alpha1.NetworkNamespace) (ownedSubnets, error) {
+	own := ownedSubnets{prefixes: map[string]bool{}, ids: map[int]bool{}}
+	if oldNN != nil {
+		for _, f := range prefixFields(oldNN) {
+			if _, ipnet, err := net.ParseCIDR(f.prefix); err == nil {
+				own.prefixes[ipnet.String()] = true
+			}
+		}
+	}
+	if v.Client == nil {
+		return own, nil
+	}
+	var ncs vitistackcrdsv1alpha1.NetworkConfigurationList
+	if err := v.Client.List(ctx, &ncs, client.InNamespace(nn.Namespace)); err != nil {
+		return own, err
+	}
+	for i := range ncs.Items {
+		nc := &ncs.Items[i]
+		if name := nc.Spec.NetworkNamespaceName; name != "" && name != nn.Name {
+			continue
+		}
+		for _, id := range controllerv1alpha1.InventorySubnetIDs(nc) {
+			own.ids[id] = true
+		}
+	}
+	return own, nil
+}
+
+// conflictingSubnet describes the first Kea subnet that overlaps want and may
+// not be used for it, or returns "".
+func (v *NetworkNamespaceValidator) conflictingSubnet(ctx context.Context, want *net.IPNet, own ownedSubnets) (string, error) {
+	subnets, err := v.Kea.ListSubnets(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, sn := range subnets {
+		_, have, err := net.ParseCIDR(sn.Subnet)
+		if err != nil || !overlaps(have, want) || own.owns(sn.ID, have) {
+			continue
+		}
+		if have.String() != want.String() {
+			return fmt.Sprintf("overlaps Kea subnet %d (%s)", sn.ID, sn.Subnet), nil
+		}
+		info, err := v.Kea.GetSubnetInfo(ctx, sn.ID)
+		if err != nil {
+			return "", err
+		}
+		if !info.Managed {
+			return fmt.Sprintf("Kea subnet %d (%s) exists and is not managed by kea-operator", sn.ID, sn.Subnet), nil
+		}
+	}
+	return "", nil
+}
+
+func overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+func invalidNetworkNamespace(nn *vitistackcrdsv1alpha1.NetworkNamespace, errs ...*field.Error) error {
+	return apierrors.NewInvalid(vitistackcrdsv1alpha1.GroupVersion.WithKind("NetworkNamespace").GroupKind(), nn.Name, errs)
+}
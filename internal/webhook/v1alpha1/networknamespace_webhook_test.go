@@ -0,0 +1,175 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vitistackcrdsv1alpha1 "github.com/vitistack/common/pkg/v1alpha1"
+	"github.com/vitistack/kea-operator/internal/consts"
+	keaservice "github.com/vitistack/kea-operator/internal/services/kea"
+	"github.com/vitistack/kea-operator/pkg/models/keamodels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// subnetsKea serves subnet4-list and subnet4-get from a fixed set of
+// subnets; managed marks those carrying the operator's user-context.
+type subnetsKea struct {
+	subnets map[int]string
+	managed map[int]bool
+	err     error
+}
+
+func (f *subnetsKea) Send(_ context.Context, cmd keamodels.Request) (keamodels.Response, error) {
+	if f.err != nil {
+		return keamodels.Response{}, f.err
+	}
+	switch cmd.Command {
+	case "subnet4-list":
+		var list []any
+		for id, prefix := range f.subnets {
+			list = append(list, map[string]any{"id": id, "subnet": prefix})
+		}
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"subnets": list}}, nil
+	case "subnet4-get":
+		id, _ := cmd.Args["id"].(int)
+		subnet := map[string]any{"id": id, "subnet": f.subnets[id]}
+		if f.managed[id] {
+			subnet["user-context"] = map[string]any{"managed-by": "kea-operator"}
+		}
+		return keamodels.Response{Result: 0, Arguments: map[string]any{"subnet4": []any{subnet}}}, nil
+	}
+	return keamodels.Response{Result: 3}, nil
+}
+
+func nnWithPrefix(prefix string) *vitistackcrdsv1alpha1.NetworkNamespace {
+	return &vitistackcrdsv1alpha1.NetworkNamespace{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "nn"},
+		Status:     vitistackcrdsv1alpha1.NetworkNamespaceStatus{IPv4Prefix: prefix},
+	}
+}
+
+func TestNetworkNamespaceValidator(t *testing.T) {
+	kea := &subnetsKea{
+		subnets: map[int]string{1: "10.0.0.0/24", 2: "10.1.0.0/24", 3: "10.2.0.0/16"},
+		managed: map[int]bool{1: true},
+	}
+	v := &NetworkNamespaceValidator{Kea: keaservice.New(kea)}
+
+	tests := []struct {
+		prefix  string
+		wantErr string
+	}{
+		{prefix: "10.0.0.0/24"},
+		{prefix: "10.9.0.0/24"},
+		{prefix: "10.1.0.0/24", wantErr: "not managed by kea-operator"},
+		{prefix: "10.2.3.0/24", wantErr: "overlaps Kea subnet 3"},
+		{prefix: "10.0.0.0/16", wantErr: "overlaps Kea subnet 1"},
+		{prefix: "not-a-prefix", wantErr: "IPv4 CIDR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			_, err := v.ValidateCreate(context.Background(), nnWithPrefix(tt.prefix))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// An unchanged prefix is not re-checked, and an unreachable Kea only warns.
+	if _, err := v.ValidateUpdate(context.Background(), nnWithPrefix("10.1.0.0/24"), nnWithPrefix("10.1.0.0/24")); err != nil {
+		t.Fatalf("expected unchanged prefix to pass, got %v", err)
+	}
+	kea.err = context.DeadlineExceeded
+	if warnings, err := v.ValidateCreate(context.Background(), nnWithPrefix("10.1.0.0/24")); err != nil || len(warnings) != 1 {
+		t.Fatalf("expected a warning while Kea is unreachable, got %v, %v", warnings, err)
+	}
+}
+
+func TestNetworkNamespaceValidator_SecondaryPrefixes(t *testing.T) {
+	kea := &subnetsKea{
+		subnets: map[int]string{1: "10.0.0.0/24", 2: "10.1.0.0/24"},
+		managed: map[int]bool{1: true},
+	}
+	v := &NetworkNamespaceValidator{Kea: keaservice.New(kea)}
+
+	tests := []struct {
+		secondary string
+		wantErr   string
+	}{
+		{secondary: "10.9.0.0/24, 10.8.0.0/24"},
+		{secondary: "10.1.0.0/24", wantErr: "not managed by kea-operator"},
+		{secondary: "10.1.0.0/16", wantErr: "overlaps Kea subnet"},
+		{secondary: "10.9.0.0/24,10.9.0.0/25", wantErr: "overlaps 10.9.0.0/24"},
+		{secondary: "10.0.0.128/25", wantErr: "overlaps 10.0.0.0/24"},
+		{secondary: "10.9.0.1/24", wantErr: "network address"},
+		{secondary: "bogus", wantErr: consts.SecondaryIPv4PrefixesAnnotation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.secondary, func(t *testing.T) {
+			nn := nnWithPrefix("10.0.0.0/24")
+			nn.Annotations = map[string]string{consts.SecondaryIPv4PrefixesAnnotation: tt.secondary}
+			_, err := v.ValidateCreate(context.Background(), nn)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Only changed secondary prefixes trigger a check.
+	old := nnWithPrefix("10.0.0.0/24")
+	nn := nnWithPrefix("10.0.0.0/24")
+	nn.Annotations = map[string]string{consts.SecondaryIPv4PrefixesAnnotation: "10.1.0.0/24"}
+	if _, err := v.ValidateUpdate(context.Background(), old, nn); err == nil {
+		t.Fatal("expected a new unmanaged secondary prefix to be rejected")
+	}
+	if _, err := v.ValidateUpdate(context.Background(), nn, nn.DeepCopy()); err != nil {
+		t.Fatalf("expected unchanged prefixes to pass, got %v", err)
+	}
+}
+
+func TestNetworkNamespaceValidator_OwnSubnets(t *testing.T) {
+	// Subnet 2 predates the managed-by user-context; subnet 3 belongs to nobody.
+	kea := &subnetsKea{
+		subnets: map[int]string{2: "10.1.0.0/24", 3: "10.2.0.0/24"},
+	}
+
+	// The subnet of the previous prefix is the NetworkNamespace's own, both as
+	// a secondary prefix and when the primary prefix grows around it.
+	v := &NetworkNamespaceValidator{Kea: keaservice.New(kea)}
+	nn := nnWithPrefix("10.5.0.0/24")
+	nn.Annotations = map[string]string{consts.SecondaryIPv4PrefixesAnnotation: "10.1.0.0/24"}
+	if _, err := v.ValidateUpdate(context.Background(), nnWithPrefix("10.1.0.0/24"), nn); err != nil {
+		t.Fatalf("expected the previous prefix's subnet to be accepted, got %v", err)
+	}
+	if _, err := v.ValidateUpdate(context.Background(), nnWithPrefix("10.1.0.0/24"), nnWithPrefix("10.1.0.0/23")); err != nil {
+		t.Fatalf("expected growing around the previous subnet to be accepted, got %v", err)
+	}
+
+	// A subnet recorded in the inventory of one of its NetworkConfigurations
+	// is its own; one recorded by a NetworkConfiguration of another
+	// NetworkNamespace is not.
+	mine := ncWithMACs("mine", "nn", "aa:bb:cc:dd:ee:01")
+	mine.Annotations = map[string]string{consts.ReservationsAnnotation: `[{"mac":"aa:bb:cc:dd:ee:01","subnetID":2}]`}
+	other := ncWithMACs("other", "nn-2", "aa:bb:cc:dd:ee:02")
+	other.Annotations = map[string]string{consts.ReservationsAnnotation: `[{"mac":"aa:bb:cc:dd:ee:02","subnetID":3}]`}
+	v.Client = newNCValidator(t, mine, other).Client
+	if _, err := v.ValidateCreate(context.Background(), nnWithPrefix("10.1.0.0/24")); err != nil {
+		t.Fatalf("expected the recorded subnet to be accepted, got %v", err)
+	}
+	if _, err := v.ValidateCreate(context.Background(), nnWithPrefix("10.2.0.0/24")); err == nil || !strings.Contains(err.Error(), "not managed by kea-operator") {
+		t.Fatalf("expected another NetworkNamespace's subnet to be rejected, got %v", err)
+	}
+}
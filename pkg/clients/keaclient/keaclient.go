@@ -14,11 +14,13 @@ import (
 	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"os"
 
 	"github.com/vitistack/common/pkg/loggers/vlog"
+	"github.com/vitistack/kea-operator/pkg/interfaces/keainterface"
 	"github.com/vitistack/kea-operator/pkg/models/keamodels"
 )
 
@@ -32,6 +34,7 @@ type keaClient struct {
 	lastConfigHash    string // simple hash to avoid rebuilding transport when unchanged
 	disableKeepAlives bool
 	currentUrl        string // Tracks which URL is currently active
+	currentUrlMu      sync.Mutex
 
 	// TLS options
 	CACertPath         string
@@ -160,14 +163,38 @@ func (c *keaClient) Send(ctx context.Context, cmd keamodels.Request) (keamodels.
 		if i > 0 {
 			vlog.Infof("Successfully failed over to secondary KEA server: url=%s", baseUrl)
 		}
+		c.currentUrlMu.Lock()
 		c.currentUrl = baseUrl
+		c.currentUrlMu.Unlock()
 
 		// Parse response (existing logic)
 		return c.parseResponse(data)
 	}
 
 	// All URLs failed
-	return keamodels.Response{}, fmt.Errorf("all KEA servers failed: %w", lastErr)
+	return keamodels.Response{}, &unreachableError{err: lastErr}
+}
+
+// unreachableError is returned by Send when no Kea server answered. It matches
+// keainterface.ErrUnreachable as well as the underlying transport error.
+type unreachableError struct {
+	err error
+}
+
+func (e *unreachableError) Error() string {
+	return "all KEA servers failed: " + e.err.Error()
+}
+
+func (e *unreachableError) Unwrap() []error {
+	return []error{keainterface.ErrUnreachable, e.err}
+}
+
+// CurrentPeer returns the URL of the Kea server that answered the last
+// successful request, or "" before the first one.
+func (c *keaClient) CurrentPeer() string {
+	c.currentUrlMu.Lock()
+	defer c.currentUrlMu.Unlock()
+	return c.currentUrl
 }
 
 // parseResponse handles the response parsing logic extracted from Send
@@ -83,6 +83,14 @@ func OptionSecondaryURL(fullURL string) KeaOption {
 	})
 }
 
+// OptionNoSecondaryURL clears the secondary URL, for a client whose primary
+// URL was overridden after OptionFromEnv.
+func OptionNoSecondaryURL() KeaOption {
+	return optionFunc(func(cfg *keaClient) {
+		cfg.SecondaryUrl = ""
+	})
+}
+
 // TLS and HTTP options
 func OptionTLS(caFile, certFile, keyFile string) KeaOption {
 	return optionFunc(func(cfg *keaClient) {
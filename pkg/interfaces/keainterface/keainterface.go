@@ -2,10 +2,22 @@ package keainterface
 
 import (
 	"context"
+	"errors"
 
 	"github.com/vitistack/kea-operator/pkg/models/keamodels"
 )
 
+// ErrUnreachable is matched (via errors.Is) by the errors KeaClient
+// implementations return when no Kea server could be reached at all, as
+// opposed to Kea answering with a failure result.
+var ErrUnreachable = errors.New("kea unreachable")
+
 type KeaClient interface {
 	Send(ctx context.Context, cmd keamodels.Request) (keamodels.Response, error)
 }
+
+// PeerReporter is implemented by KeaClients that know which Kea server (HA
+// peer) answered the most recent request.
+type PeerReporter interface {
+	CurrentPeer() string
+}
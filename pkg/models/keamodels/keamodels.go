@@ -1,11 +1,30 @@
 package keamodels
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 type Request struct {
 	Command string         `json:"command"`
 	Service string         `json:"service,omitempty"` // e.g. "dhcp4"
 	Args    map[string]any `json:"arguments,omitempty"`
 }
 
+// MarshalJSON encodes Service as the one-element list the Kea Control Agent
+// expects ("service": ["dhcp6"]).
+func (r Request) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Command string         `json:"command"`
+		Service []string       `json:"service,omitempty"`
+		Args    map[string]any `json:"arguments,omitempty"`
+	}{Command: r.Command, Args: r.Args}
+	if r.Service != "" {
+		out.Service = []string{r.Service}
+	}
+	return json.Marshal(out)
+}
+
 type Response struct {
 	Result    int            `json:"result"`
 	Text      string         `json:"text,omitempty"`
@@ -14,14 +33,119 @@ type Response struct {
 
 // SubnetConfig contains configuration options for creating a new subnet
 type SubnetConfig struct {
-	Subnet               string   // Required: CIDR notation (e.g., "192.168.1.0/24")
-	ID                   int      // Optional: specific subnet ID (0 = auto-assign)
-	Gateway              string   // Optional: default gateway (router option)
-	DNS                  []string // Optional: DNS servers
-	PoolStart            string   // Optional: start of IP pool range
-	PoolEnd              string   // Optional: end of IP pool range
-	ValidLife            int      // Optional: valid lifetime in seconds (default: 4000)
-	RenewTimer           int      // Optional: renew timer in seconds
-	RebindTimer          int      // Optional: rebind timer in seconds
-	RequireClientClasses []string // Optional: client classes required for this pool
+	Subnet               string       // Required: CIDR notation (e.g., "192.168.1.0/24")
+	ID                   int          // Optional: specific subnet ID (0 = auto-assign)
+	Gateway              string       // Optional: default gateway (router option)
+	DNS                  []string     // Optional: DNS servers
+	PoolStart            string       // Optional: start of IP pool range
+	PoolEnd              string       // Optional: end of IP pool range
+	Pools                []string     // Optional: pool ranges in Kea syntax ("start - end"); takes precedence over PoolStart/PoolEnd
+	ValidLife            int          // Optional: valid lifetime in seconds (default: 4000)
+	RenewTimer           int          // Optional: renew timer in seconds
+	RebindTimer          int          // Optional: rebind timer in seconds
+	RequireClientClasses []string     // Optional: client classes required for this pool
+	Options              []OptionData // Optional: further option-data; an entry for routers or domain-name-servers replaces Gateway or DNS
+	RelayAddresses       []string     // Optional: relay agent (giaddr) addresses whose requests select this subnet
+	Interface            string       // Optional: server interface the subnet is served on to directly attached clients
+}
+
+// OptionData is one Kea option-data entry. Kea needs Name or Code; Space
+// defaults to the server's standard space ("dhcp4") and CSVFormat to true.
+type OptionData struct {
+	Name      string `json:"name,omitempty"`
+	Code      int    `json:"code,omitempty"`
+	Space     string `json:"space,omitempty"`
+	Data      string `json:"data"`
+	CSVFormat *bool  `json:"csvFormat,omitempty"`
+}
+
+// PDPool is a DHCPv6 prefix-delegation pool: delegated-length prefixes are
+// handed out from Prefix/PrefixLen.
+type PDPool struct {
+	Prefix       string // e.g. "2001:db8:8000::"
+	PrefixLen    int    // e.g. 40
+	DelegatedLen int    // e.g. 56
+}
+
+// Subnet6Config contains configuration options for creating a new IPv6 subnet
+type Subnet6Config struct {
+	Subnet        string   // Required: CIDR notation (e.g., "2001:db8:1::/64")
+	ID            int      // Optional: specific subnet ID (0 = auto-assign)
+	Pools         []string // Optional: address pools in Kea syntax ("start - end")
+	PDPools       []PDPool // Optional: prefix-delegation pools
+	DNS           []string // Optional: DNS servers (option dns-servers)
+	ValidLife     int      // Optional: valid lifetime in seconds (default: 4000)
+	PreferredLife int      // Optional: preferred lifetime in seconds
+}
+
+// ReservationConfig contains the desired state of a host reservation
+type ReservationConfig struct {
+	MAC               string     // Required: hardware address of the host
+	SubnetID          int        // Required: Kea subnet-id the reservation belongs to
+	IPAddress         string     // Optional: fixed address (empty = MAC-only reservation); IPv6 for EnsureReservation6
+	DUID              string     // Optional: DHCPv6 client DUID; keys an EnsureReservation6 reservation instead of MAC
+	Owner             string     // Optional: owning resource (namespace/name), recorded in user-context
+	Host              HostParams // Optional: per-host settings, kept in sync on the reservation (DHCPv4 only)
+	IdentifierType    string     // Optional: DHCPv4 host identifier: hw-address (default), client-id, duid, circuit-id or flex-id
+	Identifier        string     // Optional: identifier value, hex or 'quoted text'; derived from MAC for hw-address and client-id
+	RecordedSubnetIDs []int      // Optional: subnets where the owner's inventory records this host; an owner-less reservation there is the owner's own
+}
+
+// LeaseConfig contains a DHCPv4 lease to add for a host that has not asked for one yet
+type LeaseConfig struct {
+	MAC       string // Required: hardware address of the host
+	IPAddress string // Required: leased address
+	SubnetID  int    // Required: Kea subnet-id the lease belongs to
+	Hostname  string // Optional: hostname recorded on the lease
+	ValidLife int    // Optional: lease lifetime in seconds (default: the subnet's valid-lifetime)
+	Owner     string // Optional: owning resource (namespace/name), recorded in user-context
+}
+
+// HostParams are per-host settings carried on a DHCPv4 reservation. Empty
+// fields are not set.
+type HostParams struct {
+	Hostname       string
+	NextServer     string
+	ServerHostname string
+	BootFileName   string
+	ClientClasses  []string
+	Options        []OptionData
+}
+
+// ClientClassConfig contains the desired state of a kea-dhcp4 client class
+type ClientClassConfig struct {
+	Name           string       // Required: class name
+	Test           string       // Optional: membership test expression
+	OnlyIfRequired bool         // Optional: evaluate only where required by a subnet or pool
+	NextServer     string       // Optional: siaddr handed to class members
+	ServerHostname string       // Optional: sname handed to class members
+	BootFileName   string       // Optional: BOOTP file field handed to class members
+	Options        []OptionData // Optional: option-data sent to class members
+	Owner          string       // Optional: owning resource, recorded in user-context
+}
+
+// Matches reports whether o and b are the same option: the same space, with
+// "" matching any, and the same code or, when either lacks one, the same name.
+func (o OptionData) Matches(b OptionData) bool {
+	if o.Space != "" && b.Space != "" && o.Space != b.Space {
+		return false
+	}
+	if o.Code > 0 && b.Code > 0 {
+		return o.Code == b.Code
+	}
+	return o.Name != "" && o.Name == b.Name
+}
+
+// SameData reports whether o and b carry the same data, ignoring spacing
+// around CSV separators and the case of hex digits.
+func (o OptionData) SameData(b OptionData) bool {
+	return strings.EqualFold(normalizeOptionData(o.Data), normalizeOptionData(b.Data))
+}
+
+func normalizeOptionData(data string) string {
+	parts := strings.Split(data, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return strings.Join(parts, ",")
 }